@@ -0,0 +1,230 @@
+package httpkit
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Dharmey747/quic-go-utls/http3"
+	http "github.com/bogdanfinn/fhttp"
+)
+
+const (
+	// defaultAltSvcMaxAge is the freshness lifetime of an alternative service without an explicit "ma" parameter (RFC 7838, section 3.1).
+	defaultAltSvcMaxAge = 24 * time.Hour
+	// altSvcInitialBrokenDelay and altSvcMaxBrokenDelay mirror Chrome, which stops using a failing alternative service for five minutes
+	// and doubles that delay on every consecutive failure.
+	altSvcInitialBrokenDelay = 5 * time.Minute
+	altSvcMaxBrokenDelay     = 48 * time.Hour
+)
+
+// altSvc is a single alternative service advertised by an origin through the Alt-Svc response header.
+type altSvc struct {
+	expires  time.Time
+	protocol string
+	host     string
+	port     string
+}
+
+// address returns the host:port to dial for the alternative service. An empty host means the origin host.
+func (a altSvc) address(originHost string) string {
+	host := a.host
+	if host == "" {
+		host = originHost
+	}
+
+	return net.JoinHostPort(host, a.port)
+}
+
+type brokenAltSvc struct {
+	until time.Time
+	delay time.Duration
+}
+
+// altSvcCache keeps the HTTP/3 alternative services advertised per origin (host:port) together with the ones that recently failed.
+type altSvcCache struct {
+	now     func() time.Time
+	entries map[string]altSvc
+	broken  map[string]brokenAltSvc
+	sync.Mutex
+}
+
+func newAltSvcCache() *altSvcCache {
+	return &altSvcCache{
+		now:     time.Now,
+		entries: make(map[string]altSvc),
+		broken:  make(map[string]brokenAltSvc),
+	}
+}
+
+// update records the Alt-Svc header of a response received from origin. A "clear" value removes any known alternative.
+// Headers without a supported protocol leave the cache untouched.
+func (c *altSvcCache) update(origin string, header http.Header) {
+	values := header.Values("Alt-Svc")
+	if len(values) == 0 {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	now := c.now()
+	services, clear := parseAltSvc(values, now)
+
+	if clear {
+		delete(c.entries, origin)
+		return
+	}
+
+	for _, service := range services {
+		if service.protocol != http3.NextProtoH3 {
+			continue
+		}
+
+		c.entries[origin] = service
+		return
+	}
+}
+
+// lookup returns the fresh HTTP/3 alternative service of origin, unless it is currently marked as broken.
+func (c *altSvcCache) lookup(origin string) (altSvc, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	now := c.now()
+
+	service, ok := c.entries[origin]
+	if !ok {
+		return altSvc{}, false
+	}
+
+	if !now.Before(service.expires) {
+		delete(c.entries, origin)
+		return altSvc{}, false
+	}
+
+	if broken, ok := c.broken[origin]; ok && now.Before(broken.until) {
+		return altSvc{}, false
+	}
+
+	return service, true
+}
+
+// markBroken stops using the alternative service of origin for an exponentially growing period.
+func (c *altSvcCache) markBroken(origin string) {
+	c.Lock()
+	defer c.Unlock()
+
+	delay := altSvcInitialBrokenDelay
+	if broken, ok := c.broken[origin]; ok {
+		delay = min(broken.delay*2, altSvcMaxBrokenDelay)
+	}
+
+	c.broken[origin] = brokenAltSvc{until: c.now().Add(delay), delay: delay}
+}
+
+// confirm resets the broken state of origin after a successful request over its alternative service.
+func (c *altSvcCache) confirm(origin string) {
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.broken, origin)
+}
+
+// parseAltSvc parses the field values of Alt-Svc headers as defined in RFC 7838, section 3.
+// The returned services keep the order of preference of the server.
+func parseAltSvc(values []string, now time.Time) (services []altSvc, clear bool) {
+	for _, value := range values {
+		for _, alternative := range splitQuoted(value, ',') {
+			alternative = strings.TrimSpace(alternative)
+			if alternative == "" {
+				continue
+			}
+
+			if alternative == "clear" {
+				return nil, true
+			}
+
+			params := splitQuoted(alternative, ';')
+
+			protocol, authority, ok := strings.Cut(params[0], "=")
+			if !ok {
+				continue
+			}
+
+			host, port, err := net.SplitHostPort(unquote(strings.TrimSpace(authority)))
+			if err != nil || port == "" {
+				continue
+			}
+
+			service := altSvc{
+				protocol: strings.TrimSpace(protocol),
+				host:     host,
+				port:     port,
+				expires:  now.Add(defaultAltSvcMaxAge),
+			}
+
+			for _, param := range params[1:] {
+				key, val, _ := strings.Cut(param, "=")
+				if strings.ToLower(strings.TrimSpace(key)) != "ma" {
+					continue
+				}
+
+				maxAge, err := strconv.ParseInt(unquote(strings.TrimSpace(val)), 10, 64)
+				if err != nil || maxAge < 0 {
+					continue
+				}
+
+				service.expires = now.Add(time.Duration(maxAge) * time.Second)
+			}
+
+			services = append(services, service)
+		}
+	}
+
+	return services, false
+}
+
+// splitQuoted splits s around sep, ignoring separators inside quoted strings.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+
+	quoted := false
+	start := 0
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s)-1; i++ {
+		if s[i] == '\\' && i+1 < len(s)-1 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
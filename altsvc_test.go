@@ -0,0 +1,63 @@
+package httpkit
+
+import (
+	"testing"
+	"time"
+
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+const altSvcOrigin = "example.com:443"
+
+func TestAltSvc_GivenMultipleAlternatives_WhenParse_ThenAllAreReturnedInOrder(t *testing.T) {
+	now := time.Now()
+
+	services, clear := parseAltSvc([]string{`h3=":443"; ma=86400, h3-29="alt.example.com:8443"`}, now)
+
+	assert.False(t, clear)
+	assert.Equal(t, 2, len(services), "Expected both alternatives to be parsed")
+	assert.Equal(t, "h3", services[0].protocol)
+	assert.Equal(t, "example.com:443", services[0].address("example.com"), "Expected empty host to fall back to the altSvcOrigin host")
+	assert.Equal(t, now.Add(24*time.Hour), services[0].expires)
+	assert.Equal(t, "alt.example.com:8443", services[1].address("example.com"))
+}
+
+func TestAltSvc_GivenKnownAlternative_WhenClearReceived_ThenAlternativeIsRemoved(t *testing.T) {
+	cache := newAltSvcCache()
+
+	cache.update(altSvcOrigin, http.Header{"Alt-Svc": {`h3=":443"`}})
+	_, known := cache.lookup(altSvcOrigin)
+	cache.update(altSvcOrigin, http.Header{"Alt-Svc": {"clear"}})
+	_, cleared := cache.lookup(altSvcOrigin)
+
+	assert.True(t, known, "Expected h3 alternative to be cached")
+	assert.False(t, cleared, "Expected alternative to be removed")
+}
+
+func TestAltSvc_GivenExpiredAlternative_WhenLookup_ThenAlternativeIsIgnored(t *testing.T) {
+	cache := newAltSvcCache()
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.update(altSvcOrigin, http.Header{"Alt-Svc": {`h3=":443"; ma=60`}})
+	now = now.Add(time.Minute)
+	_, ok := cache.lookup(altSvcOrigin)
+
+	assert.False(t, ok, "Expected expired alternative to be ignored")
+}
+
+func TestAltSvc_GivenBrokenAlternative_WhenLookup_ThenAlternativeIsSkippedUntilDelayPassed(t *testing.T) {
+	cache := newAltSvcCache()
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.update(altSvcOrigin, http.Header{"Alt-Svc": {`h3=":443"`}})
+	cache.markBroken(altSvcOrigin)
+	_, broken := cache.lookup(altSvcOrigin)
+	now = now.Add(altSvcInitialBrokenDelay)
+	_, retried := cache.lookup(altSvcOrigin)
+
+	assert.False(t, broken, "Expected broken alternative to be skipped")
+	assert.True(t, retried, "Expected alternative to be retried after the broken delay")
+}
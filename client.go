@@ -3,6 +3,7 @@ package httpkit
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/url"
@@ -36,8 +37,10 @@ type HttpClient interface {
 	SetFollowRedirect(followRedirect bool)
 	GetFollowRedirect() bool
 	CloseIdleConnections()
+	Close() error
 
 	Do(req *http.Request) (*http.Response, error)
+	DoWithOptions(req *http.Request, options ...RequestOption) (*http.Response, error)
 	DoWithProxy(req *http.Request, proxyUrl string) (*http.Response, error)
 
 	Get(url string) (resp *http.Response, err error)
@@ -45,6 +48,7 @@ type HttpClient interface {
 	Post(url, contentType string, body io.Reader) (resp *http.Response, err error)
 
 	GetBandwidthTracker() bandwidth.BandwidthTracker
+	GetHarRecorder() *HarRecorder
 }
 
 // Interface guards are a cheap way to make sure all methods are implemented, this is a static check and does not affect runtime performance.
@@ -54,11 +58,12 @@ type httpClient struct {
 	logger Logger
 
 	bandwidthTracker bandwidth.BandwidthTracker
+	harRecorder      *HarRecorder
 	config           *httpClientConfig
+	transports       *transportCache
 
 	http.Client
 	headerLck sync.Mutex
-	proxyLck  sync.Mutex
 }
 
 var DefaultTimeoutSeconds = 30
@@ -117,12 +122,19 @@ func NewHttpClient(logger Logger, options ...HttpClientOption) (HttpClient, erro
 		config.flowId = platekit.Generate()
 	}
 
+	var harRecorder *HarRecorder
+	if config.harOptions != nil {
+		harRecorder = newHarRecorder(*config.harOptions)
+	}
+
 	return &httpClient{
 		Client:           *client,
 		logger:           logger,
 		config:           config,
 		headerLck:        sync.Mutex{},
 		bandwidthTracker: bandwidthTracker,
+		harRecorder:      harRecorder,
+		transports:       newTransportCache(DefaultMaxCachedTransports),
 	}, nil
 }
 
@@ -131,38 +143,6 @@ func validateConfig(_ *httpClientConfig) error {
 }
 
 func buildFromConfig(logger Logger, config *httpClientConfig) (*http.Client, bandwidth.BandwidthTracker, profiles.ClientProfile, error) {
-	var dialer proxy.ContextDialer
-	dialer = newDirectDialer(config.timeout, config.localAddr, config.dialer)
-
-	if config.proxyUrl != "" && config.proxyDialerFactory == nil {
-		proxyDialer, err := newConnectDialer(config.proxyUrl, config.timeout, config.localAddr, config.dialer, config.connectHeaders, logger)
-		if err != nil {
-			return nil, nil, profiles.ClientProfile{}, err
-		}
-
-		dialer = proxyDialer
-	}
-
-	if config.proxyDialerFactory != nil {
-		proxyDialer, err := config.proxyDialerFactory(config.proxyUrl, config.timeout, config.localAddr, config.connectHeaders, logger)
-		if err != nil {
-			return nil, nil, profiles.ClientProfile{}, err
-		}
-
-		dialer = proxyDialer
-	}
-
-	var redirectFunc func(req *http.Request, via []*http.Request) error
-	if !config.followRedirects {
-		redirectFunc = defaultRedirectFunc
-	} else {
-		redirectFunc = nil
-
-		if config.customRedirectFunc != nil {
-			redirectFunc = config.customRedirectFunc
-		}
-	}
-
 	var bandwidthTracker bandwidth.BandwidthTracker
 	if config.enabledBandwidthTracker {
 		bandwidthTracker = bandwidth.NewTracker()
@@ -172,7 +152,7 @@ func buildFromConfig(logger Logger, config *httpClientConfig) (*http.Client, ban
 
 	clientProfile := config.clientProfile
 
-	transport, err := newRoundTripper(clientProfile, config.transportOptions, config.serverNameOverwrite, config.insecureSkipVerify, config.withRandomTlsExtensionOrder, config.forceHttp1, config.certificatePins, config.badPinHandler, config.disableIPV6, config.disableIPV4, bandwidthTracker, dialer)
+	transport, err := buildRoundTripper(logger, config, config.proxyUrl, clientProfile, bandwidthTracker)
 	if err != nil {
 		return nil, nil, clientProfile, err
 	}
@@ -180,7 +160,7 @@ func buildFromConfig(logger Logger, config *httpClientConfig) (*http.Client, ban
 	client := &http.Client{
 		Timeout:       config.timeout,
 		Transport:     transport,
-		CheckRedirect: redirectFunc,
+		CheckRedirect: redirectFuncFor(config.followRedirects, config.customRedirectFunc),
 	}
 
 	if config.cookieJar != nil {
@@ -190,9 +170,46 @@ func buildFromConfig(logger Logger, config *httpClientConfig) (*http.Client, ban
 	return client, bandwidthTracker, clientProfile, nil
 }
 
-// CloseIdleConnections closes all idle connections of the underlying http client.
+// buildRoundTripper creates a round tripper for the given proxy and client profile, sharing all other settings of the config.
+func buildRoundTripper(logger Logger, config *httpClientConfig, proxyUrl string, clientProfile profiles.ClientProfile, bandwidthTracker bandwidth.BandwidthTracker) (http.RoundTripper, error) {
+	dialer, err := newDialer(logger, config, proxyUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRoundTripper(clientProfile, config.transportOptions, config.serverNameOverwrite, config.insecureSkipVerify, config.withRandomTlsExtensionOrder, config.forceHttp1, config.disableHttp3, config.certificatePins, config.badPinHandler, config.disableIPV6, config.disableIPV4, proxyUrl, bandwidthTracker, dialer)
+}
+
+func newDialer(logger Logger, config *httpClientConfig, proxyUrl string) (proxy.ContextDialer, error) {
+	if config.proxyDialerFactory != nil {
+		return config.proxyDialerFactory(proxyUrl, config.timeout, config.localAddr, config.connectHeaders, logger)
+	}
+
+	if proxyUrl != "" {
+		return newConnectDialer(proxyUrl, config.timeout, config.localAddr, config.dialer, config.connectHeaders, logger)
+	}
+
+	return newDirectDialer(config.timeout, config.localAddr, config.dialer), nil
+}
+
+func redirectFuncFor(followRedirects bool, customRedirectFunc func(req *http.Request, via []*http.Request) error) func(req *http.Request, via []*http.Request) error {
+	if !followRedirects {
+		return defaultRedirectFunc
+	}
+
+	return customRedirectFunc
+}
+
+// CloseIdleConnections closes all idle connections of the underlying http client and of the per-request transports.
 func (c *httpClient) CloseIdleConnections() {
 	c.Client.CloseIdleConnections()
+	c.transports.closeIdleConnections()
+}
+
+// Close closes the connections of the client and of its per-request transports, including the UDP socket of their
+// HTTP/3 connections. The client opens new connections if it is used again.
+func (c *httpClient) Close() error {
+	return errors.Join(closeTransport(c.Transport), c.transports.close())
 }
 
 // SetFollowRedirect configures the client's HTTP redirect following policy.
@@ -211,15 +228,11 @@ func (c *httpClient) GetFollowRedirect() bool {
 func (c *httpClient) applyFollowRedirect() {
 	if c.config.followRedirects {
 		c.logger.Debug("automatic redirect following is enabled")
-		c.CheckRedirect = nil
 	} else {
 		c.logger.Debug("automatic redirect following is disabled")
-		c.CheckRedirect = defaultRedirectFunc
 	}
 
-	if c.config.customRedirectFunc != nil && c.config.followRedirects {
-		c.CheckRedirect = c.config.customRedirectFunc
-	}
+	c.CheckRedirect = redirectFuncFor(c.config.followRedirects, c.config.customRedirectFunc)
 }
 
 // SetProxy configures the client to use the given proxy URL.
@@ -250,38 +263,25 @@ func (c *httpClient) GetProxy() string {
 }
 
 func (c *httpClient) applyProxy() error {
-	var dialer proxy.ContextDialer
-	dialer = proxy.Direct
-
-	if c.config.proxyUrl != "" && c.config.proxyDialerFactory == nil {
-		c.logger.Debug("proxy url %s supplied - using proxy connect dialer", c.config.proxyUrl)
-		proxyDialer, err := newConnectDialer(c.config.proxyUrl, c.config.timeout, c.config.localAddr, c.config.dialer, c.config.connectHeaders, c.logger)
-		if err != nil {
-			c.logger.Error("failed to create proxy connect dialer: %s", err.Error())
-			return err
-		}
-
-		dialer = proxyDialer
-	}
-
 	if c.config.proxyDialerFactory != nil {
 		c.logger.Debug("using custom proxy connect dialer")
-		proxyDialer, err := c.config.proxyDialerFactory(c.config.proxyUrl, c.config.timeout, c.config.localAddr, c.config.connectHeaders, c.logger)
-		if err != nil {
-			c.logger.Error("failed to create proxy connect dialer: %s", err.Error())
-			return err
-		}
-
-		dialer = proxyDialer
+	} else if c.config.proxyUrl != "" {
+		c.logger.Debug("proxy url %s supplied - using proxy connect dialer", c.config.proxyUrl)
 	}
 
-	transport, err := newRoundTripper(c.config.clientProfile, c.config.transportOptions, c.config.serverNameOverwrite, c.config.insecureSkipVerify, c.config.withRandomTlsExtensionOrder, c.config.forceHttp1, c.config.certificatePins, c.config.badPinHandler, c.config.disableIPV6, c.config.disableIPV4, c.bandwidthTracker, dialer)
+	transport, err := buildRoundTripper(c.logger, c.config, c.config.proxyUrl, c.config.clientProfile, c.bandwidthTracker)
 	if err != nil {
+		c.logger.Error("failed to create proxy connect dialer: %s", err.Error())
 		return err
 	}
 
+	previous := c.Transport
 	c.Transport = transport
 
+	if previous != nil {
+		_ = closeTransport(previous)
+	}
+
 	return nil
 }
 
@@ -328,10 +328,85 @@ func (c *httpClient) GetBandwidthTracker() bandwidth.BandwidthTracker {
 	return c.bandwidthTracker
 }
 
+// GetHarRecorder returns the HAR recorder of the client, nil if it was not configured with WithHarRecorder.
+func (c *httpClient) GetHarRecorder() *HarRecorder {
+	return c.harRecorder
+}
+
 // Do issues a given HTTP request and returns the corresponding response.
 //
 // If the returned error is nil, the response contains a non-nil body, which the user is expected to close.
 func (c *httpClient) Do(req *http.Request) (*http.Response, error) {
+	return c.DoWithOptions(req)
+}
+
+// DoWithOptions issues a given HTTP request with per-request options and returns the corresponding response.
+//
+// The options only apply to this request and never change the client, so concurrent requests can use different proxies
+// and client profiles in parallel. Transports are cached per proxy and client profile and keep their pooled connections.
+func (c *httpClient) DoWithOptions(req *http.Request, options ...RequestOption) (*http.Response, error) {
+	reqConfig := &requestConfig{}
+
+	for _, opt := range options {
+		opt(reqConfig)
+	}
+
+	return c.do(req, reqConfig)
+}
+
+// DoWithProxy issues a given HTTP request through the given proxy.
+//
+// Deprecated: use DoWithOptions with WithRequestProxy instead.
+func (c *httpClient) DoWithProxy(req *http.Request, proxyUrl string) (*http.Response, error) {
+	return c.DoWithOptions(req, WithRequestProxy(proxyUrl))
+}
+
+// clientFor returns a shallow copy of the underlying http client with the request options applied.
+func (c *httpClient) clientFor(reqConfig *requestConfig) (*http.Client, error) {
+	client := c.Client
+
+	if reqConfig.proxyUrl != nil || reqConfig.clientProfile != nil {
+		proxyUrl := c.config.proxyUrl
+		if reqConfig.proxyUrl != nil {
+			proxyUrl = *reqConfig.proxyUrl
+		}
+
+		clientProfile := c.config.clientProfile
+		if reqConfig.clientProfile != nil {
+			clientProfile = *reqConfig.clientProfile
+		}
+
+		transport, err := c.transports.get(proxyUrl, clientProfile, func() (http.RoundTripper, error) {
+			c.logger.Debug("creating transport for proxy %s and client profile %s", proxyUrl, clientProfile.GetClientHelloStr())
+			return buildRoundTripper(c.logger, c.config, proxyUrl, clientProfile, c.bandwidthTracker)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		client.Transport = transport
+	}
+
+	if reqConfig.timeout > 0 {
+		client.Timeout = reqConfig.timeout
+	}
+
+	if reqConfig.followRedirects != nil {
+		client.CheckRedirect = redirectFuncFor(*reqConfig.followRedirects, c.config.customRedirectFunc)
+	}
+
+	if reqConfig.redirectFunc != nil {
+		client.CheckRedirect = reqConfig.redirectFunc
+	}
+
+	if c.harRecorder != nil {
+		client.Transport = c.harRecorder.transport(client.Transport)
+	}
+
+	return &client, nil
+}
+
+func (c *httpClient) do(req *http.Request, reqConfig *requestConfig) (*http.Response, error) {
 	if c.config.catchPanics {
 		defer func() {
 			err := recover()
@@ -346,16 +421,52 @@ func (c *httpClient) Do(req *http.Request) (*http.Response, error) {
 		}()
 	}
 
+	client, err := c.clientFor(reqConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultHeaders := c.config.defaultHeaders
+	if reqConfig.defaultHeaders != nil {
+		defaultHeaders = reqConfig.defaultHeaders
+	}
+
 	// Header order must be defined in all lowercase. On HTTP 1 people sometimes define them also in uppercase and then ordering does not work.
 	c.headerLck.Lock()
 
 	if len(req.Header) == 0 {
-		req.Header = c.config.defaultHeaders.Clone()
+		req.Header = defaultHeaders.Clone()
+	}
+
+	if c.config.profileHeaders {
+		clientProfile := c.config.clientProfile
+		if reqConfig.clientProfile != nil {
+			clientProfile = *reqConfig.clientProfile
+		}
+
+		applyProfileHeaders(req, clientProfile.GetHeaders(requestKindFor(req, reqConfig)))
 	}
 
 	req.Header[http.HeaderOrderKey] = allToLower(req.Header[http.HeaderOrderKey])
 	c.headerLck.Unlock()
 
+	roundTrip := func(req *http.Request) (*http.Response, error) {
+		return c.roundTrip(client, req, reqConfig)
+	}
+
+	for i := len(c.config.middlewares) - 1; i >= 0; i-- {
+		roundTrip = c.config.middlewares[i](roundTrip)
+	}
+
+	return roundTrip(req)
+}
+
+// roundTrip sends the request once the middlewares ran, logging it in debug mode.
+func (c *httpClient) roundTrip(client *http.Client, req *http.Request, reqConfig *requestConfig) (*http.Response, error) {
+	if hook := c.sendHookFor(reqConfig); hook != nil {
+		req = withSendHook(req, hook)
+	}
+
 	if c.config.debug {
 		debugReq := req.Clone(context.Background())
 
@@ -372,6 +483,9 @@ func (c *httpClient) Do(req *http.Request) (*http.Response, error) {
 
 			debugReq.Body = debugBody
 			req.Body = requestBody
+			req.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(buf)), nil
+			}
 		}
 
 		requestBytes, err := httputil.DumpRequestOut(debugReq, debugReq.ContentLength > 0)
@@ -382,7 +496,7 @@ func (c *httpClient) Do(req *http.Request) (*http.Response, error) {
 		c.logger.Debug("raw request bytes sent over wire: %d (%d kb)", len(requestBytes), len(requestBytes)/1024)
 	}
 
-	resp, err := c.Client.Do(req)
+	resp, err := c.send(client, req, reqConfig)
 	if err != nil {
 		c.logger.Debug("failed to do request: %s", err.Error())
 		return nil, err
@@ -420,17 +534,134 @@ func (c *httpClient) Do(req *http.Request) (*http.Response, error) {
 	return resp, nil
 }
 
-func (c *httpClient) DoWithProxy(req *http.Request, proxyUrl string) (*http.Response, error) {
-	c.proxyLck.Lock()
-	defer c.proxyLck.Unlock()
+// sendHookFor returns the hook completing the requests sent for reqConfig once their protocol is known: the headers of
+// the client profile are sent in the case browsers use over HTTP/1.1 and the fingerprint consistency check, if enabled,
+// runs on every hop of the redirect chain. It returns nil if there is nothing to do.
+func (c *httpClient) sendHookFor(reqConfig *requestConfig) sendHook {
+	if !c.config.profileHeaders && c.config.fingerprintCheck == nil {
+		return nil
+	}
 
-	currentProxy := c.GetProxy()
-	if err := c.SetProxy(proxyUrl); err != nil {
-		return nil, err
+	clientProfile := c.config.clientProfile
+	if reqConfig.clientProfile != nil {
+		clientProfile = *reqConfig.clientProfile
+	}
+
+	return func(req *http.Request, http1 bool) error {
+		kind := requestKindFor(req, reqConfig)
+
+		if http1 && c.config.profileHeaders {
+			canonicalizeProfileHeaders(req.Header, clientProfile.GetHeaders(kind))
+		}
+
+		return c.checkFingerprint(req, clientProfile, kind, http1)
+	}
+}
+
+// checkFingerprint runs the fingerprint consistency check on req, if enabled, and returns a *FingerprintError if the
+// request has issues and must be rejected. http1 is whether the request is sent over HTTP/1.1.
+func (c *httpClient) checkFingerprint(req *http.Request, clientProfile profiles.ClientProfile, kind profiles.RequestKind, http1 bool) error {
+	options := c.config.fingerprintCheck
+	if options == nil {
+		return nil
+	}
+
+	decodes := c.config.transportOptions == nil || !c.config.transportOptions.DisableCompression
+
+	issues := checkFingerprint(req, clientProfile, kind, http1, decodes)
+	if len(issues) == 0 {
+		return nil
+	}
+
+	if options.OnIssues != nil {
+		options.OnIssues(req, issues)
+	} else {
+		for _, issue := range issues {
+			c.logger.Warn("inconsistent fingerprint of request to %s: %s", req.URL.String(), issue.String())
+		}
+	}
+
+	if options.Reject {
+		return &FingerprintError{Issues: issues}
+	}
+
+	return nil
+}
+
+// send issues the request with the given client, retrying it according to the retry policy of the request or the client.
+func (c *httpClient) send(client *http.Client, req *http.Request, reqConfig *requestConfig) (*http.Response, error) {
+	retryPolicy := c.config.retryPolicy
+	if reqConfig.retryPolicy != nil {
+		retryPolicy = reqConfig.retryPolicy
+	}
+
+	kind := requestKindFor(req, reqConfig)
+
+	if retryPolicy == nil || retryPolicy.MaxAttempts <= 1 {
+		return fetchClient(client, req, c.config.navigationHeaders, kind).Do(req)
+	}
+
+	for attempt := 1; ; attempt++ {
+		// every attempt follows its own redirect chain
+		resp, err := fetchClient(client, req, c.config.navigationHeaders, kind).Do(req)
+
+		if attempt >= retryPolicy.MaxAttempts || !retryPolicy.shouldRetry(req, resp, err) {
+			c.logger.Debug("request to %s finished after %d attempt(s)", req.URL.String(), attempt)
+			return resp, err
+		}
+
+		delay, ok := retryPolicy.backoff(attempt, resp)
+		if !ok {
+			c.logger.Debug("server asked to retry %s later than the max retry after delay, giving up after %d attempt(s)", req.URL.String(), attempt)
+			return resp, err
+		}
+
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				c.logger.Debug("request body of %s can not be replayed, giving up after %d attempt(s)", req.URL.String(), attempt)
+				return resp, err
+			}
+
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+
+			req.Body = body
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		if err != nil {
+			c.logger.Debug("attempt %d/%d to %s failed: %s. retrying in %s", attempt, retryPolicy.MaxAttempts, req.URL.String(), err.Error(), delay)
+		} else {
+			c.logger.Debug("attempt %d/%d to %s returned status %d. retrying in %s", attempt, retryPolicy.MaxAttempts, req.URL.String(), resp.StatusCode, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+
+		if retryPolicy.ProxyRotator != nil {
+			rotatedConfig := *reqConfig
+			proxyUrl := retryPolicy.ProxyRotator(attempt + 1)
+			rotatedConfig.proxyUrl = &proxyUrl
+
+			c.logger.Debug("rotating proxy to %s for attempt %d", proxyUrl, attempt+1)
+
+			client, err = c.clientFor(&rotatedConfig)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
-	resp, doErr := c.Do(req)
-	_ = c.SetProxy(currentProxy)
-	return resp, doErr
 }
 
 func (c *httpClient) Get(url string) (resp *http.Response, err error) {
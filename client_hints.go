@@ -0,0 +1,158 @@
+package httpkit
+
+import (
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/Mathious6/httpkit/profiles"
+	http "github.com/bogdanfinn/fhttp"
+)
+
+// ClientHints returns a middleware sending the user-agent header and the client hints of userAgent with every request,
+// replacing the ones set on the request so that they always match.
+//
+// Like a browser, the middleware remembers the high entropy hints requested by an HTTPS origin through the Accept-CH
+// response header and sends them on the following requests to that origin. When the response lists hints in
+// Critical-CH which were not sent, the request is retried once with them, if its body can be replayed.
+func ClientHints(userAgent *profiles.UserAgent) Middleware {
+	hints := &acceptedClientHints{origins: make(map[string][]string)}
+
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			origin := req.URL.Scheme + "://" + req.URL.Host
+
+			sent := hints.get(origin)
+			setClientHints(req, userAgent, sent)
+
+			resp, err := next(req)
+			if err != nil || !hints.update(origin, resp) {
+				return resp, err
+			}
+
+			if !missesCriticalHints(userAgent, resp, sent, hints.get(origin)) || !rewindBody(req) {
+				return resp, err
+			}
+
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+
+			setClientHints(req, userAgent, hints.get(origin))
+
+			return next(req)
+		}
+	}
+}
+
+// acceptedClientHints holds the hints requested by each origin through Accept-CH.
+type acceptedClientHints struct {
+	origins map[string][]string
+	sync.Mutex
+}
+
+func (h *acceptedClientHints) get(origin string) []string {
+	h.Lock()
+	defer h.Unlock()
+
+	return h.origins[origin]
+}
+
+// update stores the hints requested by the response of origin and reports whether they changed. Only secure origins
+// which answered the request themselves, without redirecting it, can request client hints.
+func (h *acceptedClientHints) update(origin string, resp *http.Response) bool {
+	values, ok := resp.Header["Accept-Ch"]
+	if !ok || !strings.HasPrefix(origin, "https://") || resp.Request == nil || resp.Request.URL.Scheme+"://"+resp.Request.URL.Host != origin {
+		return false
+	}
+
+	accepted := profiles.ParseAcceptCH(strings.Join(values, ","))
+
+	h.Lock()
+	defer h.Unlock()
+
+	previous := h.origins[origin]
+	h.origins[origin] = accepted
+
+	return strings.Join(previous, ",") != strings.Join(accepted, ",")
+}
+
+// missesCriticalHints reports whether the response lists critical hints which are accepted by the origin and known to
+// the user agent but which were not sent.
+func missesCriticalHints(userAgent *profiles.UserAgent, resp *http.Response, sent, accepted []string) bool {
+	for _, hint := range profiles.ParseAcceptCH(resp.Header.Get("Critical-Ch")) {
+		if _, ok := userAgent.Hint(hint); ok && containsHint(accepted, hint) && !containsHint(sent, hint) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setClientHints replaces the user-agent header and client hints of req with the ones of userAgent.
+func setClientHints(req *http.Request, userAgent *profiles.UserAgent, hints []string) {
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+
+	for name := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "user-agent" || strings.HasPrefix(lower, "sec-ch-ua") {
+			delete(req.Header, name)
+		}
+	}
+
+	for name, values := range userAgent.Headers(hints...) {
+		req.Header[name] = values
+
+		if order := req.Header[http.HeaderOrderKey]; len(order) > 0 && !containsHint(order, name) {
+			req.Header[http.HeaderOrderKey] = insertClientHintOrder(order, name)
+		}
+	}
+}
+
+// insertClientHintOrder inserts the client hint name in the header order after the last client hint, like Chrome
+// sends them, or at the end if the order has none.
+func insertClientHintOrder(order []string, name string) []string {
+	position := len(order)
+	for i, key := range order {
+		if strings.HasPrefix(strings.ToLower(key), "sec-ch-ua") {
+			position = i + 1
+		}
+	}
+
+	inserted := make([]string, 0, len(order)+1)
+	inserted = append(inserted, order[:position]...)
+	inserted = append(inserted, name)
+
+	return append(inserted, order[position:]...)
+}
+
+// rewindBody prepares the body of req to be sent again and reports whether it could.
+func rewindBody(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody {
+		return true
+	}
+
+	if req.GetBody == nil {
+		return false
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return false
+	}
+
+	req.Body = body
+
+	return true
+}
+
+func containsHint(hints []string, hint string) bool {
+	for _, h := range hints {
+		if strings.EqualFold(h, hint) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -49,6 +49,10 @@ type httpClientConfig struct {
 	badPinHandler      BadPinHandlerFunc
 	transportOptions   *TransportOptions
 	localAddr          *net.TCPAddr
+	retryPolicy        *RetryPolicy
+	fingerprintCheck   *FingerprintCheckOptions
+	harOptions         *HarOptions
+	middlewares        []Middleware
 
 	dialer             net.Dialer
 	proxyDialerFactory ProxyDialerFactory
@@ -64,6 +68,9 @@ type httpClientConfig struct {
 	insecureSkipVerify          bool
 	withRandomTlsExtensionOrder bool
 	forceHttp1                  bool
+	disableHttp3                bool
+	profileHeaders              bool
+	navigationHeaders           bool
 
 	// Establish a connection to origin server via ipv4 only
 	disableIPV6 bool
@@ -174,6 +181,15 @@ func WithCustomRedirectFunc(redirectFunc func(req *http.Request, via []*http.Req
 	}
 }
 
+// WithRedirectPolicy configures an HTTP client to follow redirects according to the given policy.
+// Use DefaultRedirectPolicy as a starting point. It replaces the redirect func set with WithCustomRedirectFunc and has
+// no effect if the client does not follow redirects.
+func WithRedirectPolicy(redirectPolicy RedirectPolicy) HttpClientOption {
+	return func(config *httpClientConfig) {
+		config.customRedirectFunc = redirectPolicy.CheckRedirect
+	}
+}
+
 // WithRandomTLSExtensionOrder configures a TLS client to randomize the order of TLS extensions being sent in the ClientHello.
 //
 // Placement of GREASE and padding is fixed and will not be affected by this.
@@ -194,6 +210,26 @@ func WithCertificatePinning(certificatePins map[string][]string, handlerFunc Bad
 	}
 }
 
+// WithRetryPolicy configures a client to retry failed requests according to the given policy.
+// Use DefaultRetryPolicy as a starting point.
+func WithRetryPolicy(retryPolicy RetryPolicy) HttpClientOption {
+	return func(config *httpClientConfig) {
+		config.retryPolicy = &retryPolicy
+	}
+}
+
+// WithMiddleware configures a client to pass every request through the given middlewares. They are applied in order, the first
+// one being the outermost, and can be configured multiple times.
+//
+// Middlewares wrap a whole call to Do: they run after the default headers are applied, once per call and around redirects,
+// retries and the cookie jar. The request they receive therefore does not carry the cookies of the jar yet, and the response
+// they receive is the final one, whose cookies were already stored in the jar.
+func WithMiddleware(middlewares ...Middleware) HttpClientOption {
+	return func(config *httpClientConfig) {
+		config.middlewares = append(config.middlewares, middlewares...)
+	}
+}
+
 // WithDebug configures a client to log debugging information.
 func WithDebug() HttpClientOption {
 	return func(config *httpClientConfig) {
@@ -229,6 +265,13 @@ func WithForceHttp1() HttpClientOption {
 	}
 }
 
+// WithDisableHttp3 configures a client to never switch to HTTP/3, even if the origin advertises it through the Alt-Svc header.
+func WithDisableHttp3() HttpClientOption {
+	return func(config *httpClientConfig) {
+		config.disableHttp3 = true
+	}
+}
+
 // WithClientProfile configures a TLS client to use the specified client profile.
 func WithClientProfile(clientProfile profiles.ClientProfile) HttpClientOption {
 	return func(config *httpClientConfig) {
@@ -243,6 +286,40 @@ func WithDefaultHeaders(defaultHeaders http.Header) HttpClientOption {
 	}
 }
 
+// WithProfileHeaders configures a TLS client to complete every request with the headers of its client profile for the
+// kind of request (see profiles.ClientProfile.GetHeaders), after the default headers are applied. Only the headers left
+// unset by the request are added, as well as the header order if the request has none. Like browsers, the headers are
+// sent in lowercase over HTTP/2 and HTTP/3 and in canonical case over HTTP/1.1, client hints excepted.
+// The kind of request is set with WithRequestKind, otherwise it is taken from the FetchContext of the request and
+// defaults to a navigation.
+func WithProfileHeaders() HttpClientOption {
+	return func(config *httpClientConfig) {
+		config.profileHeaders = true
+	}
+}
+
+// WithFingerprintConsistencyCheck configures a TLS client to check every request against its client profile before
+// sending it, once the protocol of the connection is known and for every hop of a redirect chain: the family and
+// version of the user agent, the client hints, the header case over HTTP/1.1, the accepted content codings and the
+// header and pseudo header order. The issues found are reported to options.OnIssues, or logged as warnings, and the
+// request is failed with a *FingerprintError if options.Reject is set.
+func WithFingerprintConsistencyCheck(options FingerprintCheckOptions) HttpClientOption {
+	return func(config *httpClientConfig) {
+		config.fingerprintCheck = &options
+	}
+}
+
+// WithNavigationHeaders configures a TLS client to set the Sec-Fetch-Site, Sec-Fetch-Mode, Sec-Fetch-Dest,
+// Sec-Fetch-User, Referer and Origin headers of every request and of every hop of its redirect chain like a browser,
+// replacing the ones of the request. They are computed from the FetchContext of the request (see WithFetchContext and
+// FetchContextFrom), following the referrer policy of the initiating document and of the redirect responses. Requests
+// without a FetchContext are browser initiated navigations, like an URL typed in the address bar.
+func WithNavigationHeaders() HttpClientOption {
+	return func(config *httpClientConfig) {
+		config.navigationHeaders = true
+	}
+}
+
 // WithServerNameOverwrite configures a TLS client to overwrite the server name being used for certificate verification and in the client hello.
 // This option does only work properly if WithInsecureSkipVerify is set to true in addition
 func WithServerNameOverwrite(serverName string) HttpClientOption {
@@ -272,6 +349,18 @@ func WithBandwidthTracker() HttpClientOption {
 	}
 }
 
+// WithHarRecorder configures a client to record every exchange it sends as an HTTP Archive (HAR 1.2) entry, each hop
+// of a redirect chain and each retry attempt included, with the headers in the order they are sent, the cookies, the
+// timings of the connection, the negotiated protocol and the bodies. The recorder is retrieved with GetHarRecorder.
+//
+// An exchange is recorded once its response body is read to EOF or closed, so the responses whose body is left
+// unclosed are missing from the archive.
+func WithHarRecorder(options HarOptions) HttpClientOption {
+	return func(config *httpClientConfig) {
+		config.harOptions = &options
+	}
+}
+
 // WithConnectHeaders configures a client to use the specified headers for the CONNECT request.
 func WithConnectHeaders(headers http.Header) HttpClientOption {
 	return func(config *httpClientConfig) {
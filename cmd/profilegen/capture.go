@@ -0,0 +1,624 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Mathious6/httpkit"
+	"github.com/Mathious6/httpkit/profiles"
+	tls "github.com/bogdanfinn/utls"
+	"github.com/bogdanfinn/utls/dicttls"
+)
+
+// peetCapture is the JSON returned by https://tls.peet.ws/api/all, like tests.TlsApiResponse, limited to the fields a
+// profile is made of.
+type peetCapture struct {
+	TLS *struct {
+		Ciphers    []string        `json:"ciphers"`
+		Extensions []peetExtension `json:"extensions"`
+	} `json:"tls"`
+	HTTP2 *struct {
+		AkamaiFingerprint string `json:"akamai_fingerprint"`
+		SentFrames        []struct {
+			FrameType string   `json:"frame_type"`
+			Headers   []string `json:"headers"`
+			Flags     []string `json:"flags"`
+			Priority  *struct {
+				Weight    int `json:"weight"`
+				DependsOn int `json:"depends_on"`
+				Exclusive int `json:"exclusive"`
+			} `json:"priority"`
+		} `json:"sent_frames"`
+	} `json:"http2"`
+	HTTP1 *struct {
+		Headers []string `json:"headers"`
+	} `json:"http1"`
+}
+
+// peetExtension is a ClientHello extension of a peetCapture. Its name ends with its id in parentheses, like
+// "supported_groups (10)", or is the GREASE value, like "TLS_GREASE (0x7a7a)".
+type peetExtension struct {
+	Name                string              `json:"name"`
+	Data                string              `json:"data"`
+	SupportedGroups     []string            `json:"supported_groups"`
+	PointFormats        []any               `json:"elliptic_curves_point_formats"`
+	SignatureAlgorithms []string            `json:"signature_algorithms"`
+	Protocols           []string            `json:"protocols"`
+	Versions            []string            `json:"versions"`
+	SharedKeys          []map[string]string `json:"shared_keys"`
+	PskKeyExchangeMode  string              `json:"PSK_Key_Exchange_Mode"`
+	Algorithms          []string            `json:"algorithms"`
+}
+
+// The TLS versions of a peetCapture by their name.
+var peetVersions = map[string]uint16{
+	"TLS 1.3": tls.VersionTLS13,
+	"TLS 1.2": tls.VersionTLS12,
+	"TLS 1.1": tls.VersionTLS11,
+	"TLS 1.0": tls.VersionTLS10,
+}
+
+// readCapture returns the profile file of the capture input, with the client hello and HTTP/2 fingerprint of opts.
+func readCapture(input []byte, opts options) (*profiles.ProfileFile, error) {
+	var (
+		file *profiles.ProfileFile
+		err  error
+	)
+
+	trimmed := bytes.TrimSpace(input)
+
+	if hello, ok := decodeHex(trimmed); ok {
+		file, err = fromClientHello(hello)
+	} else if isPeetCapture(trimmed) {
+		file, err = fromPeetCapture(trimmed, opts.kind)
+	} else {
+		file, err = profiles.ReadProfileFile(bytes.NewReader(input))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	client, version, _ := strings.Cut(opts.name, "_")
+
+	if opts.client != "" {
+		file.ClientHello.Client = opts.client
+	} else if file.ClientHello.Client == "" {
+		file.ClientHello.Client = client
+	}
+
+	if opts.version != "" {
+		file.ClientHello.Version = opts.version
+	} else if file.ClientHello.Version == "" {
+		file.ClientHello.Version = version
+	}
+
+	if opts.akamai != "" {
+		if err := applyAkamai(file, opts.akamai); err != nil {
+			return nil, err
+		}
+	}
+
+	if file.ClientHello.Spec != nil {
+		if err := normalizeSpec(file.ClientHello.Spec); err != nil {
+			return nil, err
+		}
+	}
+
+	return file, nil
+}
+
+// decodeHex decodes a hex dump, ignoring the white space.
+func decodeHex(dump []byte) ([]byte, bool) {
+	compact := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+
+		return r
+	}, string(dump))
+
+	decoded, err := hex.DecodeString(compact)
+	if err != nil || len(decoded) == 0 {
+		return nil, false
+	}
+
+	return decoded, true
+}
+
+func isPeetCapture(input []byte) bool {
+	var capture struct {
+		TLS json.RawMessage `json:"tls"`
+	}
+
+	return json.Unmarshal(input, &capture) == nil && capture.TLS != nil
+}
+
+// fromClientHello returns the profile file of a raw ClientHello, which may lack its record header.
+func fromClientHello(hello []byte) (*profiles.ProfileFile, error) {
+	if hello[0] == 0x01 {
+		hello = append([]byte{0x16, 0x03, 0x01, byte(len(hello) >> 8), byte(len(hello))}, hello...)
+	}
+
+	spec, err := (&tls.Fingerprinter{AllowBluntMimicry: true, RealPSKResumption: true}).FingerprintClientHello(hello)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client hello: %w", err)
+	}
+
+	// The extensions hold the values of the captured connection, only their settings are kept.
+	for i, extension := range spec.Extensions {
+		switch ext := extension.(type) {
+		case *tls.FakeDelegatedCredentialsExtension:
+			spec.Extensions[i] = &tls.DelegatedCredentialsExtension{SupportedSignatureAlgorithms: ext.SupportedSignatureAlgorithms}
+		case *tls.UtlsPreSharedKeyExtension:
+			spec.Extensions[i] = &tls.UtlsPreSharedKeyExtension{}
+		case *tls.SessionTicketExtension:
+			spec.Extensions[i] = &tls.SessionTicketExtension{}
+		case *tls.UtlsPaddingExtension:
+			spec.Extensions[i] = &tls.UtlsPaddingExtension{GetPaddingLen: tls.BoringPaddingStyle}
+		case *tls.GREASEEncryptedClientHelloExtension:
+			spec.Extensions[i] = &tls.GREASEEncryptedClientHelloExtension{
+				CandidateCipherSuites: ext.CandidateCipherSuites,
+				CandidatePayloadLens:  ext.CandidatePayloadLens,
+			}
+		}
+	}
+
+	return exportSpec(*spec)
+}
+
+// exportSpec returns the profile file of a profile sending spec.
+func exportSpec(spec tls.ClientHelloSpec) (*profiles.ProfileFile, error) {
+	profile := profiles.NewClientProfile(tls.ClientHelloID{
+		SpecFactory: func() (tls.ClientHelloSpec, error) {
+			return spec, nil
+		},
+	}, nil, nil, nil, 0, nil, nil, nil)
+
+	return profile.Export()
+}
+
+// fromPeetCapture returns the profile file of a peetCapture, with the captured headers for the request kind.
+func fromPeetCapture(input []byte, kind string) (*profiles.ProfileFile, error) {
+	var capture peetCapture
+	if err := json.Unmarshal(input, &capture); err != nil {
+		return nil, fmt.Errorf("invalid capture: %w", err)
+	}
+
+	spec := &profiles.ProfileClientHelloSpec{CompressionMethods: []uint16{0}}
+
+	for _, cipher := range capture.TLS.Ciphers {
+		id, err := lookup(cipher, dicttls.DictCipherSuiteNameIndexed)
+		if err != nil {
+			return nil, fmt.Errorf("cipher suite %w", err)
+		}
+
+		spec.CipherSuites = append(spec.CipherSuites, id)
+	}
+
+	for _, e := range capture.TLS.Extensions {
+		extension, err := e.extension()
+		if err != nil {
+			return nil, fmt.Errorf("extension %s: %w", e.Name, err)
+		}
+
+		spec.Extensions = append(spec.Extensions, extension)
+	}
+
+	file := &profiles.ProfileFile{ClientHello: profiles.ProfileClientHello{Spec: spec}}
+
+	var headers []string
+
+	if capture.HTTP2 != nil {
+		if capture.HTTP2.AkamaiFingerprint != "" {
+			if err := applyAkamai(file, capture.HTTP2.AkamaiFingerprint); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, frame := range capture.HTTP2.SentFrames {
+			if frame.FrameType != "HEADERS" {
+				continue
+			}
+
+			headers = frame.Headers
+
+			if frame.Priority != nil && hasFlag(frame.Flags, "Priority") {
+				file.HTTP2.HeaderPriority = &profiles.ProfilePriorityParam{
+					StreamDep: uint32(frame.Priority.DependsOn),
+					Exclusive: frame.Priority.Exclusive == 1,
+					Weight:    uint8(frame.Priority.Weight - 1),
+				}
+			}
+
+			break
+		}
+	}
+
+	if headers == nil && capture.HTTP1 != nil {
+		headers = capture.HTTP1.Headers
+	}
+
+	if len(headers) > 0 {
+		file.Headers = map[string]profiles.ProfileHeaders{kind: capturedHeaders(headers)}
+	}
+
+	return file, nil
+}
+
+// extension returns the profile file extension of e.
+func (e peetExtension) extension() (profiles.ProfileExtension, error) {
+	if isGREASEName(e.Name) {
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypeGREASE}, nil
+	}
+
+	id, err := parenValue(e.Name)
+	if err != nil {
+		return profiles.ProfileExtension{}, err
+	}
+
+	data, err := hex.DecodeString(e.Data)
+	if err != nil {
+		return profiles.ProfileExtension{}, fmt.Errorf("invalid data %s", e.Data)
+	}
+
+	switch id {
+	case dicttls.ExtType_server_name:
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypeServerName}, nil
+	case dicttls.ExtType_status_request:
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypeStatusRequest}, nil
+	case dicttls.ExtType_supported_groups:
+		curves, err := lookupAll(e.SupportedGroups, dicttls.DictSupportedGroupsNameIndexed)
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypeSupportedCurves, Curves: curves}, err
+	case dicttls.ExtType_ec_point_formats:
+		points := make([]uint16, 0, len(e.PointFormats))
+		for _, point := range e.PointFormats {
+			value, err := strconv.ParseUint(fmt.Sprint(point), 0, 8)
+			if err != nil {
+				return profiles.ProfileExtension{}, fmt.Errorf("invalid point format %v", point)
+			}
+
+			points = append(points, uint16(value))
+		}
+
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypeSupportedPoints, Points: points}, nil
+	case dicttls.ExtType_signature_algorithms:
+		algorithms, err := lookupAll(e.SignatureAlgorithms, dicttls.DictSignatureSchemeNameIndexed)
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypeSignatureAlgorithms, SignatureAlgorithms: algorithms}, err
+	case dicttls.ExtType_delegated_credentials:
+		if e.SignatureAlgorithms == nil {
+			algorithms, err := uint16List(data)
+			return profiles.ProfileExtension{Type: profiles.ExtensionTypeDelegatedCredentials, SignatureAlgorithms: algorithms}, err
+		}
+
+		algorithms, err := lookupAll(e.SignatureAlgorithms, dicttls.DictSignatureSchemeNameIndexed)
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypeDelegatedCredentials, SignatureAlgorithms: algorithms}, err
+	case dicttls.ExtType_application_layer_protocol_negotiation:
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypeALPN, Protocols: e.Protocols}, nil
+	case tls.ExtensionALPSOld:
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypeApplicationSettings, Protocols: e.Protocols}, nil
+	case tls.ExtensionALPS:
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypeApplicationSettingsNew, Protocols: e.Protocols}, nil
+	case dicttls.ExtType_signed_certificate_timestamp:
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypeSCT}, nil
+	case dicttls.ExtType_padding:
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypePadding, Padding: "boring"}, nil
+	case dicttls.ExtType_extended_master_secret:
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypeExtendedMasterSecret}, nil
+	case dicttls.ExtType_session_ticket:
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypeSessionTicket}, nil
+	case dicttls.ExtType_pre_shared_key:
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypePreSharedKey}, nil
+	case dicttls.ExtType_supported_versions:
+		versions := make([]uint16, 0, len(e.Versions))
+		for _, name := range e.Versions {
+			version, err := lookup(name, peetVersions)
+			if err != nil {
+				return profiles.ProfileExtension{}, fmt.Errorf("version %w", err)
+			}
+
+			versions = append(versions, version)
+		}
+
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypeSupportedVersions, Versions: versions}, nil
+	case dicttls.ExtType_psk_key_exchange_modes:
+		mode, err := parenValue(e.PskKeyExchangeMode)
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypePSKKeyExchangeModes, Modes: []uint16{mode}}, err
+	case dicttls.ExtType_key_share:
+		extension := profiles.ProfileExtension{Type: profiles.ExtensionTypeKeyShare}
+
+		for _, sharedKey := range e.SharedKeys {
+			for name, key := range sharedKey {
+				group, err := lookup(name, dicttls.DictSupportedGroupsNameIndexed)
+				if err != nil {
+					return profiles.ProfileExtension{}, fmt.Errorf("key share %w", err)
+				}
+
+				keyShare := profiles.ProfileKeyShare{Group: group}
+				if isGREASE(group) {
+					keyShare.Data, _ = hex.DecodeString(key)
+				}
+
+				extension.KeyShares = append(extension.KeyShares, keyShare)
+			}
+		}
+
+		return extension, nil
+	case dicttls.ExtType_compress_certificate:
+		algorithms, err := lookupAll(e.Algorithms, dicttls.DictCertificateCompressionAlgorithmNameIndexed)
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypeCompressCertificate, Algorithms: algorithms}, err
+	case dicttls.ExtType_record_size_limit:
+		if len(data) != 2 {
+			return profiles.ProfileExtension{}, errors.New("record size limit without data")
+		}
+
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypeRecordSizeLimit, Limit: uint16(data[0])<<8 | uint16(data[1])}, nil
+	case dicttls.ExtType_renegotiation_info:
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypeRenegotiationInfo, Renegotiation: int(tls.RenegotiateOnceAsClient)}, nil
+	case tls.ExtensionECH:
+		return echExtension(data)
+	default:
+		return profiles.ProfileExtension{Type: profiles.ExtensionTypeGeneric, ID: id, Data: data}, nil
+	}
+}
+
+// echExtension returns the GREASE ECH extension of the captured data, or the one of BoringSSL without data.
+func echExtension(data []byte) (profiles.ProfileExtension, error) {
+	if len(data) == 0 {
+		return boringECH()
+	}
+
+	ech := &tls.GREASEEncryptedClientHelloExtension{}
+	if _, err := ech.Write(data); err != nil {
+		return profiles.ProfileExtension{}, err
+	}
+
+	file, err := exportSpec(tls.ClientHelloSpec{Extensions: []tls.TLSExtension{&tls.GREASEEncryptedClientHelloExtension{
+		CandidateCipherSuites: ech.CandidateCipherSuites,
+		CandidatePayloadLens:  ech.CandidatePayloadLens,
+	}}})
+	if err != nil {
+		return profiles.ProfileExtension{}, err
+	}
+
+	return file.ClientHello.Spec.Extensions[0], nil
+}
+
+// boringECH returns the GREASE ECH extension of BoringSSL, sent by Chrome.
+func boringECH() (profiles.ProfileExtension, error) {
+	file, err := exportSpec(tls.ClientHelloSpec{Extensions: []tls.TLSExtension{tls.BoringGREASEECH()}})
+	if err != nil {
+		return profiles.ProfileExtension{}, err
+	}
+
+	return file.ClientHello.Spec.Extensions[0], nil
+}
+
+// normalizeSpec replaces the values of the captured connection by the placeholders the TLS library fills in.
+func normalizeSpec(spec *profiles.ProfileClientHelloSpec) error {
+	boring, err := boringECH()
+	if err != nil {
+		return err
+	}
+
+	spec.CipherSuites = ungrease(spec.CipherSuites)
+
+	for i := range spec.Extensions {
+		e := &spec.Extensions[i]
+
+		switch e.Type {
+		case profiles.ExtensionTypeGREASE:
+			e.Value, e.Data = 0, nil
+		case profiles.ExtensionTypeServerName:
+			e.ServerName = ""
+		case profiles.ExtensionTypePadding:
+			e.Padding, e.PaddingLength = "boring", 0
+		case profiles.ExtensionTypeSupportedCurves:
+			e.Curves = ungrease(e.Curves)
+		case profiles.ExtensionTypeSupportedVersions:
+			e.Versions = ungrease(e.Versions)
+		case profiles.ExtensionTypeKeyShare:
+			for j := range e.KeyShares {
+				if isGREASE(e.KeyShares[j].Group) {
+					e.KeyShares[j].Group = tls.GREASE_PLACEHOLDER
+				} else {
+					e.KeyShares[j].Data = nil
+				}
+			}
+		case profiles.ExtensionTypeGREASEECH:
+			e.Data, e.ConfigIDs = nil, nil
+
+			// A capture shows one of the candidates of BoringSSL, which are then all sent.
+			if subsetOf(e.CipherSuites, boring.CipherSuites) && subsetOf(e.PayloadLengths, boring.PayloadLengths) {
+				*e = boring
+			}
+		}
+	}
+
+	return nil
+}
+
+// applyAkamai sets the HTTP/2 fingerprint of file to the Akamai fingerprint akamai.
+func applyAkamai(file *profiles.ProfileFile, akamai string) error {
+	fingerprint, err := httpkit.ParseAkamaiFingerprint(akamai)
+	if err != nil {
+		return err
+	}
+
+	h2 := profiles.ProfileHTTP2{
+		ConnectionFlow:    fingerprint.ConnectionFlow,
+		HeaderPriority:    file.HTTP2.HeaderPriority,
+		PseudoHeaderOrder: fingerprint.PseudoHeaderOrder,
+	}
+
+	for _, id := range fingerprint.SettingsOrder {
+		h2.SettingsOrder = append(h2.SettingsOrder, uint16(id))
+		h2.Settings = append(h2.Settings, profiles.ProfileSetting{ID: uint16(id), Value: fingerprint.Settings[id]})
+	}
+
+	sort.Slice(h2.Settings, func(i, j int) bool { return h2.Settings[i].ID < h2.Settings[j].ID })
+
+	for _, priority := range fingerprint.Priorities {
+		h2.Priorities = append(h2.Priorities, profiles.ProfilePriority{
+			StreamID: priority.StreamID,
+			ProfilePriorityParam: profiles.ProfilePriorityParam{
+				StreamDep: priority.PriorityParam.StreamDep,
+				Exclusive: priority.PriorityParam.Exclusive,
+				Weight:    priority.PriorityParam.Weight,
+			},
+		})
+	}
+
+	file.HTTP2 = h2
+
+	return nil
+}
+
+// capturedHeaders returns the headers of the captured "name: value" lines, without the pseudo headers.
+func capturedHeaders(lines []string) profiles.ProfileHeaders {
+	headers := profiles.ProfileHeaders{}
+	seen := make(map[string]bool, len(lines))
+
+	for _, line := range lines {
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		name, value, _ := strings.Cut(line, ":")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		if !seen[name] {
+			seen[name] = true
+			headers.Order = append(headers.Order, name)
+		}
+
+		headers.Values = append(headers.Values, profiles.ProfileHeader{Name: name, Value: strings.TrimSpace(value)})
+	}
+
+	return headers
+}
+
+func hasFlag(flags []string, flag string) bool {
+	for _, f := range flags {
+		if strings.HasPrefix(f, flag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lookup returns the id of a name of a capture: the GREASE placeholder for GREASE values, the value of names, else the
+// id in parentheses at the end of the name, like in "X25519 (29)".
+func lookup(name string, ids map[string]uint16) (uint16, error) {
+	if isGREASEName(name) {
+		return tls.GREASE_PLACEHOLDER, nil
+	}
+
+	if id, ok := ids[name]; ok {
+		return id, nil
+	}
+
+	return parenValue(name)
+}
+
+func lookupAll(names []string, ids map[string]uint16) ([]uint16, error) {
+	values := make([]uint16, 0, len(names))
+	for _, name := range names {
+		value, err := lookup(name, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// parenValue returns the decimal or hexadecimal number in parentheses at the end of s.
+func parenValue(s string) (uint16, error) {
+	start, end := strings.LastIndex(s, "("), strings.LastIndex(s, ")")
+	if start < 0 || end < start {
+		return 0, fmt.Errorf("%q has no value", s)
+	}
+
+	inner := s[start+1 : end]
+
+	var (
+		value uint64
+		err   error
+	)
+
+	if hexValue, ok := strings.CutPrefix(strings.ToLower(inner), "0x"); ok {
+		value, err = strconv.ParseUint(hexValue, 16, 16)
+	} else {
+		value, err = strconv.ParseUint(inner, 10, 16)
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("%q has no valid value", s)
+	}
+
+	return uint16(value), nil
+}
+
+// uint16List parses a list of uint16 prefixed by its length in bytes.
+func uint16List(data []byte) ([]uint16, error) {
+	if len(data) < 2 || int(data[0])<<8|int(data[1]) != len(data)-2 || len(data)%2 != 0 {
+		return nil, errors.New("invalid list")
+	}
+
+	values := make([]uint16, 0, len(data)/2-1)
+	for i := 2; i < len(data); i += 2 {
+		values = append(values, uint16(data[i])<<8|uint16(data[i+1]))
+	}
+
+	return values, nil
+}
+
+func isGREASEName(name string) bool {
+	return strings.HasPrefix(name, "TLS_GREASE")
+}
+
+// isGREASE reports whether value is a GREASE value of RFC 8701, like 0x0a0a or 0x7a7a.
+func isGREASE(value uint16) bool {
+	return value&0x0f0f == 0x0a0a && value>>8 == value&0xff
+}
+
+func ungrease(values []uint16) []uint16 {
+	for i, value := range values {
+		if isGREASE(value) {
+			values[i] = tls.GREASE_PLACEHOLDER
+		}
+	}
+
+	return values
+}
+
+// subsetOf reports whether every element of a is an element of b.
+func subsetOf[T any](a []T, b []T) bool {
+	for _, x := range a {
+		found := false
+		for _, y := range b {
+			if reflect.DeepEqual(x, y) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,137 @@
+// Command profilegen generates the Go source of a client profile from a capture of the client, so that keeping up with
+// browser releases is a capture-and-generate step:
+//
+//	//go:generate go run github.com/Mathious6/httpkit/cmd/profilegen -in captures/chrome_140.json -name Chrome_140 -out chrome_140_profile.go
+//
+// The capture is one of:
+//
+//   - the JSON returned by https://tls.peet.ws/api/all, with the ClientHello, the HTTP/2 fingerprint and the headers
+//   - a ClientHello dumped as hex, with or without its record header; -akamai then gives the HTTP/2 fingerprint
+//   - a profile file in JSON or YAML, as read by profiles.LoadProfile
+//
+// The generated file belongs to the profiles package. It declares the profile under -name and registers it in
+// profiles.MappedTLSClients under -key. The values which change on every connection, like the key shares or the server
+// name, are left to the TLS library. Name the output like chrome_140_profile.go rather than chrome_ios.go, whose suffix
+// is a build constraint.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// options are the command line options of profilegen.
+type options struct {
+	name    string
+	key     string
+	client  string
+	version string
+	akamai  string
+	kind    string
+	source  string
+}
+
+func main() {
+	var in, out string
+
+	opts := options{}
+
+	flag.StringVar(&in, "in", "", "capture to read, - for stdin")
+	flag.StringVar(&out, "out", "", "Go file to write, stdout if empty")
+	flag.StringVar(&opts.name, "name", "", "name of the profile variable, like Chrome_140")
+	flag.StringVar(&opts.key, "key", "", "key of the profile in MappedTLSClients, the lowercase name if empty")
+	flag.StringVar(&opts.client, "client", "", "client of the ClientHelloID, the one of the profile file or the name up to its first underscore if empty")
+	flag.StringVar(&opts.version, "version", "", "version of the ClientHelloID, the one of the profile file or the name after its first underscore if empty")
+	flag.StringVar(&opts.akamai, "akamai", "", "Akamai HTTP/2 fingerprint, replacing the one of the capture")
+	flag.StringVar(&opts.kind, "kind", "navigation", "kind of request of the captured headers: navigation, fetch or image")
+	flag.Parse()
+
+	if in == "" || opts.name == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(in, out, opts); err != nil {
+		fmt.Fprintln(os.Stderr, "profilegen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(in string, out string, opts options) error {
+	var (
+		input []byte
+		err   error
+	)
+
+	if in == "-" {
+		input, err = io.ReadAll(os.Stdin)
+		opts.source = "stdin"
+	} else {
+		input, err = os.ReadFile(in)
+		opts.source = in
+	}
+
+	if err != nil {
+		return err
+	}
+
+	source, err := generate(input, opts)
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		_, err = os.Stdout.Write(source)
+		return err
+	}
+
+	return os.WriteFile(out, source, 0o644)
+}
+
+// generate returns the Go source of the profile captured in input.
+func generate(input []byte, opts options) ([]byte, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := readCapture(input, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := file.Profile(); err != nil {
+		return nil, fmt.Errorf("invalid profile: %w", err)
+	}
+
+	return render(file, opts)
+}
+
+func (o options) withDefaults() (options, error) {
+	if !isIdentifier(o.name) {
+		return o, fmt.Errorf("%q is not an exported Go identifier", o.name)
+	}
+
+	if o.key == "" {
+		o.key = strings.ToLower(o.name)
+	}
+
+	if _, ok := requestKindNames[o.kind]; !ok {
+		return o, fmt.Errorf("unknown request kind %s", o.kind)
+	}
+
+	return o, nil
+}
+
+func isIdentifier(name string) bool {
+	for i, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+
+	return name != "" && name[0] >= 'A' && name[0] <= 'Z'
+}
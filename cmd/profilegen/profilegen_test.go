@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/Mathious6/httpkit/profiles"
+	http "github.com/bogdanfinn/fhttp"
+	tls "github.com/bogdanfinn/utls"
+	"github.com/stretchr/testify/assert"
+)
+
+// A capture of https://tls.peet.ws/api/all, trimmed to the fields profilegen reads.
+const peetCaptureSample = `{
+  "tls": {
+    "ja3": "771,4865-4866-49195-49199,0-23-65281-10-11-16-13-51-45-43-27,29-23-24,0",
+    "ciphers": ["TLS_GREASE (0x3A3A)", "TLS_AES_128_GCM_SHA256", "TLS_AES_256_GCM_SHA384", "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"],
+    "extensions": [
+      {"name": "TLS_GREASE (0x8a8a)"},
+      {"name": "server_name (0)", "server_name": "tls.peet.ws"},
+      {"name": "extended_master_secret (23)", "master_secret_data": "", "extended_master_secret_data": ""},
+      {"name": "extensionRenegotiationInfo (boringssl) (65281)", "data": "00"},
+      {"name": "supported_groups (10)", "supported_groups": ["TLS_GREASE (0x4a4a)", "X25519 (29)", "P-256 (23)", "P-384 (24)"]},
+      {"name": "ec_point_formats (11)", "elliptic_curves_point_formats": ["0x00"]},
+      {"name": "application_layer_protocol_negotiation (16)", "protocols": ["h2", "http/1.1"]},
+      {"name": "signature_algorithms (13)", "signature_algorithms": ["ecdsa_secp256r1_sha256", "rsa_pss_rsae_sha256", "rsa_pkcs1_sha256"]},
+      {"name": "key_share (51)", "shared_keys": [{"TLS_GREASE (0x4a4a)": "00"}, {"X25519 (29)": "2a4d7b9f6c1e8a3b5d0f2e4c6a8b1d3f5e7a9c0b2d4f6e8a1c3b5d7f9e0a2c4b"}]},
+      {"name": "psk_key_exchange_modes (45)", "PSK_Key_Exchange_Mode": "PSK with (EC)DHE key establishment (psk_dhe_ke) (1)"},
+      {"name": "supported_versions (43)", "versions": ["TLS_GREASE (0x5a5a)", "TLS 1.3", "TLS 1.2"]},
+      {"name": "compress_certificate (27)", "algorithms": ["brotli (2)"]},
+      {"name": "TLS_GREASE (0x9a9a)"}
+    ]
+  },
+  "http2": {
+    "akamai_fingerprint": "1:65536;2:0;4:6291456;6:262144|15663105|0|m,a,s,p",
+    "sent_frames": [
+      {"frame_type": "SETTINGS", "length": 24},
+      {"frame_type": "HEADERS", "stream_id": 1, "headers": [":method: GET", ":authority: tls.peet.ws", ":scheme: https", ":path: /api/all", "sec-ch-ua: \"Chromium\";v=\"140\"", "user-agent: Mozilla/5.0", "accept: */*"], "flags": ["EndStream (0x1)", "EndHeaders (0x4)", "Priority (0x20)"], "priority": {"weight": 256, "depends_on": 0, "exclusive": 1}}
+    ]
+  }
+}`
+
+func TestGenerate_ClientHello(t *testing.T) {
+	conn := tls.UClient(nil, &tls.Config{ServerName: "example.com", OmitEmptyPsk: true}, profiles.Chrome_133.GetClientHelloId(), false, false)
+	if err := conn.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := options{name: "Chrome_133", key: "chrome_133_captured", kind: "navigation", source: "chrome_133.hex"}.withDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts.akamai = profiles.Chrome_133.GetAkamaiFingerprint()
+
+	file, err := readCapture([]byte(hex.EncodeToString(conn.HandshakeState.Hello.Raw)), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profile, err := file.Profile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := profiles.Chrome_133.GetTLSFingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := profile.GetTLSFingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, expected.Ja4R, actual.Ja4R, "Expected the captured ClientHello to be sent again")
+	assert.Equal(t, profiles.Chrome_133.GetAkamaiFingerprint(), profile.GetAkamaiFingerprint())
+
+	source, err := render(file, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertValidSource(t, source)
+	assert.Contains(t, string(source), "tls.BoringGREASEECH()", "Expected the GREASE ECH of Chrome to be recognized")
+	assert.Contains(t, string(source), `MappedTLSClients["chrome_133_captured"] = Chrome_133`)
+}
+
+func TestGenerate_PeetCapture(t *testing.T) {
+	opts, err := options{name: "Chrome_140", kind: "navigation", source: "captures/chrome_140.json"}.withDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := readCapture([]byte(peetCaptureSample), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profile, err := file.Profile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var capture struct {
+		TLS struct {
+			Ja3 string `json:"ja3"`
+		} `json:"tls"`
+	}
+
+	if err := json.Unmarshal([]byte(peetCaptureSample), &capture); err != nil {
+		t.Fatal(err)
+	}
+
+	fingerprint, err := profile.GetTLSFingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, capture.TLS.Ja3, fingerprint.Ja3)
+	assert.Equal(t, "1:65536;2:0;4:6291456;6:262144|15663105|0|m,a,s,p", profile.GetAkamaiFingerprint())
+	assert.Equal(t, "Chrome-140", profile.GetClientHelloStr())
+	assert.Equal(t, []string{"sec-ch-ua", "user-agent", "accept"}, profile.GetHeaders(profiles.RequestKindNavigation)[http.HeaderOrderKey])
+	assert.Equal(t, uint8(255), profile.GetHeaderPriority().Weight)
+
+	source, err := render(file, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertValidSource(t, source)
+	assert.True(t, strings.HasPrefix(string(source), "// Code generated by profilegen from chrome_140.json. DO NOT EDIT."))
+	assert.Contains(t, string(source), "`\"Chromium\";v=\"140\"`")
+	assert.Contains(t, string(source), `MappedTLSClients["chrome_140"] = Chrome_140`)
+}
+
+func TestGenerate_ProfileFile(t *testing.T) {
+	tests := map[string]struct {
+		profile  profiles.ClientProfile
+		expected []string
+	}{
+		"Firefox_135": {
+			profile:  profiles.Firefox_135,
+			expected: []string{"&tls.DelegatedCredentialsExtension{", "dicttls.AEAD_AES_128_GCM", "http.HeaderOrderKey"},
+		},
+		"Chrome_133": {
+			profile:  profiles.Chrome_133,
+			expected: []string{"tls.BoringGREASEECH()", "quicProfile: &QUICProfile{", "QUICTransportParameterMaxIdleTimeout"},
+		},
+	}
+
+	for name, test := range tests {
+		exported, err := test.profile.Export()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		input, err := json.Marshal(exported)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		source, err := generate(input, options{name: name, key: strings.ToLower(name) + "_generated", kind: "navigation", source: "profile.json"})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		assertValidSource(t, source)
+
+		for _, expected := range test.expected {
+			assert.Contains(t, string(source), expected, "Expected the source of %s to contain %s", name, expected)
+		}
+	}
+}
+
+func TestGenerate_InvalidOptions(t *testing.T) {
+	_, err := generate([]byte(peetCaptureSample), options{name: "chrome_140", kind: "navigation"})
+	assert.Error(t, err, "Expected unexported names to be rejected")
+
+	_, err = generate([]byte(peetCaptureSample), options{name: "Chrome_140", kind: "xhr"})
+	assert.Error(t, err, "Expected unknown request kinds to be rejected")
+}
+
+func assertValidSource(t *testing.T, source []byte) {
+	t.Helper()
+
+	file, err := parser.ParseFile(token.NewFileSet(), "profile.go", source, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("invalid source: %v\n%s", err, source)
+	}
+
+	assert.Equal(t, "profiles", file.Name.Name)
+	assert.True(t, bytes.Contains(source, []byte("func init() {")))
+}
@@ -0,0 +1,603 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Mathious6/httpkit/profiles"
+	"github.com/bogdanfinn/fhttp/http2"
+	tls "github.com/bogdanfinn/utls"
+	"github.com/bogdanfinn/utls/dicttls"
+)
+
+// The imports of the generated files, by their name in the generated code.
+var importPaths = map[string]string{
+	"sha256":  `"crypto/sha256"`,
+	"http":    `http "github.com/bogdanfinn/fhttp"`,
+	"http2":   `"github.com/bogdanfinn/fhttp/http2"`,
+	"tls":     `tls "github.com/bogdanfinn/utls"`,
+	"dicttls": `"github.com/bogdanfinn/utls/dicttls"`,
+}
+
+// The request kinds of the profile files by their name, with the name of their constant.
+var requestKindNames = map[string]string{
+	profiles.RequestKindNavigation.String(): "RequestKindNavigation",
+	profiles.RequestKindFetch.String():      "RequestKindFetch",
+	profiles.RequestKindImage.String():      "RequestKindImage",
+}
+
+// The constants of the TLS values, written in place of the numbers.
+var (
+	cipherSuiteNames = map[uint16]string{
+		tls.GREASE_PLACEHOLDER:                               "tls.GREASE_PLACEHOLDER",
+		tls.TLS_AES_128_GCM_SHA256:                           "tls.TLS_AES_128_GCM_SHA256",
+		tls.TLS_AES_256_GCM_SHA384:                           "tls.TLS_AES_256_GCM_SHA384",
+		tls.TLS_CHACHA20_POLY1305_SHA256:                     "tls.TLS_CHACHA20_POLY1305_SHA256",
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256:          "tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256:            "tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384:          "tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384:            "tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+		tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256:    "tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256",
+		tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256:      "tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA:             "tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA",
+		tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA:               "tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA:             "tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA",
+		tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA:               "tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256:          "tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256",
+		tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256:            "tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256",
+		tls.DISABLED_TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384: "tls.DISABLED_TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384",
+		tls.DISABLED_TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384:   "tls.DISABLED_TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384",
+		tls.TLS_ECDHE_ECDSA_WITH_3DES_EDE_CBC_SHA:            "tls.TLS_ECDHE_ECDSA_WITH_3DES_EDE_CBC_SHA",
+		tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA:              "tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA",
+		tls.TLS_RSA_WITH_AES_128_GCM_SHA256:                  "tls.TLS_RSA_WITH_AES_128_GCM_SHA256",
+		tls.TLS_RSA_WITH_AES_256_GCM_SHA384:                  "tls.TLS_RSA_WITH_AES_256_GCM_SHA384",
+		tls.TLS_RSA_WITH_AES_128_CBC_SHA:                     "tls.TLS_RSA_WITH_AES_128_CBC_SHA",
+		tls.TLS_RSA_WITH_AES_256_CBC_SHA:                     "tls.TLS_RSA_WITH_AES_256_CBC_SHA",
+		tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA:                    "tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA",
+		tls.FAKE_TLS_EMPTY_RENEGOTIATION_INFO_SCSV:           "tls.FAKE_TLS_EMPTY_RENEGOTIATION_INFO_SCSV",
+	}
+
+	curveNames = map[uint16]string{
+		tls.GREASE_PLACEHOLDER:            "tls.GREASE_PLACEHOLDER",
+		uint16(tls.CurveP256):             "tls.CurveP256",
+		uint16(tls.CurveP384):             "tls.CurveP384",
+		uint16(tls.CurveP521):             "tls.CurveP521",
+		uint16(tls.X25519):                "tls.X25519",
+		uint16(tls.X25519MLKEM768):        "tls.X25519MLKEM768",
+		uint16(tls.X25519Kyber768Draft00): "tls.X25519Kyber768Draft00",
+		uint16(tls.FAKEFFDHE2048):         "tls.CurveID(tls.FAKEFFDHE2048)",
+		uint16(tls.FAKEFFDHE3072):         "tls.CurveID(tls.FAKEFFDHE3072)",
+	}
+
+	signatureSchemeNames = map[uint16]string{
+		uint16(tls.PKCS1WithSHA256):        "tls.PKCS1WithSHA256",
+		uint16(tls.PKCS1WithSHA384):        "tls.PKCS1WithSHA384",
+		uint16(tls.PKCS1WithSHA512):        "tls.PKCS1WithSHA512",
+		uint16(tls.PSSWithSHA256):          "tls.PSSWithSHA256",
+		uint16(tls.PSSWithSHA384):          "tls.PSSWithSHA384",
+		uint16(tls.PSSWithSHA512):          "tls.PSSWithSHA512",
+		uint16(tls.ECDSAWithP256AndSHA256): "tls.ECDSAWithP256AndSHA256",
+		uint16(tls.ECDSAWithP384AndSHA384): "tls.ECDSAWithP384AndSHA384",
+		uint16(tls.ECDSAWithP521AndSHA512): "tls.ECDSAWithP521AndSHA512",
+		uint16(tls.PKCS1WithSHA1):          "tls.PKCS1WithSHA1",
+		uint16(tls.ECDSAWithSHA1):          "tls.ECDSAWithSHA1",
+		uint16(tls.Ed25519):                "tls.Ed25519",
+	}
+
+	versionNames = map[uint16]string{
+		tls.GREASE_PLACEHOLDER: "tls.GREASE_PLACEHOLDER",
+		tls.VersionTLS13:       "tls.VersionTLS13",
+		tls.VersionTLS12:       "tls.VersionTLS12",
+		tls.VersionTLS11:       "tls.VersionTLS11",
+		tls.VersionTLS10:       "tls.VersionTLS10",
+	}
+
+	certCompressionNames = map[uint16]string{
+		uint16(tls.CertCompressionZlib):   "tls.CertCompressionZlib",
+		uint16(tls.CertCompressionBrotli): "tls.CertCompressionBrotli",
+		uint16(tls.CertCompressionZstd):   "tls.CertCompressionZstd",
+	}
+
+	compressionMethodNames = map[uint16]string{uint16(tls.CompressionNone): "tls.CompressionNone"}
+	pointFormatNames       = map[uint16]string{uint16(tls.PointFormatUncompressed): "tls.PointFormatUncompressed"}
+	pskModeNames           = map[uint16]string{uint16(tls.PskModePlain): "tls.PskModePlain", uint16(tls.PskModeDHE): "tls.PskModeDHE"}
+
+	renegotiationNames = map[int]string{
+		int(tls.RenegotiateNever):          "tls.RenegotiateNever",
+		int(tls.RenegotiateOnceAsClient):   "tls.RenegotiateOnceAsClient",
+		int(tls.RenegotiateFreelyAsClient): "tls.RenegotiateFreelyAsClient",
+	}
+
+	kdfNames = map[uint16]string{
+		dicttls.HKDF_SHA256: "dicttls.HKDF_SHA256",
+		dicttls.HKDF_SHA384: "dicttls.HKDF_SHA384",
+		dicttls.HKDF_SHA512: "dicttls.HKDF_SHA512",
+	}
+
+	aeadNames = map[uint16]string{
+		dicttls.AEAD_AES_128_GCM:       "dicttls.AEAD_AES_128_GCM",
+		dicttls.AEAD_AES_256_GCM:       "dicttls.AEAD_AES_256_GCM",
+		dicttls.AEAD_CHACHA20_POLY1305: "dicttls.AEAD_CHACHA20_POLY1305",
+	}
+
+	settingNames = map[uint16]string{
+		uint16(http2.SettingHeaderTableSize):      "http2.SettingHeaderTableSize",
+		uint16(http2.SettingEnablePush):           "http2.SettingEnablePush",
+		uint16(http2.SettingMaxConcurrentStreams): "http2.SettingMaxConcurrentStreams",
+		uint16(http2.SettingInitialWindowSize):    "http2.SettingInitialWindowSize",
+		uint16(http2.SettingMaxFrameSize):         "http2.SettingMaxFrameSize",
+		uint16(http2.SettingMaxHeaderListSize):    "http2.SettingMaxHeaderListSize",
+		uint16(http2.SettingNoRFC7540Priorities):  "http2.SettingNoRFC7540Priorities",
+	}
+
+	transportParameterNames = map[uint64]string{
+		uint64(profiles.QUICTransportParameterMaxIdleTimeout):                 "QUICTransportParameterMaxIdleTimeout",
+		uint64(profiles.QUICTransportParameterInitialMaxData):                 "QUICTransportParameterInitialMaxData",
+		uint64(profiles.QUICTransportParameterInitialMaxStreamDataBidiLocal):  "QUICTransportParameterInitialMaxStreamDataBidiLocal",
+		uint64(profiles.QUICTransportParameterInitialMaxStreamDataBidiRemote): "QUICTransportParameterInitialMaxStreamDataBidiRemote",
+		uint64(profiles.QUICTransportParameterInitialMaxStreamDataUni):        "QUICTransportParameterInitialMaxStreamDataUni",
+		uint64(profiles.QUICTransportParameterInitialMaxStreamsBidi):          "QUICTransportParameterInitialMaxStreamsBidi",
+		uint64(profiles.QUICTransportParameterInitialMaxStreamsUni):           "QUICTransportParameterInitialMaxStreamsUni",
+	}
+
+	h3SettingNames = map[uint64]string{
+		uint64(profiles.H3SettingQpackMaxTableCapacity): "H3SettingQpackMaxTableCapacity",
+		uint64(profiles.H3SettingMaxFieldSectionSize):   "H3SettingMaxFieldSectionSize",
+		uint64(profiles.H3SettingQpackBlockedStreams):   "H3SettingQpackBlockedStreams",
+		uint64(profiles.H3SettingEnableConnectProtocol): "H3SettingEnableConnectProtocol",
+		uint64(profiles.H3SettingH3Datagram):            "H3SettingH3Datagram",
+	}
+)
+
+// renderer writes the Go source of a profile and collects its imports.
+type renderer struct {
+	buf     bytes.Buffer
+	imports map[string]bool
+}
+
+// render returns the Go source declaring the profile of file and registering it in MappedTLSClients.
+func render(file *profiles.ProfileFile, opts options) ([]byte, error) {
+	r := &renderer{imports: map[string]bool{"tls": true}}
+
+	boring, err := boringECH()
+	if err != nil {
+		return nil, err
+	}
+
+	r.printf("var %s = ClientProfile{\n", opts.name)
+
+	if len(file.Headers) > 0 {
+		r.headers(file.Headers)
+	}
+
+	r.printf("clientHelloId: tls.ClientHelloID{\n")
+	r.printf("Client: %s,\n", strconv.Quote(file.ClientHello.Client))
+	r.printf("RandomExtensionOrder: %t,\n", file.ClientHello.RandomExtensionOrder)
+	r.printf("Version: %s,\n", strconv.Quote(file.ClientHello.Version))
+	r.printf("Seed: nil,\n")
+
+	if spec := file.ClientHello.Spec; spec != nil {
+		r.printf("SpecFactory: func() (tls.ClientHelloSpec, error) {\nreturn tls.ClientHelloSpec{\n")
+
+		if err := r.spec(spec, boring); err != nil {
+			return nil, err
+		}
+
+		r.printf("}, nil\n},\n")
+	} else {
+		r.printf("SpecFactory: tls.EmptyClientHelloSpecFactory,\n")
+	}
+
+	r.printf("},\n")
+	r.http2(file.HTTP2)
+
+	if file.QUIC != nil {
+		r.quic(file.QUIC)
+	}
+
+	r.printf("}\n\nfunc init() {\nMappedTLSClients[%s] = %s\n}\n", strconv.Quote(opts.key), opts.name)
+
+	var source bytes.Buffer
+
+	fmt.Fprintf(&source, "// Code generated by profilegen from %s. DO NOT EDIT.\n\npackage profiles\n\nimport (\n", filepath.Base(opts.source))
+
+	names := make([]string, 0, len(r.imports))
+	for name := range r.imports {
+		names = append(names, importPaths[name])
+	}
+
+	sort.Slice(names, func(i, j int) bool { return importPath(names[i]) < importPath(names[j]) })
+
+	for _, name := range names {
+		fmt.Fprintf(&source, "%s\n", name)
+	}
+
+	fmt.Fprintf(&source, ")\n\n")
+	source.Write(r.buf.Bytes())
+
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid source: %w", err)
+	}
+
+	return formatted, nil
+}
+
+// importPath returns the path of an import declaration, without its name.
+func importPath(declaration string) string {
+	return declaration[strings.Index(declaration, `"`):]
+}
+
+func (r *renderer) printf(format string, args ...any) {
+	fmt.Fprintf(&r.buf, format, args...)
+}
+
+// list writes the values as a composite literal of type typ, one per line.
+func (r *renderer) list(typ string, values []string) {
+	r.printf("%s{\n", typ)
+
+	for _, value := range values {
+		r.printf("%s,\n", value)
+	}
+
+	r.printf("}")
+}
+
+func (r *renderer) spec(spec *profiles.ProfileClientHelloSpec, boring profiles.ProfileExtension) error {
+	r.printf("CipherSuites: ")
+	r.list("[]uint16", names(spec.CipherSuites, cipherSuiteNames))
+	r.printf(",\n")
+
+	if spec.CompressionMethods != nil {
+		r.printf("CompressionMethods: ")
+		r.list("[]byte", names(spec.CompressionMethods, compressionMethodNames))
+		r.printf(",\n")
+	}
+
+	r.printf("Extensions: []tls.TLSExtension{\n")
+
+	for _, extension := range spec.Extensions {
+		if err := r.extension(extension, boring); err != nil {
+			return err
+		}
+
+		r.printf(",\n")
+	}
+
+	r.printf("},\n")
+
+	if spec.TLSVersMin != 0 {
+		r.printf("TLSVersMin: %s,\n", name(spec.TLSVersMin, versionNames))
+	}
+
+	if spec.TLSVersMax != 0 {
+		r.printf("TLSVersMax: %s,\n", name(spec.TLSVersMax, versionNames))
+	}
+
+	if spec.SessionID != "" {
+		r.imports["sha256"] = true
+		r.printf("GetSessionID: sha256.Sum256,\n")
+	}
+
+	return nil
+}
+
+// extension writes the TLS extension of e, in the way the profiles of the package are written.
+func (r *renderer) extension(e profiles.ProfileExtension, boring profiles.ProfileExtension) error {
+	switch e.Type {
+	case profiles.ExtensionTypeGREASE:
+		r.printf("&tls.UtlsGREASEExtension{%s}", fields(
+			field("Value", e.Value != 0, fmt.Sprintf("0x%04x", e.Value)),
+			field("Body", e.Data != nil, byteSlice(e.Data)),
+		))
+	case profiles.ExtensionTypeServerName:
+		r.printf("&tls.SNIExtension{%s}", fields(field("ServerName", e.ServerName != "", strconv.Quote(e.ServerName))))
+	case profiles.ExtensionTypeStatusRequest:
+		r.printf("&tls.StatusRequestExtension{}")
+	case profiles.ExtensionTypeSupportedCurves:
+		r.printf("&tls.SupportedCurvesExtension{Curves: ")
+		r.list("[]tls.CurveID", names(e.Curves, curveNames))
+		r.printf("}")
+	case profiles.ExtensionTypeSupportedPoints:
+		r.printf("&tls.SupportedPointsExtension{SupportedPoints: ")
+		r.list("[]byte", names(e.Points, pointFormatNames))
+		r.printf("}")
+	case profiles.ExtensionTypeSignatureAlgorithms:
+		r.printf("&tls.SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: ")
+		r.list("[]tls.SignatureScheme", names(e.SignatureAlgorithms, signatureSchemeNames))
+		r.printf("}")
+	case profiles.ExtensionTypeSignatureAlgorithmsCert:
+		r.printf("&tls.SignatureAlgorithmsCertExtension{SupportedSignatureAlgorithms: ")
+		r.list("[]tls.SignatureScheme", names(e.SignatureAlgorithms, signatureSchemeNames))
+		r.printf("}")
+	case profiles.ExtensionTypeDelegatedCredentials:
+		r.printf("&tls.DelegatedCredentialsExtension{SupportedSignatureAlgorithms: ")
+		r.list("[]tls.SignatureScheme", names(e.SignatureAlgorithms, signatureSchemeNames))
+		r.printf("}")
+	case profiles.ExtensionTypeALPN:
+		r.printf("&tls.ALPNExtension{AlpnProtocols: ")
+		r.list("[]string", quoted(e.Protocols))
+		r.printf("}")
+	case profiles.ExtensionTypeApplicationSettings:
+		r.printf("&tls.ApplicationSettingsExtension{SupportedProtocols: []string{%s}}", strings.Join(quoted(e.Protocols), ", "))
+	case profiles.ExtensionTypeApplicationSettingsNew:
+		r.printf("&tls.ApplicationSettingsExtensionNew{SupportedProtocols: []string{%s}}", strings.Join(quoted(e.Protocols), ", "))
+	case profiles.ExtensionTypeNPN:
+		r.printf("&tls.NPNExtension{NextProtos: []string{%s}}", strings.Join(quoted(e.Protocols), ", "))
+	case profiles.ExtensionTypeSCT:
+		r.printf("&tls.SCTExtension{}")
+	case profiles.ExtensionTypePadding:
+		r.printf("&tls.UtlsPaddingExtension{%s}", fields(
+			field("PaddingLen", e.PaddingLength != 0, strconv.Itoa(e.PaddingLength)),
+			field("WillPad", e.PaddingLength != 0, "true"),
+			field("GetPaddingLen", e.Padding != "", "tls.BoringPaddingStyle"),
+		))
+	case profiles.ExtensionTypeExtendedMasterSecret:
+		r.printf("&tls.ExtendedMasterSecretExtension{}")
+	case profiles.ExtensionTypeSessionTicket:
+		r.printf("&tls.SessionTicketExtension{}")
+	case profiles.ExtensionTypePreSharedKey:
+		r.printf("&tls.UtlsPreSharedKeyExtension{}")
+	case profiles.ExtensionTypeSupportedVersions:
+		r.printf("&tls.SupportedVersionsExtension{Versions: ")
+		r.list("[]uint16", names(e.Versions, versionNames))
+		r.printf("}")
+	case profiles.ExtensionTypePSKKeyExchangeModes:
+		r.printf("&tls.PSKKeyExchangeModesExtension{Modes: ")
+		r.list("[]uint8", names(e.Modes, pskModeNames))
+		r.printf("}")
+	case profiles.ExtensionTypeKeyShare:
+		keyShares := make([]string, 0, len(e.KeyShares))
+		for _, keyShare := range e.KeyShares {
+			group := name(keyShare.Group, curveNames)
+			if keyShare.Group == tls.GREASE_PLACEHOLDER {
+				group = "tls.CurveID(tls.GREASE_PLACEHOLDER)"
+			}
+
+			keyShares = append(keyShares, fmt.Sprintf("{%s}", fields(
+				field("Group", true, group),
+				field("Data", keyShare.Data != nil, byteSlice(keyShare.Data)),
+			)))
+		}
+
+		r.printf("&tls.KeyShareExtension{KeyShares: ")
+		r.list("[]tls.KeyShare", keyShares)
+		r.printf("}")
+	case profiles.ExtensionTypeCompressCertificate:
+		r.printf("&tls.UtlsCompressCertExtension{Algorithms: ")
+		r.list("[]tls.CertCompressionAlgo", names(e.Algorithms, certCompressionNames))
+		r.printf("}")
+	case profiles.ExtensionTypeRecordSizeLimit:
+		r.printf("&tls.FakeRecordSizeLimitExtension{Limit: 0x%04x}", e.Limit)
+	case profiles.ExtensionTypeRenegotiationInfo:
+		renegotiation, ok := renegotiationNames[e.Renegotiation]
+		if !ok {
+			renegotiation = strconv.Itoa(e.Renegotiation)
+		}
+
+		r.printf("&tls.RenegotiationInfoExtension{%s}", fields(
+			field("Renegotiation", true, renegotiation),
+			field("RenegotiatedConnection", e.Data != nil, byteSlice(e.Data)),
+		))
+	case profiles.ExtensionTypeGREASEECH:
+		if reflect.DeepEqual(e, boring) {
+			r.printf("tls.BoringGREASEECH()")
+			break
+		}
+
+		cipherSuites := make([]string, 0, len(e.CipherSuites))
+		for _, cipherSuite := range e.CipherSuites {
+			cipherSuites = append(cipherSuites, fmt.Sprintf("{\nKdfId: %s,\nAeadId: %s,\n}", name(cipherSuite.KdfID, kdfNames), name(cipherSuite.AeadID, aeadNames)))
+		}
+
+		r.imports["dicttls"] = true
+		r.printf("&tls.GREASEEncryptedClientHelloExtension{\nCandidateCipherSuites: ")
+		r.list("[]tls.HPKESymmetricCipherSuite", cipherSuites)
+		r.printf(",\n%s,\n}", fields(
+			field("CandidateConfigIds", e.ConfigIDs != nil, fmt.Sprintf("[]uint8{%s}", strings.Join(numbers(e.ConfigIDs), ", "))),
+			field("EncapsulatedKey", e.Data != nil, byteSlice(e.Data)),
+			field("CandidatePayloadLens", e.PayloadLengths != nil, fmt.Sprintf("[]uint16{%s}", strings.Join(numbers(e.PayloadLengths), ", "))),
+		))
+	case profiles.ExtensionTypeCookie:
+		r.printf("&tls.CookieExtension{Cookie: %s}", byteSlice(e.Data))
+	case profiles.ExtensionTypeChannelID:
+		r.printf("&tls.FakeChannelIDExtension{OldExtensionID: %t}", e.OldID)
+	case profiles.ExtensionTypeGeneric:
+		r.printf("&tls.GenericExtension{%s}", fields(
+			field("Id", true, strconv.Itoa(int(e.ID))),
+			field("Data", e.Data != nil, byteSlice(e.Data)),
+		))
+	default:
+		return fmt.Errorf("unknown extension type %q", e.Type)
+	}
+
+	return nil
+}
+
+func (r *renderer) headers(headers map[string]profiles.ProfileHeaders) {
+	r.imports["http"] = true
+	r.printf("headers: map[RequestKind]http.Header{\n")
+
+	for _, kind := range []string{"navigation", "fetch", "image"} {
+		header, ok := headers[kind]
+		if !ok {
+			continue
+		}
+
+		r.printf("%s: {\n", requestKindNames[kind])
+
+		var order []string
+
+		values := make(map[string][]string, len(header.Values))
+		for _, value := range header.Values {
+			if _, ok := values[value.Name]; !ok {
+				order = append(order, value.Name)
+			}
+
+			values[value.Name] = append(values[value.Name], goString(value.Value))
+		}
+
+		for _, name := range order {
+			r.printf("%s: {%s},\n", strconv.Quote(name), strings.Join(values[name], ", "))
+		}
+
+		if header.Order != nil {
+			r.printf("http.HeaderOrderKey: ")
+			r.list("", quoted(header.Order))
+			r.printf(",\n")
+		}
+
+		r.printf("},\n")
+	}
+
+	r.printf("},\n")
+}
+
+func (r *renderer) http2(h2 profiles.ProfileHTTP2) {
+	r.imports["http2"] = true
+
+	settings := make([]string, 0, len(h2.Settings))
+	for _, setting := range h2.Settings {
+		settings = append(settings, fmt.Sprintf("%s: %d", name(setting.ID, settingNames), setting.Value))
+	}
+
+	r.printf("settings: ")
+	r.list("map[http2.SettingID]uint32", settings)
+	r.printf(",\nsettingsOrder: ")
+	r.list("[]http2.SettingID", names(h2.SettingsOrder, settingNames))
+	r.printf(",\npseudoHeaderOrder: ")
+	r.list("[]string", quoted(h2.PseudoHeaderOrder))
+	r.printf(",\nconnectionFlow: %d,\n", h2.ConnectionFlow)
+
+	if h2.Priorities != nil {
+		priorities := make([]string, 0, len(h2.Priorities))
+		for _, priority := range h2.Priorities {
+			priorities = append(priorities, fmt.Sprintf("{StreamID: %d, PriorityParam: %s}", priority.StreamID, priorityParam(priority.ProfilePriorityParam)))
+		}
+
+		r.printf("priorities: ")
+		r.list("[]http2.Priority", priorities)
+		r.printf(",\n")
+	}
+
+	if h2.HeaderPriority != nil {
+		r.printf("headerPriority: &%s,\n", priorityParam(*h2.HeaderPriority))
+	}
+}
+
+func (r *renderer) quic(quic *profiles.ProfileQUIC) {
+	r.printf("quicProfile: &QUICProfile{\n")
+
+	if quic.TransportParameters != nil {
+		parameters := make([]string, 0, len(quic.TransportParameters))
+		for _, parameter := range quic.TransportParameters {
+			parameters = append(parameters, fmt.Sprintf("%s: %d", name(parameter.ID, transportParameterNames), parameter.Value))
+		}
+
+		r.printf("TransportParameters: ")
+		r.list("map[QUICTransportParameterID]uint64", parameters)
+		r.printf(",\n")
+	}
+
+	if quic.H3Settings != nil {
+		settings := make([]string, 0, len(quic.H3Settings))
+		for _, setting := range quic.H3Settings {
+			settings = append(settings, fmt.Sprintf("%s: %d", name(setting.ID, h3SettingNames), setting.Value))
+		}
+
+		r.printf("H3Settings: ")
+		r.list("map[H3SettingID]uint64", settings)
+		r.printf(",\n")
+	}
+
+	r.printf("%s,\n},\n", fields(
+		field("InitialPacketSize", quic.InitialPacketSize != 0, strconv.Itoa(int(quic.InitialPacketSize))),
+		field("ConnectionIDLength", quic.ConnectionIDLength != 0, strconv.Itoa(quic.ConnectionIDLength)),
+		field("H3Datagram", quic.H3Datagram, "true"),
+	))
+}
+
+func priorityParam(param profiles.ProfilePriorityParam) string {
+	return fmt.Sprintf("http2.PriorityParam{\nStreamDep: %d,\nExclusive: %t,\nWeight: %d,\n}", param.StreamDep, param.Exclusive, param.Weight)
+}
+
+// name returns the constant of value in constants, else value as a number.
+func name[T uint16 | uint64](value T, constants map[T]string) string {
+	if constant, ok := constants[value]; ok {
+		return constant
+	}
+
+	return fmt.Sprintf("0x%04x", uint64(value))
+}
+
+func names[T uint16 | uint64](values []T, constants map[T]string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, name(value, constants))
+	}
+
+	return result
+}
+
+func numbers(values []uint16) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, strconv.Itoa(int(value)))
+	}
+
+	return result
+}
+
+func quoted(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, strconv.Quote(value))
+	}
+
+	return result
+}
+
+// goString returns the Go literal of s, raw when it has quotes, like `"Windows"`.
+func goString(s string) string {
+	if strings.Contains(s, `"`) && strconv.CanBackquote(s) {
+		return "`" + s + "`"
+	}
+
+	return strconv.Quote(s)
+}
+
+func byteSlice(data []byte) string {
+	values := make([]string, 0, len(data))
+	for _, b := range data {
+		values = append(values, fmt.Sprintf("0x%02x", b))
+	}
+
+	return fmt.Sprintf("[]byte{%s}", strings.Join(values, ", "))
+}
+
+// field returns the "Name: value" of a composite literal, or an empty string if the field is not set.
+func field(name string, set bool, value string) string {
+	if !set {
+		return ""
+	}
+
+	return name + ": " + value
+}
+
+// fields joins the set fields of a composite literal.
+func fields(all ...string) string {
+	set := make([]string, 0, len(all))
+	for _, f := range all {
+		if f != "" {
+			set = append(set, f)
+		}
+	}
+
+	return strings.Join(set, ", ")
+}
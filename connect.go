@@ -40,7 +40,7 @@ func (d *directDialer) Dial(network, addr string) (net.Conn, error) {
 }
 
 func (d *directDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
-	return d.dialer.DialContext(ctx, network, addr)
+	return d.dialer.DialContext(withNetTrace(ctx), network, addr)
 }
 
 type socksContextDialer struct {
@@ -199,7 +199,14 @@ func (c *connectDialer) DialContext(ctx context.Context, network, address string
 		}
 	}
 
-	connectHttp2 := func(rawConn net.Conn, h2clientConn *http2.ClientConn) (net.Conn, error) {
+	trace := ContextClientTrace(ctx)
+
+	connectHttp2 := func(rawConn net.Conn, h2clientConn *http2.ClientConn) (_ net.Conn, err error) {
+		trace.proxyConnectStart(c.ProxyUrl.Host, address)
+		defer func() {
+			trace.proxyConnectDone(c.ProxyUrl.Host, address, err)
+		}()
+
 		req.Proto = "HTTP/2.0"
 		req.ProtoMajor = 2
 		req.ProtoMinor = 0
@@ -214,19 +221,24 @@ func (c *connectDialer) DialContext(ctx context.Context, network, address string
 
 		if resp.StatusCode != http.StatusOK {
 			_ = rawConn.Close()
-			return nil, errors.New("Proxy responded with non 200 code: " + resp.Status)
+			return nil, &ProxyConnectError{Status: resp.Status, StatusCode: resp.StatusCode}
 		}
 
 		return newHttp2Conn(rawConn, pw, resp.Body), nil
 	}
 
-	connectHttp1 := func(rawConn net.Conn) (net.Conn, error) {
+	connectHttp1 := func(rawConn net.Conn) (_ net.Conn, err error) {
+		trace.proxyConnectStart(c.ProxyUrl.Host, address)
+		defer func() {
+			trace.proxyConnectDone(c.ProxyUrl.Host, address, err)
+		}()
+
 		req.Proto = "HTTP/1.1"
 		req.ProtoMajor = 1
 		req.ProtoMinor = 1
 
 		deadline := time.Now().Add(c.Timeout)
-		err := rawConn.SetDeadline(deadline)
+		err = rawConn.SetDeadline(deadline)
 		if err != nil {
 			_ = rawConn.Close()
 			return nil, err
@@ -254,7 +266,7 @@ func (c *connectDialer) DialContext(ctx context.Context, network, address string
 
 		if resp.StatusCode != http.StatusOK {
 			_ = rawConn.Close()
-			return nil, errors.New("Proxy responded with non 200 code: " + resp.Status)
+			return nil, &ProxyConnectError{Status: resp.Status, StatusCode: resp.StatusCode}
 		}
 
 		rawConn.SetDeadline(time.Time{})
@@ -289,7 +301,7 @@ func (c *connectDialer) DialContext(ctx context.Context, network, address string
 	negotiatedProtocol := ""
 	switch c.ProxyUrl.Scheme {
 	case "http":
-		rawConn, err = c.Dialer.DialContext(ctx, network, c.ProxyUrl.Host)
+		rawConn, err = c.Dialer.DialContext(withNetTrace(ctx), network, c.ProxyUrl.Host)
 
 		if err != nil {
 			return nil, err
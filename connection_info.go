@@ -0,0 +1,208 @@
+package httpkit
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	http "github.com/bogdanfinn/fhttp"
+	tls "github.com/bogdanfinn/utls"
+)
+
+// ConnectionInfo describes the connection a response was received on. It is retrieved with ConnInfo.
+type ConnectionInfo struct {
+	// RemoteAddr is the address the connection is established with. With a proxy, this is the address of the proxy.
+	RemoteAddr net.Addr
+	// ServerHello holds the parameters of the ServerHello, nil for plain HTTP and HTTP/3.
+	ServerHello *ServerHelloInfo
+	// Protocol is the application protocol of the connection: "http/1.1", "h2" or "h3".
+	Protocol string
+	// Proxy is the URL of the proxy the request was sent through, without credentials. Empty for direct connections.
+	Proxy      string
+	ServerName string
+	// PeerCertificates is the certificate chain presented by the server, the leaf first.
+	PeerCertificates []*x509.Certificate
+	// TLSVersion is zero for plain HTTP.
+	TLSVersion  uint16
+	CipherSuite uint16
+	DidResume   bool
+	// Reused is whether the connection was previously used for another request.
+	Reused bool
+}
+
+// ServerHelloInfo holds the parameters of a ServerHello message, which make up the JA3S and JA4S fingerprints of the server.
+type ServerHelloInfo struct {
+	// ALPN is the protocol selected through the application_layer_protocol_negotiation extension.
+	ALPN string
+	// Extensions are the extension types in the order sent by the server.
+	Extensions []uint16
+	// Version is the legacy_version field. See SupportedVersion for TLS 1.3.
+	Version uint16
+	// SupportedVersion is the version selected through the supported_versions extension, zero before TLS 1.3.
+	SupportedVersion uint16
+	CipherSuite      uint16
+}
+
+type connectionInfoContextKey struct{}
+
+// ConnInfo returns the information about the connection a response was received on, or nil if the response was not
+// received through a client of this package.
+func ConnInfo(resp *http.Response) *ConnectionInfo {
+	if resp == nil || resp.Request == nil {
+		return nil
+	}
+
+	info, _ := resp.Request.Context().Value(connectionInfoContextKey{}).(*ConnectionInfo)
+
+	return info
+}
+
+// withConnectionInfo attaches info to the request of resp, so that ConnInfo can find it.
+func withConnectionInfo(resp *http.Response, req *http.Request, info *ConnectionInfo) {
+	if resp.Request == nil {
+		resp.Request = req
+	}
+
+	resp.Request = resp.Request.WithContext(context.WithValue(resp.Request.Context(), connectionInfoContextKey{}, info))
+}
+
+// newConnectionInfo describes the connection conn, the TLS state being taken from the response if the connection does not expose it.
+func newConnectionInfo(conn net.Conn, resp *http.Response, reused bool, proxyUrl string) *ConnectionInfo {
+	info := &ConnectionInfo{
+		Protocol: connProtocol(conn),
+		Proxy:    proxyUrl,
+		Reused:   reused,
+	}
+
+	if conn != nil {
+		info.RemoteAddr = conn.RemoteAddr()
+	}
+
+	state := resp.TLS
+
+	if tlsConn, ok := conn.(*tls.UConn); ok {
+		connState := tlsConn.ConnectionState()
+		state = &connState
+		info.ServerHello = newServerHelloInfo(tlsConn.HandshakeState.ServerHello)
+	}
+
+	if state != nil {
+		info.TLSVersion = state.Version
+		info.CipherSuite = state.CipherSuite
+		info.DidResume = state.DidResume
+		info.ServerName = state.ServerName
+		info.PeerCertificates = state.PeerCertificates
+	}
+
+	return info
+}
+
+func newServerHelloInfo(serverHello *tls.PubServerHelloMsg) *ServerHelloInfo {
+	if serverHello == nil {
+		return nil
+	}
+
+	return &ServerHelloInfo{
+		Version:          serverHello.Vers,
+		SupportedVersion: serverHello.SupportedVersion,
+		CipherSuite:      serverHello.CipherSuite,
+		ALPN:             serverHello.AlpnProtocol,
+		Extensions:       parseServerHelloExtensions(serverHello.Raw),
+	}
+}
+
+// parseServerHelloExtensions returns the extension types of a raw ServerHello handshake message (RFC 8446, section 4.1.3).
+func parseServerHelloExtensions(raw []byte) []uint16 {
+	// message type, uint24 length, legacy_version and random
+	offset := 4 + 2 + 32
+	if len(raw) < offset+1 {
+		return nil
+	}
+
+	// legacy_session_id_echo, cipher_suite and legacy_compression_method
+	offset += 1 + int(raw[offset]) + 2 + 1
+	if len(raw) < offset+2 {
+		return nil
+	}
+
+	end := offset + 2 + int(binary.BigEndian.Uint16(raw[offset:]))
+	if len(raw) < end {
+		return nil
+	}
+
+	var extensions []uint16
+
+	for offset += 2; offset+4 <= end; {
+		extensions = append(extensions, binary.BigEndian.Uint16(raw[offset:]))
+		offset += 4 + int(binary.BigEndian.Uint16(raw[offset+2:]))
+	}
+
+	return extensions
+}
+
+// JA3S returns the JA3S fingerprint string of the ServerHello: version, cipher suite and extensions.
+func (s *ServerHelloInfo) JA3S() string {
+	extensions := make([]string, len(s.Extensions))
+	for i, extension := range s.Extensions {
+		extensions[i] = strconv.Itoa(int(extension))
+	}
+
+	return fmt.Sprintf("%d,%d,%s", s.Version, s.CipherSuite, strings.Join(extensions, "-"))
+}
+
+// JA3SHash returns the MD5 hash of the JA3S fingerprint string.
+func (s *ServerHelloInfo) JA3SHash() string {
+	hash := md5.Sum([]byte(s.JA3S()))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// JA4S returns the JA4S fingerprint of a ServerHello received over TCP.
+func (s *ServerHelloInfo) JA4S() string {
+	version := s.Version
+	if s.SupportedVersion != 0 {
+		version = s.SupportedVersion
+	}
+
+	alpn := "00"
+	if len(s.ALPN) > 0 {
+		alpn = string(s.ALPN[0]) + string(s.ALPN[len(s.ALPN)-1])
+	}
+
+	extensions := make([]string, len(s.Extensions))
+	for i, extension := range s.Extensions {
+		extensions[i] = fmt.Sprintf("%04x", extension)
+	}
+
+	extensionsHash := "000000000000"
+	if len(extensions) > 0 {
+		hash := sha256.Sum256([]byte(strings.Join(extensions, ",")))
+		extensionsHash = hex.EncodeToString(hash[:])[:12]
+	}
+
+	return fmt.Sprintf("t%s%02d%s_%04x_%s", ja4Version(version), min(len(s.Extensions), 99), alpn, s.CipherSuite, extensionsHash)
+}
+
+func ja4Version(version uint16) string {
+	switch version {
+	case tls.VersionTLS13:
+		return "13"
+	case tls.VersionTLS12:
+		return "12"
+	case tls.VersionTLS11:
+		return "11"
+	case tls.VersionTLS10:
+		return "10"
+	case tls.VersionSSL30:
+		return "s3"
+	default:
+		return "00"
+	}
+}
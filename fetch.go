@@ -0,0 +1,162 @@
+package httpkit
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/Mathious6/httpkit/profiles"
+	http "github.com/bogdanfinn/fhttp"
+)
+
+// FetchContext describes how the emulated browser initiated a request. It decides which SameSite cookies are sent with
+// the request and set from its response, see CookieJar.CookiesFor.
+//
+// The context is carried by the request context, see WithFetchContext, and follows the request through its redirects.
+type FetchContext struct {
+	// Initiator is the origin of the document which initiated the request, e.g. "https://shop.example.com".
+	// Empty for browser initiated requests, like an URL typed in the address bar, which are same-site.
+	Initiator string
+	// Method is the method of the request, taken from the request when sent through a client.
+	Method string
+	// Redirects are the URLs of the previous hops of the redirect chain. The request is cross-site if any of them is
+	// cross-site with the initiator. It is filled in by the client while following redirects.
+	Redirects []*url.URL
+	// Referrer is the URL of the document which initiated the request, sent in the Referer header according to the
+	// referrer policy by clients emulating navigation headers, see WithNavigationHeaders. The origin of the initiator
+	// is used when empty.
+	Referrer string
+	// ReferrerPolicy is the referrer policy of the initiating document, e.g. "no-referrer". Empty for the default
+	// strict-origin-when-cross-origin policy. It is replaced by the Referrer-Policy of the redirect responses.
+	ReferrerPolicy string
+	// Navigation is whether the request is a top-level navigation, as opposed to a subresource request (fetch, XHR,
+	// image, iframe...). Partitioned cookies of subresource requests are keyed by the site of the initiator.
+	Navigation bool
+	// UserActivation is whether the navigation was triggered by the user, like a click on a link, as opposed to a
+	// script. Browser initiated navigations are always user activated.
+	UserActivation bool
+}
+
+type fetchContextKey struct{}
+
+// WithFetchContext returns a new context based on the provided parent ctx. Requests made with the returned context
+// through a client with a CookieJar send and store cookies according to fetch.
+func WithFetchContext(ctx context.Context, fetch FetchContext) context.Context {
+	return context.WithValue(ctx, fetchContextKey{}, &fetch)
+}
+
+// ContextFetchContext returns the FetchContext associated with the provided context. If none, it returns nil.
+func ContextFetchContext(ctx context.Context) *FetchContext {
+	fetch, _ := ctx.Value(fetchContextKey{}).(*FetchContext)
+
+	return fetch
+}
+
+// FetchContextFrom returns the context of a request initiated by the document of resp, like a click on a link of the
+// page when navigation is set, or a subresource of the page otherwise. It is meant to browse from page to page:
+//
+//	ctx := httpkit.WithFetchContext(context.Background(), httpkit.FetchContextFrom(resp, true))
+func FetchContextFrom(resp *http.Response, navigation bool) FetchContext {
+	document := resp.Request.URL
+
+	return FetchContext{
+		Initiator:      document.Scheme + "://" + document.Host,
+		Referrer:       document.String(),
+		ReferrerPolicy: referrerPolicy(resp.Header, ""),
+		Navigation:     navigation,
+		UserActivation: navigation,
+	}
+}
+
+// fetchClient returns a shallow copy of client following the fetch context of req on every hop of the redirect chain.
+// Its jar sees the fetch context of each hop and, when navigationHeaders is set, the fetch metadata headers of each hop
+// are set for a request of the given kind, see setFetchHeaders.
+// The client is returned as is if there is nothing to follow.
+func fetchClient(client *http.Client, req *http.Request, navigationHeaders bool, kind profiles.RequestKind) *http.Client {
+	fetch := ContextFetchContext(req.Context())
+
+	jar, isCookieJar := client.Jar.(CookieJar)
+	if !navigationHeaders && (fetch == nil || !isCookieJar) {
+		return client
+	}
+
+	// requests without a fetch context are browser initiated navigations
+	hop := &fetchHop{fetch: FetchContext{Navigation: true, UserActivation: true}}
+	if fetch != nil {
+		hop.fetch = *fetch
+		hop.redirects = fetch.Redirects
+	}
+
+	if hop.fetch.Method == "" {
+		hop.fetch.Method = req.Method
+	}
+
+	checkRedirect := client.CheckRedirect
+
+	fetchClient := *client
+	if fetch != nil && isCookieJar {
+		fetchClient.Jar = &fetchCookieJar{CookieJar: jar, hop: hop}
+	}
+
+	if navigationHeaders {
+		setFetchHeaders(req, hop.fetch, kind)
+	}
+
+	fetchClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if checkRedirect != nil {
+			if err := checkRedirect(req, via); err != nil {
+				return err
+			}
+		} else if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+
+		hop.redirect(req, via)
+
+		if navigationHeaders {
+			setFetchHeaders(req, hop.fetch, kind)
+		}
+
+		return nil
+	}
+
+	return &fetchClient
+}
+
+// fetchHop is the fetch context of the current hop of the redirect chain of a request.
+type fetchHop struct {
+	fetch FetchContext
+	// redirects are the redirects of the request context, which preceded the request.
+	redirects []*url.URL
+}
+
+// redirect moves the fetch context to the next hop req of the redirect chain.
+func (h *fetchHop) redirect(req *http.Request, via []*http.Request) {
+	redirects := make([]*url.URL, 0, len(h.redirects)+len(via))
+	redirects = append(redirects, h.redirects...)
+
+	for _, previous := range via {
+		redirects = append(redirects, previous.URL)
+	}
+
+	h.fetch.Redirects = redirects
+	h.fetch.Method = req.Method
+
+	if req.Response != nil {
+		h.fetch.ReferrerPolicy = referrerPolicy(req.Response.Header, h.fetch.ReferrerPolicy)
+	}
+}
+
+// fetchCookieJar passes the fetch context of the current hop of a request to the jar. It is used by a single request.
+type fetchCookieJar struct {
+	CookieJar
+	hop *fetchHop
+}
+
+func (j *fetchCookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.CookiesFor(u, j.hop.fetch)
+}
+
+func (j *fetchCookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.SetCookiesFor(u, cookies, j.hop.fetch)
+}
@@ -0,0 +1,276 @@
+package httpkit
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/Mathious6/httpkit/profiles"
+	http "github.com/bogdanfinn/fhttp"
+	"golang.org/x/net/publicsuffix"
+)
+
+// The referrer policies, see https://w3c.github.io/webappsec-referrer-policy/#referrer-policies.
+const (
+	referrerPolicyNoReferrer                  = "no-referrer"
+	referrerPolicyNoReferrerWhenDowngrade     = "no-referrer-when-downgrade"
+	referrerPolicySameOrigin                  = "same-origin"
+	referrerPolicyOrigin                      = "origin"
+	referrerPolicyStrictOrigin                = "strict-origin"
+	referrerPolicyOriginWhenCrossOrigin       = "origin-when-cross-origin"
+	referrerPolicyStrictOriginWhenCrossOrigin = "strict-origin-when-cross-origin"
+	referrerPolicyUnsafeURL                   = "unsafe-url"
+)
+
+// The headers set by setFetchHeaders.
+var fetchHeaderNames = []string{"sec-fetch-site", "sec-fetch-mode", "sec-fetch-user", "sec-fetch-dest", "referer", "origin"}
+
+// setFetchHeaders sets the Sec-Fetch-* metadata, Referer and Origin headers a browser sends for the current hop of a
+// request of the given kind made in fetch, replacing the ones of the request. The headers a browser would not send
+// are removed.
+func setFetchHeaders(req *http.Request, fetch FetchContext, kind profiles.RequestKind) {
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+
+	headers := make(map[string]string, len(fetchHeaderNames))
+
+	initiator, _ := url.Parse(fetch.Initiator)
+	if fetch.Initiator == "" || initiator == nil {
+		initiator = nil
+	}
+
+	if isTrustworthyURL(req.URL) {
+		headers["sec-fetch-site"] = fetchSite(req.URL, initiator, fetch.Redirects)
+		headers["sec-fetch-mode"], headers["sec-fetch-dest"] = fetchModeAndDest(kind)
+
+		if kind == profiles.RequestKindNavigation && (initiator == nil || fetch.UserActivation) {
+			headers["sec-fetch-user"] = "?1"
+		}
+	}
+
+	if initiator != nil {
+		referrer := fetch.Referrer
+		if referrer == "" {
+			referrer = fetch.Initiator
+		}
+
+		policy := fetch.ReferrerPolicy
+		if policy == "" {
+			policy = referrerPolicyStrictOriginWhenCrossOrigin
+		}
+
+		if referrerURL, err := url.Parse(referrer); err == nil {
+			if referer := refererFor(referrerURL, req.URL, policy); referer != "" {
+				headers["referer"] = referer
+			}
+		}
+
+		corsCrossOrigin := kind == profiles.RequestKindFetch && urlOrigin(req.URL) != urlOrigin(initiator)
+		if corsCrossOrigin || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+			headers["origin"] = originFor(initiator, req.URL, fetch.Redirects, policy)
+		}
+	}
+
+	lowercase := hasLowercaseHeaderNames(req.Header)
+
+	for _, name := range fetchHeaderNames {
+		deleteHeader(req.Header, name)
+
+		value, ok := headers[name]
+		if !ok {
+			continue
+		}
+
+		if !lowercase {
+			name = http.CanonicalHeaderKey(name)
+		}
+
+		req.Header[name] = []string{value}
+	}
+}
+
+// fetchSite returns the Sec-Fetch-Site of a request to u, initiated by initiator and redirected through redirects.
+// Requests without initiator are browser initiated.
+func fetchSite(u *url.URL, initiator *url.URL, redirects []*url.URL) string {
+	if initiator == nil {
+		return "none"
+	}
+
+	site := "same-origin"
+
+	for _, hop := range append(append([]*url.URL{}, redirects...), u) {
+		if urlOrigin(hop) == urlOrigin(initiator) {
+			continue
+		}
+
+		if urlSite(hop) != urlSite(initiator) {
+			return "cross-site"
+		}
+
+		site = "same-site"
+	}
+
+	return site
+}
+
+// fetchModeAndDest returns the Sec-Fetch-Mode and Sec-Fetch-Dest of a request of the given kind.
+func fetchModeAndDest(kind profiles.RequestKind) (string, string) {
+	switch kind {
+	case profiles.RequestKindFetch:
+		return "cors", "empty"
+	case profiles.RequestKindImage:
+		return "no-cors", "image"
+	default:
+		return "navigate", "document"
+	}
+}
+
+// refererFor returns the Referer of a request from the document referrer to u with the given referrer policy, or an
+// empty string if none must be sent.
+func refererFor(referrer *url.URL, u *url.URL, policy string) string {
+	if referrer.Scheme != "http" && referrer.Scheme != "https" {
+		return ""
+	}
+
+	stripped := *referrer
+	stripped.User = nil
+	stripped.Fragment = ""
+	stripped.RawFragment = ""
+
+	full := stripped.String()
+	origin := urlOrigin(referrer) + "/"
+	sameOrigin := urlOrigin(referrer) == urlOrigin(u)
+	downgrade := referrer.Scheme == "https" && u.Scheme != "https"
+
+	switch policy {
+	case referrerPolicyNoReferrer:
+		return ""
+	case referrerPolicyNoReferrerWhenDowngrade:
+		if downgrade {
+			return ""
+		}
+
+		return full
+	case referrerPolicySameOrigin:
+		if !sameOrigin {
+			return ""
+		}
+
+		return full
+	case referrerPolicyOrigin:
+		return origin
+	case referrerPolicyStrictOrigin:
+		if downgrade {
+			return ""
+		}
+
+		return origin
+	case referrerPolicyOriginWhenCrossOrigin:
+		if !sameOrigin {
+			return origin
+		}
+
+		return full
+	case referrerPolicyUnsafeURL:
+		return full
+	default:
+		if sameOrigin {
+			return full
+		}
+
+		if downgrade {
+			return ""
+		}
+
+		return origin
+	}
+}
+
+// originFor returns the Origin of a request from initiator to u, which is "null" when the referrer policy hides it or
+// when a cross-origin redirect of the chain tainted it.
+func originFor(initiator *url.URL, u *url.URL, redirects []*url.URL, policy string) string {
+	origin := urlOrigin(initiator)
+
+	hops := append(append([]*url.URL{}, redirects...), u)
+	for i := 0; i+1 < len(hops); i++ {
+		if urlOrigin(hops[i]) != urlOrigin(hops[i+1]) && urlOrigin(hops[i]) != origin {
+			return "null"
+		}
+	}
+
+	downgrade := initiator.Scheme == "https" && u.Scheme != "https"
+
+	switch policy {
+	case referrerPolicyNoReferrer:
+		return "null"
+	case referrerPolicyNoReferrerWhenDowngrade, referrerPolicyStrictOrigin, referrerPolicyStrictOriginWhenCrossOrigin:
+		if downgrade {
+			return "null"
+		}
+	case referrerPolicySameOrigin:
+		if origin != urlOrigin(u) {
+			return "null"
+		}
+	}
+
+	return origin
+}
+
+// referrerPolicy returns the referrer policy set by the Referrer-Policy header, the last known policy of the list, or
+// current if it sets none.
+func referrerPolicy(header http.Header, current string) string {
+	value, _ := headerValue(header, "referrer-policy")
+
+	policy := current
+	for _, token := range strings.Split(value, ",") {
+		switch token = strings.ToLower(strings.TrimSpace(token)); token {
+		case referrerPolicyNoReferrer, referrerPolicyNoReferrerWhenDowngrade, referrerPolicySameOrigin, referrerPolicyOrigin,
+			referrerPolicyStrictOrigin, referrerPolicyOriginWhenCrossOrigin, referrerPolicyStrictOriginWhenCrossOrigin,
+			referrerPolicyUnsafeURL:
+			policy = token
+		}
+	}
+
+	return policy
+}
+
+// isTrustworthyURL reports whether u is potentially trustworthy, the only URLs browsers send fetch metadata to.
+func isTrustworthyURL(u *url.URL) bool {
+	if u.Scheme == "https" || u.Scheme == "wss" {
+		return true
+	}
+
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback()
+	}
+
+	return host == "localhost" || strings.HasSuffix(host, ".localhost")
+}
+
+// urlOrigin returns the origin of u, its scheme and host.
+func urlOrigin(u *url.URL) string {
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// urlSite returns the site of u, its scheme and registrable domain.
+func urlSite(u *url.URL) string {
+	host, err := canonicalCookieHost(u.Host)
+	if err != nil {
+		return urlOrigin(u)
+	}
+
+	return u.Scheme + "://" + cookieJarKey(host, publicsuffix.List)
+}
+
+// hasLowercaseHeaderNames reports whether the header names are defined in lowercase rather than in their canonical form.
+func hasLowercaseHeaderNames(header http.Header) bool {
+	for name := range header {
+		if name != http.HeaderOrderKey && name != http.PHeaderOrderKey && name == strings.ToLower(name) && name != http.CanonicalHeaderKey(name) {
+			return true
+		}
+	}
+
+	return false
+}
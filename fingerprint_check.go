@@ -0,0 +1,368 @@
+package httpkit
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Mathious6/httpkit/profiles"
+	http "github.com/bogdanfinn/fhttp"
+)
+
+// The checks of the fingerprint consistency check, reported in FingerprintIssue.Check.
+const (
+	FingerprintCheckUserAgent      = "user-agent"
+	FingerprintCheckClientHints    = "client-hints"
+	FingerprintCheckHeaderCase     = "header-case"
+	FingerprintCheckAcceptEncoding = "accept-encoding"
+	FingerprintCheckHeaderOrder    = "header-order"
+)
+
+// The client families a user agent or a client profile can belong to.
+const (
+	clientFamilyChrome  = "Chrome"
+	clientFamilyFirefox = "Firefox"
+	clientFamilySafari  = "Safari"
+	clientFamilyOkHttp  = "OkHttp"
+)
+
+// The content codings the transport decodes when it is not configured with DisableCompression.
+var decodableContentCodings = []string{"gzip", "deflate", "br", "zstd", "identity", "*"}
+
+// FingerprintIssue is an inconsistency between a request and the client profile it is sent with, which makes the
+// request stand out from the traffic of the emulated client.
+type FingerprintIssue struct {
+	// Check is the check which found the issue, one of the FingerprintCheck constants.
+	Check   string
+	Message string
+}
+
+func (i FingerprintIssue) String() string {
+	return i.Check + ": " + i.Message
+}
+
+// FingerprintError is returned by the client for a request rejected by the fingerprint consistency check.
+type FingerprintError struct {
+	Issues []FingerprintIssue
+}
+
+func (e *FingerprintError) Error() string {
+	messages := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		messages[i] = issue.String()
+	}
+
+	return "inconsistent request fingerprint: " + strings.Join(messages, "; ")
+}
+
+// FingerprintCheckOptions configures the fingerprint consistency check, see WithFingerprintConsistencyCheck.
+type FingerprintCheckOptions struct {
+	// OnIssues is called with the issues found for a request before it is sent or rejected, for every hop of a redirect
+	// chain. When nil, the issues are logged as warnings.
+	OnIssues func(req *http.Request, issues []FingerprintIssue)
+	// Reject fails the requests with issues with a *FingerprintError instead of sending them. The connection to the
+	// server may already be established, as the protocol it negotiates decides some checks.
+	Reject bool
+}
+
+// checkFingerprint returns the inconsistencies of req with profile. kind is the kind of request whose profile headers
+// give the expected header order and http1 is whether the request is sent over HTTP/1.1. decodes is whether the
+// transport decodes the content codings of the responses.
+func checkFingerprint(req *http.Request, profile profiles.ClientProfile, kind profiles.RequestKind, http1 bool, decodes bool) []FingerprintIssue {
+	var issues []FingerprintIssue
+
+	userAgent, _ := headerValue(req.Header, "user-agent")
+	uaFamily, uaMajor := parseUserAgentFamily(userAgent)
+
+	clientHelloId := profile.GetClientHelloId()
+	profileFamily := clientFamily(clientHelloId.Client)
+
+	if userAgent != "" && uaFamily != "" && profileFamily != "" {
+		profileMajor, _ := strconv.Atoi(strings.SplitN(clientHelloId.Version, ".", 2)[0])
+
+		switch {
+		case uaFamily != profileFamily:
+			issues = append(issues, FingerprintIssue{
+				Check:   FingerprintCheckUserAgent,
+				Message: fmt.Sprintf("the user-agent is a %s one but the client profile is %s", uaFamily, clientHelloId.Str()),
+			})
+		case uaMajor != 0 && profileMajor != 0 && uaMajor != profileMajor:
+			issues = append(issues, FingerprintIssue{
+				Check:   FingerprintCheckUserAgent,
+				Message: fmt.Sprintf("the user-agent is %s %d but the client profile is %s", uaFamily, uaMajor, clientHelloId.Str()),
+			})
+		}
+	}
+
+	if uaFamily != "" {
+		issues = append(issues, checkClientHints(req, userAgent, uaFamily, uaMajor)...)
+	}
+
+	if http1 {
+		issues = append(issues, checkHeaderCase(req.Header)...)
+	}
+
+	if decodes {
+		issues = append(issues, checkAcceptEncoding(req.Header)...)
+	}
+
+	if !http1 {
+		issues = append(issues, checkPseudoHeaderOrder(req.Header, profile.GetPseudoHeaderOrder())...)
+	}
+
+	if headers := profile.GetHeaders(kind); headers != nil {
+		issues = append(issues, checkHeaderOrder(req.Header[http.HeaderOrderKey], headers[http.HeaderOrderKey])...)
+	}
+
+	return issues
+}
+
+// checkClientHints checks that the client hints of the request are the ones a client of uaFamily sends and match its
+// user agent.
+func checkClientHints(req *http.Request, userAgent string, uaFamily string, uaMajor int) []FingerprintIssue {
+	var issues []FingerprintIssue
+
+	report := func(format string, args ...any) {
+		issues = append(issues, FingerprintIssue{Check: FingerprintCheckClientHints, Message: fmt.Sprintf(format, args...)})
+	}
+
+	secChUa, hasSecChUa := headerValue(req.Header, "sec-ch-ua")
+
+	if uaFamily != clientFamilyChrome {
+		for _, name := range sortedHeaderNames(req.Header) {
+			if strings.HasPrefix(strings.ToLower(name), "sec-ch-ua") {
+				report("%s is sent with a %s user-agent, which does not send client hints", strings.ToLower(name), uaFamily)
+			}
+		}
+
+		return issues
+	}
+
+	if !hasSecChUa {
+		if req.URL.Scheme == "https" {
+			report("sec-ch-ua is missing, Chrome sends it on every secure request")
+		}
+
+		return issues
+	}
+
+	if version, ok := brandVersion(secChUa, "Chromium"); ok && uaMajor != 0 && version != strconv.Itoa(uaMajor) {
+		report("sec-ch-ua declares Chromium %s but the user-agent is Chrome %d", version, uaMajor)
+	}
+
+	if mobile, ok := headerValue(req.Header, "sec-ch-ua-mobile"); ok {
+		switch isMobile := strings.Contains(userAgent, "Mobile"); {
+		case mobile == "?1" && !isMobile:
+			report("sec-ch-ua-mobile is ?1 but the user-agent is a desktop one")
+		case mobile != "?1" && isMobile:
+			report("sec-ch-ua-mobile is %s but the user-agent is a mobile one", mobile)
+		}
+	}
+
+	if platform, ok := headerValue(req.Header, "sec-ch-ua-platform"); ok {
+		if uaPlatform := userAgentPlatform(userAgent); uaPlatform != "" && strings.Trim(platform, `"`) != uaPlatform {
+			report("sec-ch-ua-platform is %s but the user-agent runs on %s", platform, uaPlatform)
+		}
+	}
+
+	return issues
+}
+
+// checkHeaderCase checks that the headers sent over HTTP/1.1 are in their canonical case, like browsers send them.
+// Client hints are sent in lowercase.
+func checkHeaderCase(header http.Header) []FingerprintIssue {
+	var issues []FingerprintIssue
+
+	for _, name := range sortedHeaderNames(header) {
+		if name == http.HeaderOrderKey || name == http.PHeaderOrderKey || strings.HasPrefix(strings.ToLower(name), "sec-ch-") {
+			continue
+		}
+
+		if canonical := http.CanonicalHeaderKey(name); canonical != name {
+			issues = append(issues, FingerprintIssue{
+				Check:   FingerprintCheckHeaderCase,
+				Message: fmt.Sprintf("%s is sent over HTTP/1.1, where browsers send %s", name, canonical),
+			})
+		}
+	}
+
+	return issues
+}
+
+// checkAcceptEncoding checks that the request only accepts the content codings the transport decodes.
+func checkAcceptEncoding(header http.Header) []FingerprintIssue {
+	var issues []FingerprintIssue
+
+	acceptEncoding, _ := headerValue(header, "accept-encoding")
+
+	for _, coding := range strings.Split(acceptEncoding, ",") {
+		coding, _, _ = strings.Cut(coding, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+
+		if coding != "" && !containsHint(decodableContentCodings, coding) {
+			issues = append(issues, FingerprintIssue{
+				Check:   FingerprintCheckAcceptEncoding,
+				Message: fmt.Sprintf("accept-encoding accepts %s, which the client cannot decode", coding),
+			})
+		}
+	}
+
+	return issues
+}
+
+// checkPseudoHeaderOrder checks that the pseudo header order of the request is the one of the profile.
+func checkPseudoHeaderOrder(header http.Header, profileOrder []string) []FingerprintIssue {
+	order := header[http.PHeaderOrderKey]
+	if len(order) == 0 || len(profileOrder) == 0 || strings.Join(allToLower(order), ",") == strings.Join(allToLower(profileOrder), ",") {
+		return nil
+	}
+
+	return []FingerprintIssue{{
+		Check:   FingerprintCheckHeaderOrder,
+		Message: fmt.Sprintf("the pseudo header order %s is not the %s of the client profile", strings.Join(order, ","), strings.Join(profileOrder, ",")),
+	}}
+}
+
+// checkHeaderOrder checks that the headers ordered both by the request and the profile are in the same relative order.
+func checkHeaderOrder(order []string, profileOrder []string) []FingerprintIssue {
+	if len(order) == 0 || len(profileOrder) == 0 {
+		return nil
+	}
+
+	positions := make(map[string]int, len(profileOrder))
+	for i, name := range profileOrder {
+		positions[strings.ToLower(name)] = i
+	}
+
+	previous := ""
+	for _, name := range order {
+		name = strings.ToLower(name)
+
+		position, ok := positions[name]
+		if !ok {
+			continue
+		}
+
+		if previous != "" && position < positions[previous] {
+			return []FingerprintIssue{{
+				Check:   FingerprintCheckHeaderOrder,
+				Message: fmt.Sprintf("%s is ordered after %s while the client profile sends it before", name, previous),
+			}}
+		}
+
+		previous = name
+	}
+
+	return nil
+}
+
+// clientFamily returns the family of the client of a tls.ClientHelloID, or an empty string for custom clients.
+func clientFamily(client string) string {
+	switch {
+	case client == "Chrome":
+		return clientFamilyChrome
+	case client == "Firefox":
+		return clientFamilyFirefox
+	case client == "Safari" || client == "iOS" || client == "iPad":
+		return clientFamilySafari
+	case strings.HasPrefix(client, "OkHttp"):
+		return clientFamilyOkHttp
+	default:
+		return ""
+	}
+}
+
+// parseUserAgentFamily returns the family and major version of the client of a user agent. Browsers on iOS are built
+// on WebKit and belong to the Safari family, with an unknown version. It returns an empty family for unknown clients.
+func parseUserAgentFamily(userAgent string) (string, int) {
+	switch {
+	case strings.HasPrefix(strings.ToLower(userAgent), "okhttp/"):
+		return clientFamilyOkHttp, userAgentMajor(userAgent, "okhttp/")
+	case strings.Contains(userAgent, "CriOS/") || strings.Contains(userAgent, "FxiOS/") || strings.Contains(userAgent, "EdgiOS/"):
+		return clientFamilySafari, 0
+	case strings.Contains(userAgent, "Firefox/"):
+		return clientFamilyFirefox, userAgentMajor(userAgent, "Firefox/")
+	case strings.Contains(userAgent, "Chrome/"):
+		return clientFamilyChrome, userAgentMajor(userAgent, "Chrome/")
+	case strings.Contains(userAgent, "Version/") && strings.Contains(userAgent, "Safari/"):
+		return clientFamilySafari, userAgentMajor(userAgent, "Version/")
+	default:
+		return "", 0
+	}
+}
+
+// userAgentMajor returns the major version following the product token of a user agent, or 0 if it has none.
+func userAgentMajor(userAgent string, product string) int {
+	index := strings.Index(strings.ToLower(userAgent), strings.ToLower(product))
+	if index < 0 {
+		return 0
+	}
+
+	version := userAgent[index+len(product):]
+	end := strings.IndexFunc(version, func(r rune) bool { return r < '0' || r > '9' })
+	if end >= 0 {
+		version = version[:end]
+	}
+
+	major, _ := strconv.Atoi(version)
+
+	return major
+}
+
+// userAgentPlatform returns the platform of a user agent as named by sec-ch-ua-platform, or an empty string if unknown.
+func userAgentPlatform(userAgent string) string {
+	switch {
+	case strings.Contains(userAgent, "Android"):
+		return "Android"
+	case strings.Contains(userAgent, "Windows"):
+		return "Windows"
+	case strings.Contains(userAgent, "Macintosh"):
+		return "macOS"
+	case strings.Contains(userAgent, "CrOS"):
+		return "Chrome OS"
+	case strings.Contains(userAgent, "Linux"):
+		return "Linux"
+	default:
+		return ""
+	}
+}
+
+// brandVersion returns the version of the brand of a sec-ch-ua brand list.
+func brandVersion(brandList string, brand string) (string, bool) {
+	for _, entry := range strings.Split(brandList, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(entry), ";")
+		if strings.Trim(name, `"`) != brand {
+			continue
+		}
+
+		version, ok := strings.CutPrefix(strings.TrimSpace(params), "v=")
+
+		return strings.Trim(version, `"`), ok
+	}
+
+	return "", false
+}
+
+// headerValue returns the first value of the header key, its name being compared case-insensitively.
+func headerValue(header http.Header, key string) (string, bool) {
+	for name, values := range header {
+		if strings.EqualFold(name, key) && len(values) > 0 {
+			return values[0], true
+		}
+	}
+
+	return "", false
+}
+
+// sortedHeaderNames returns the names of the headers in sorted order, so that issues are reported in a stable order.
+func sortedHeaderNames(header http.Header) []string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
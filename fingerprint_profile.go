@@ -0,0 +1,170 @@
+package httpkit
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Mathious6/httpkit/profiles"
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/bogdanfinn/fhttp/http2"
+	tls "github.com/bogdanfinn/utls"
+)
+
+// The pseudo headers of the Akamai fingerprint, by their initial.
+var akamaiPseudoHeaders = map[string]string{
+	"m": ":method",
+	"a": ":authority",
+	"s": ":scheme",
+	"p": ":path",
+}
+
+// AkamaiFingerprint is the HTTP/2 fingerprint of a client, in the format SETTINGS|WINDOW_UPDATE|PRIORITY|PSEUDO_HEADER
+// described in https://www.blackhat.com/docs/eu-17/materials/eu-17-Shuster-Passive-Fingerprinting-Of-HTTP2-Clients-wp.pdf.
+type AkamaiFingerprint struct {
+	Settings          map[http2.SettingID]uint32
+	SettingsOrder     []http2.SettingID
+	ConnectionFlow    uint32
+	Priorities        []http2.Priority
+	PseudoHeaderOrder []string
+}
+
+// ParseAkamaiFingerprint parses an Akamai fingerprint, like "1:65536;2:0;4:6291456;6:262144|15663105|0|m,a,s,p".
+// The settings may also be separated by commas.
+func ParseAkamaiFingerprint(akamai string) (AkamaiFingerprint, error) {
+	parts := strings.Split(strings.TrimSpace(akamai), "|")
+	if len(parts) != 4 {
+		return AkamaiFingerprint{}, fmt.Errorf("akamai fingerprint %q does not have 4 parts", akamai)
+	}
+
+	fingerprint := AkamaiFingerprint{Settings: make(map[http2.SettingID]uint32)}
+
+	for _, setting := range strings.FieldsFunc(parts[0], func(r rune) bool { return r == ';' || r == ',' }) {
+		id, value, ok := strings.Cut(setting, ":")
+		if !ok {
+			return AkamaiFingerprint{}, fmt.Errorf("%s is not a valid akamai setting", setting)
+		}
+
+		settingId, err := strconv.ParseUint(id, 10, 16)
+		if err != nil {
+			return AkamaiFingerprint{}, fmt.Errorf("%s is not a valid akamai setting id", id)
+		}
+
+		settingValue, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return AkamaiFingerprint{}, fmt.Errorf("%s is not a valid akamai setting value", value)
+		}
+
+		if _, ok := fingerprint.Settings[http2.SettingID(settingId)]; !ok {
+			fingerprint.SettingsOrder = append(fingerprint.SettingsOrder, http2.SettingID(settingId))
+		}
+
+		fingerprint.Settings[http2.SettingID(settingId)] = uint32(settingValue)
+	}
+
+	connectionFlow, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return AkamaiFingerprint{}, fmt.Errorf("%s is not a valid akamai window update", parts[1])
+	}
+
+	fingerprint.ConnectionFlow = uint32(connectionFlow)
+
+	if parts[2] != "0" {
+		for _, priority := range strings.Split(parts[2], ",") {
+			fields := strings.Split(priority, ":")
+			if len(fields) != 4 {
+				return AkamaiFingerprint{}, fmt.Errorf("%s is not a valid akamai priority", priority)
+			}
+
+			streamId, err1 := strconv.ParseUint(fields[0], 10, 31)
+			exclusive, err2 := strconv.ParseBool(fields[1])
+			streamDep, err3 := strconv.ParseUint(fields[2], 10, 31)
+			weight, err4 := strconv.ParseUint(fields[3], 10, 16)
+
+			if err1 != nil || err2 != nil || err3 != nil || err4 != nil || weight < 1 || weight > 256 {
+				return AkamaiFingerprint{}, fmt.Errorf("%s is not a valid akamai priority", priority)
+			}
+
+			fingerprint.Priorities = append(fingerprint.Priorities, http2.Priority{
+				StreamID: uint32(streamId),
+				PriorityParam: http2.PriorityParam{
+					StreamDep: uint32(streamDep),
+					Exclusive: exclusive,
+					Weight:    uint8(weight - 1),
+				},
+			})
+		}
+	}
+
+	for _, initial := range strings.Split(parts[3], ",") {
+		pseudoHeader, ok := akamaiPseudoHeaders[strings.TrimSpace(initial)]
+		if !ok {
+			return AkamaiFingerprint{}, fmt.Errorf("%s is not a valid akamai pseudo header", initial)
+		}
+
+		fingerprint.PseudoHeaderOrder = append(fingerprint.PseudoHeaderOrder, pseudoHeader)
+	}
+
+	return fingerprint, nil
+}
+
+// FingerprintExtras are the parts of a client profile which neither the JA3 nor the Akamai fingerprint describe.
+type FingerprintExtras struct {
+	// Client and Version name the ClientHelloID of the profile. Client defaults to "Custom" and Version to a hash of the
+	// fingerprints and extras.
+	Client  string
+	Version string
+
+	// Ja3SpecOptions are the contents of the ClientHello extensions, see GetSpecFactoryFromJa3Options.
+	Ja3SpecOptions
+
+	// HeaderPriority is the priority of the HEADERS frames, nil if they have none.
+	HeaderPriority *http2.PriorityParam
+	// Headers are the headers the profile sends for each kind of request, see profiles.ClientProfile.WithHeaders.
+	Headers map[profiles.RequestKind]http.Header
+	// QUICProfile is the HTTP/3 description of the profile, nil if it does not speak HTTP/3.
+	QUICProfile *profiles.QUICProfile
+}
+
+// ProfileFromFingerprints builds a client profile sending the ClientHello of the JA3 string ja3 and the HTTP/2 settings,
+// connection flow, priorities and pseudo header order of the Akamai fingerprint akamai, completed by extras. The
+// warnings report the Ja3SpecOptions of extras which are ignored or missing, see GetSpecFactoryFromJa3Options.
+func ProfileFromFingerprints(ja3 string, akamai string, extras FingerprintExtras) (profiles.ClientProfile, []string, error) {
+	h2, err := ParseAkamaiFingerprint(akamai)
+	if err != nil {
+		return profiles.ClientProfile{}, nil, err
+	}
+
+	specFactory, warnings, err := GetSpecFactoryFromJa3Options(ja3, extras.Ja3SpecOptions)
+	if err != nil {
+		return profiles.ClientProfile{}, nil, err
+	}
+
+	client := extras.Client
+	if client == "" {
+		client = "Custom"
+	}
+
+	version := extras.Version
+	if version == "" {
+		tlsExtras := extras
+		tlsExtras.HeaderPriority, tlsExtras.Headers, tlsExtras.QUICProfile = nil, nil, nil
+
+		hash := md5.Sum([]byte(fmt.Sprintf("%s|%s|%v", ja3, akamai, tlsExtras)))
+		version = hex.EncodeToString(hash[:8])
+	}
+
+	profile := profiles.NewClientProfile(tls.ClientHelloID{
+		Client:      client,
+		Version:     version,
+		SpecFactory: specFactory,
+	}, h2.Settings, h2.SettingsOrder, h2.PseudoHeaderOrder, h2.ConnectionFlow, h2.Priorities, extras.HeaderPriority, extras.QUICProfile)
+
+	if extras.Headers != nil {
+		profile = profile.WithHeaders(extras.Headers)
+	}
+
+	return profile, warnings, nil
+}
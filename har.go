@@ -0,0 +1,665 @@
+package httpkit
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	http "github.com/bogdanfinn/fhttp"
+)
+
+// defaultHarMaxBodySize is the number of bytes of the bodies recorded when HarOptions.MaxBodySize is zero.
+const defaultHarMaxBodySize = 1 << 20
+
+// HarOptions configures the HAR recorder of a client, see WithHarRecorder.
+type HarOptions struct {
+	// EntryWriter receives the entries as newline delimited JSON (NDJSON), one HarEntry per line written once it is
+	// complete, if set. The output is not a HAR file: wrap the entries in a Har to load them in HAR tools. The entries
+	// are then not kept in memory. Write errors are ignored.
+	EntryWriter io.Writer
+	// MaxBodySize is the number of bytes of the request and response bodies recorded, 1 MiB if zero. The bodies are
+	// not recorded if negative.
+	MaxBodySize int
+}
+
+// Har is an HTTP Archive, see http://www.softwareishard.com/blog/har-12-spec/.
+type Har struct {
+	Log HarLog `json:"log"`
+}
+
+type HarLog struct {
+	Version string     `json:"version"`
+	Creator HarCreator `json:"creator"`
+	Entries []HarEntry `json:"entries"`
+}
+
+type HarCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HarEntry is an exchange of the archive, a single hop of a redirect chain or a single attempt of a retried request.
+type HarEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Request         HarRequest  `json:"request"`
+	Response        HarResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HarTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	// Connection is the local address of the connection the request was sent on.
+	Connection string `json:"connection,omitempty"`
+	// Protocol is the application protocol of the connection: "http/1.1", "h2" or "h3".
+	Protocol string `json:"_protocol,omitempty"`
+	// Error is the error which failed the request, in which case the response is empty.
+	Error string `json:"_error,omitempty"`
+	// Time is the total time of the exchange in milliseconds, the sum of the timings.
+	Time float64 `json:"time"`
+}
+
+type HarRequest struct {
+	PostData    *HarPostData   `json:"postData,omitempty"`
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HarCookie    `json:"cookies"`
+	Headers     []HarNameValue `json:"headers"`
+	QueryString []HarNameValue `json:"queryString"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type HarResponse struct {
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	RedirectURL string         `json:"redirectURL"`
+	Cookies     []HarCookie    `json:"cookies"`
+	Headers     []HarNameValue `json:"headers"`
+	Content     HarContent     `json:"content"`
+	Status      int            `json:"status"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type HarNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HarCookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Path     string `json:"path,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+	Expires  string `json:"expires,omitempty"`
+	SameSite string `json:"sameSite,omitempty"`
+	HTTPOnly bool   `json:"httpOnly,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+}
+
+type HarPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HarContent is the body of a response. Binary bodies are base64 encoded.
+type HarContent struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+	Size     int64  `json:"size"`
+}
+
+// HarTimings are the durations of the phases of an exchange in milliseconds, -1 for the phases which did not happen.
+// Connect includes SSL.
+type HarTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// HarRecorder records the traffic of a client as an HTTP Archive. It is enabled with WithHarRecorder and retrieved
+// with HttpClient.GetHarRecorder.
+type HarRecorder struct {
+	options HarOptions
+	entries []HarEntry
+	sync.Mutex
+}
+
+func newHarRecorder(options HarOptions) *HarRecorder {
+	if options.MaxBodySize == 0 {
+		options.MaxBodySize = defaultHarMaxBodySize
+	}
+
+	return &HarRecorder{options: options}
+}
+
+// Har returns the archive of the entries recorded so far, in the order they completed.
+func (r *HarRecorder) Har() *Har {
+	r.Lock()
+	defer r.Unlock()
+
+	return &Har{Log: HarLog{
+		Version: "1.2",
+		Creator: harCreator(),
+		Entries: append([]HarEntry{}, r.entries...),
+	}}
+}
+
+// WriteTo writes the archive of the entries recorded so far to w as JSON.
+func (r *HarRecorder) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.Marshal(r.Har())
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(data)
+
+	return int64(n), err
+}
+
+// Reset drops the entries recorded so far.
+func (r *HarRecorder) Reset() {
+	r.Lock()
+	defer r.Unlock()
+
+	r.entries = nil
+}
+
+func (r *HarRecorder) add(entry HarEntry) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.options.EntryWriter == nil {
+		r.entries = append(r.entries, entry)
+		return
+	}
+
+	data, err := json.Marshal(entry)
+	if err == nil {
+		_, _ = r.options.EntryWriter.Write(append(data, '\n'))
+	}
+}
+
+// transport returns a round tripper recording every exchange sent through next.
+func (r *HarRecorder) transport(next http.RoundTripper) http.RoundTripper {
+	return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		exchange := &harExchange{recorder: r, start: time.Now()}
+
+		sent := req.WithContext(WithClientTrace(req.Context(), exchange.trace(ContextClientTrace(req.Context()))))
+
+		if req.Body != nil && req.Body != http.NoBody {
+			exchange.requestBody = &harBody{ReadCloser: req.Body, limit: r.options.MaxBodySize}
+			sent.Body = exchange.requestBody
+		}
+
+		resp, err := next.RoundTrip(sent)
+		if err != nil {
+			exchange.finish(sent, nil, err)
+			return nil, err
+		}
+
+		exchange.lck.Lock()
+		exchange.responded = time.Now()
+		exchange.lck.Unlock()
+
+		if resp.Body == nil || resp.Body == http.NoBody {
+			exchange.finish(sent, resp, nil)
+			return resp, nil
+		}
+
+		exchange.responseBody = &harBody{ReadCloser: resp.Body, limit: r.options.MaxBodySize, done: func() {
+			exchange.finish(sent, resp, nil)
+		}}
+		resp.Body = exchange.responseBody
+
+		return resp, nil
+	})
+}
+
+// harExchange collects the timings and bodies of an exchange until it completes.
+type harExchange struct {
+	recorder     *HarRecorder
+	requestBody  *harBody
+	responseBody *harBody
+	conn         net.Conn
+	protocol     string
+
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	firstByte    time.Time
+	responded    time.Time
+
+	finished bool
+	lck      sync.Mutex
+}
+
+// trace returns a trace recording the timings of the exchange, which also fires the hooks of parent.
+func (e *harExchange) trace(parent *ClientTrace) *ClientTrace {
+	if parent == nil {
+		parent = &ClientTrace{}
+	}
+
+	record := func(at *time.Time, first bool) {
+		e.lck.Lock()
+		defer e.lck.Unlock()
+
+		if !first || at.IsZero() {
+			*at = time.Now()
+		}
+	}
+
+	return &ClientTrace{
+		DNSStart: func(host string) {
+			record(&e.dnsStart, true)
+			parent.dnsStart(host)
+		},
+		DNSDone: func(addrs []net.IPAddr, err error) {
+			record(&e.dnsDone, false)
+			parent.dnsDone(addrs, err)
+		},
+		ConnectStart: func(network, addr string) {
+			record(&e.connectStart, true)
+			parent.connectStart(network, addr)
+		},
+		ConnectDone: func(network, addr string, err error) {
+			record(&e.connectDone, false)
+			parent.connectDone(network, addr, err)
+		},
+		ProxyConnectStart: parent.proxyConnectStart,
+		ProxyConnectDone: func(proxyAddr, targetAddr string, err error) {
+			record(&e.connectDone, false)
+			parent.proxyConnectDone(proxyAddr, targetAddr, err)
+		},
+		TLSHandshakeStart: func(serverName string) {
+			record(&e.tlsStart, true)
+			parent.tlsHandshakeStart(serverName)
+		},
+		TLSHandshakeDone: func(info TLSHandshakeInfo, err error) {
+			record(&e.tlsDone, false)
+
+			if parent.TLSHandshakeDone != nil {
+				parent.TLSHandshakeDone(info, err)
+			}
+		},
+		// GotConn and GotFirstResponseByte of parent are already fired by the transports.
+		GotConn: func(info GotConnInfo) {
+			record(&e.gotConn, true)
+
+			e.lck.Lock()
+			e.conn = info.Conn
+			e.protocol = info.Protocol
+			e.lck.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			record(&e.firstByte, true)
+		},
+	}
+}
+
+// finish records the exchange once its response body was read or closed, or once it failed.
+func (e *harExchange) finish(req *http.Request, resp *http.Response, err error) {
+	e.lck.Lock()
+	if e.finished {
+		e.lck.Unlock()
+		return
+	}
+
+	e.finished = true
+	end := time.Now()
+
+	entry := HarEntry{
+		StartedDateTime: e.start.Format(time.RFC3339Nano),
+		Protocol:        e.protocol,
+		Timings:         e.timings(end),
+	}
+
+	if e.conn != nil {
+		entry.Connection = e.conn.LocalAddr().String()
+
+		if host, _, splitErr := net.SplitHostPort(e.conn.RemoteAddr().String()); splitErr == nil {
+			entry.ServerIPAddress = host
+		}
+	}
+	e.lck.Unlock()
+
+	if info := ConnInfo(resp); info != nil && info.Protocol != "" {
+		entry.Protocol = info.Protocol
+	}
+
+	for _, timing := range []float64{entry.Timings.Blocked, entry.Timings.DNS, entry.Timings.Connect, entry.Timings.Send, entry.Timings.Wait, entry.Timings.Receive} {
+		if timing > 0 {
+			entry.Time += timing
+		}
+	}
+
+	entry.Request = harRequest(req, entry.Protocol, e.requestBody)
+
+	if err != nil {
+		entry.Error = err.Error()
+		entry.Response = HarResponse{Cookies: []HarCookie{}, Headers: []HarNameValue{}, HeadersSize: -1, BodySize: -1}
+	} else {
+		entry.Response = harResponse(resp, entry.Protocol, e.responseBody)
+	}
+
+	e.recorder.add(entry)
+}
+
+// timings returns the timings of the exchange which ended at end. It must be called with the lock held.
+func (e *harExchange) timings(end time.Time) HarTimings {
+	timings := HarTimings{Blocked: -1, DNS: -1, Connect: -1, Send: 0, Wait: -1, Receive: 0, SSL: -1}
+
+	sent := e.gotConn
+	if sent.IsZero() {
+		sent = e.start
+	}
+
+	if firstPhase := firstTime(e.dnsStart, e.connectStart, e.gotConn); !firstPhase.IsZero() {
+		timings.Blocked = harDuration(firstPhase.Sub(e.start))
+	}
+
+	if !e.dnsStart.IsZero() && !e.dnsDone.IsZero() {
+		timings.DNS = harDuration(e.dnsDone.Sub(e.dnsStart))
+	}
+
+	if connectDone := lastTime(e.connectDone, e.tlsDone); !e.connectStart.IsZero() && !connectDone.IsZero() {
+		timings.Connect = harDuration(connectDone.Sub(e.connectStart))
+	}
+
+	if !e.tlsStart.IsZero() && !e.tlsDone.IsZero() {
+		timings.SSL = harDuration(e.tlsDone.Sub(e.tlsStart))
+	}
+
+	firstByte := e.firstByte
+	if firstByte.IsZero() {
+		firstByte = e.responded
+	}
+
+	if !firstByte.IsZero() {
+		timings.Wait = harDuration(firstByte.Sub(sent))
+		timings.Receive = harDuration(end.Sub(firstByte))
+	}
+
+	return timings
+}
+
+func harRequest(req *http.Request, protocol string, body *harBody) HarRequest {
+	request := HarRequest{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		HTTPVersion: harHTTPVersion(protocol, req.Proto),
+		Cookies:     []HarCookie{},
+		Headers:     orderedHarHeaders(req.Header),
+		QueryString: []HarNameValue{},
+		HeadersSize: -1,
+	}
+
+	// the Host header is sent first over HTTP/1.1, whether the transport added it to the headers or not
+	if protocol == "http/1.1" || protocol == "" {
+		host := req.Host
+		if host == "" {
+			host = req.URL.Host
+		}
+
+		headers := []HarNameValue{{Name: "Host", Value: host}}
+		for _, header := range request.Headers {
+			if !strings.EqualFold(header.Name, "host") {
+				headers = append(headers, header)
+			}
+		}
+
+		request.Headers = headers
+	}
+
+	for _, cookie := range req.Cookies() {
+		request.Cookies = append(request.Cookies, HarCookie{Name: cookie.Name, Value: cookie.Value})
+	}
+
+	query := req.URL.Query()
+	for _, name := range sortedKeys(query) {
+		for _, value := range query[name] {
+			request.QueryString = append(request.QueryString, HarNameValue{Name: name, Value: value})
+		}
+	}
+
+	if body != nil {
+		text, _, comment := body.content()
+
+		request.BodySize = body.size
+		request.PostData = &HarPostData{MimeType: req.Header.Get("Content-Type"), Text: text, Comment: comment}
+	}
+
+	return request
+}
+
+func harResponse(resp *http.Response, protocol string, body *harBody) HarResponse {
+	response := HarResponse{
+		Status:      resp.StatusCode,
+		StatusText:  strings.TrimSpace(strings.TrimPrefix(resp.Status, fmt.Sprint(resp.StatusCode))),
+		HTTPVersion: harHTTPVersion(protocol, resp.Proto),
+		RedirectURL: resp.Header.Get("Location"),
+		Cookies:     []HarCookie{},
+		Headers:     orderedHarHeaders(resp.Header),
+		Content:     HarContent{MimeType: resp.Header.Get("Content-Type")},
+		HeadersSize: -1,
+	}
+
+	for _, cookie := range resp.Cookies() {
+		harCookie := HarCookie{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Path:     cookie.Path,
+			Domain:   cookie.Domain,
+			HTTPOnly: cookie.HttpOnly,
+			Secure:   cookie.Secure,
+			SameSite: sameSiteString(cookie.SameSite),
+		}
+
+		if !cookie.Expires.IsZero() {
+			harCookie.Expires = cookie.Expires.UTC().Format(time.RFC3339)
+		}
+
+		response.Cookies = append(response.Cookies, harCookie)
+	}
+
+	if body != nil {
+		response.BodySize = body.size
+		response.Content.Size = body.size
+		response.Content.Text, response.Content.Encoding, response.Content.Comment = body.content()
+	}
+
+	return response
+}
+
+// orderedHarHeaders returns the headers in the order they are sent: the ones of the header order first, the others
+// sorted by name.
+func orderedHarHeaders(header http.Header) []HarNameValue {
+	positions := make(map[string]int)
+	for i, name := range header[http.HeaderOrderKey] {
+		positions[strings.ToLower(name)] = i
+	}
+
+	names := make([]string, 0, len(header))
+	for name := range header {
+		if name != http.HeaderOrderKey && name != http.PHeaderOrderKey {
+			names = append(names, name)
+		}
+	}
+
+	sort.SliceStable(names, func(i, j int) bool {
+		pi, iok := positions[strings.ToLower(names[i])]
+		pj, jok := positions[strings.ToLower(names[j])]
+
+		if iok != jok {
+			return iok
+		}
+
+		if iok {
+			return pi < pj
+		}
+
+		return names[i] < names[j]
+	})
+
+	headers := make([]HarNameValue, 0, len(names))
+	for _, name := range names {
+		for _, value := range header[name] {
+			headers = append(headers, HarNameValue{Name: name, Value: value})
+		}
+	}
+
+	return headers
+}
+
+// harHTTPVersion returns the HTTP version of an exchange over protocol, like browsers report it.
+func harHTTPVersion(protocol string, proto string) string {
+	switch protocol {
+	case "h2":
+		return "HTTP/2.0"
+	case "h3":
+		return "HTTP/3.0"
+	case "http/1.1":
+		return "HTTP/1.1"
+	default:
+		return proto
+	}
+}
+
+// harBody records up to limit bytes of a body while it is read, and calls done once it is read or closed.
+type harBody struct {
+	io.ReadCloser
+	done   func()
+	buf    bytes.Buffer
+	limit  int
+	size   int64
+	lck    sync.Mutex
+	closed bool
+}
+
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	b.lck.Lock()
+	b.size += int64(n)
+	if remaining := b.limit - b.buf.Len(); remaining > 0 {
+		b.buf.Write(p[:min(n, remaining)])
+	}
+	b.lck.Unlock()
+
+	if err == io.EOF {
+		b.complete()
+	}
+
+	return n, err
+}
+
+func (b *harBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.complete()
+
+	return err
+}
+
+func (b *harBody) complete() {
+	b.lck.Lock()
+	done := b.done
+	closed := b.closed
+	b.closed = true
+	b.lck.Unlock()
+
+	if !closed && done != nil {
+		done()
+	}
+}
+
+// content returns the recorded body as text, base64 encoded if it is binary, with a comment if it was truncated.
+func (b *harBody) content() (text string, encoding string, comment string) {
+	b.lck.Lock()
+	defer b.lck.Unlock()
+
+	if b.limit < 0 {
+		return "", "", ""
+	}
+
+	if int64(b.buf.Len()) < b.size {
+		comment = fmt.Sprintf("body truncated to %d of %d bytes", b.buf.Len(), b.size)
+	}
+
+	if utf8.Valid(b.buf.Bytes()) {
+		return b.buf.String(), "", comment
+	}
+
+	return base64.StdEncoding.EncodeToString(b.buf.Bytes()), "base64", comment
+}
+
+// harCreator returns the creator of the archives, this module with its version if known.
+func harCreator() HarCreator {
+	creator := HarCreator{Name: "httpkit", Version: "(devel)"}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, module := range info.Deps {
+			if module.Path == "github.com/Mathious6/httpkit" {
+				creator.Version = module.Version
+			}
+		}
+	}
+
+	return creator
+}
+
+func harDuration(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func firstTime(times ...time.Time) time.Time {
+	var first time.Time
+	for _, t := range times {
+		if !t.IsZero() && (first.IsZero() || t.Before(first)) {
+			first = t
+		}
+	}
+
+	return first
+}
+
+func lastTime(times ...time.Time) time.Time {
+	var last time.Time
+	for _, t := range times {
+		if t.After(last) {
+			last = t
+		}
+	}
+
+	return last
+}
+
+func sortedKeys(values map[string][]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -2,229 +2,414 @@ package httpkit
 
 import (
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 
 	tls "github.com/bogdanfinn/utls"
 )
 
+// ErrInvalidJa3 is matched by the errors of malformed JA3 strings.
+var ErrInvalidJa3 = errors.New("invalid ja3 string")
+
+// Ja3FieldError is returned for a JA3 string with a malformed field: Field is "format" if it does not have five fields,
+// otherwise one of "version", "cipher suite", "extension", "curve" or "point format".
+type Ja3FieldError struct {
+	Field string
+	Value string
+}
+
+func (e *Ja3FieldError) Error() string {
+	return fmt.Sprintf("%s: %s %q is not valid", ErrInvalidJa3, e.Field, e.Value)
+}
+
+func (e *Ja3FieldError) Unwrap() error {
+	return ErrInvalidJa3
+}
+
+// UnknownExtensionError is returned for a JA3 string with an extension which has no payload in
+// Ja3SpecOptions.ExtensionPayloads and is either not supported or lacks the options it is built from, like the
+// certificate compression extension without CertCompressionAlgorithms.
+type UnknownExtensionError struct {
+	Id uint16
+}
+
+func (e *UnknownExtensionError) Error() string {
+	return fmt.Sprintf("unknown extension with id %d provided", e.Id)
+}
+
 type CandidateCipherSuites struct {
 	KdfId  string
 	AeadId string
 }
 
+// Ja3SpecOptions are the contents of the ClientHello extensions a JA3 string does not describe. The names of the
+// signature algorithms, versions, curves and certificate compression algorithms are the keys of the maps of mapper.go;
+// signature algorithms may also be given as hexadecimal values.
+type Ja3SpecOptions struct {
+	SignatureAlgorithms            []string
+	DelegatedCredentialsAlgorithms []string
+	SupportedVersions              []string
+	KeyShareCurves                 []string
+	ALPNProtocols                  []string
+	ALPSProtocols                  []string
+	ECHCandidateCipherSuites       []CandidateCipherSuites
+	ECHCandidatePayloads           []uint16
+	CertCompressionAlgorithms      []string
+	RecordSizeLimit                uint16
+
+	// ExtensionPayloads are the raw data of extensions by id, sent as is in place of the supported extension of the id if
+	// any. They allow to send extensions which are not supported.
+	ExtensionPayloads map[uint16][]byte
+}
+
+// ja3Spec are the fields of a JA3 string.
+type ja3Spec struct {
+	cipherSuites []uint16
+	extensions   []uint16
+	curves       []tls.CurveID
+	pointFormats []byte
+}
+
+// ja3Extensions are the contents of the extensions resolved from Ja3SpecOptions.
+type ja3Extensions struct {
+	signatureAlgorithms            []tls.SignatureScheme
+	delegatedCredentialsAlgorithms []tls.SignatureScheme
+	hpkeSymmetricCipherSuites      []tls.HPKESymmetricCipherSuite
+	tlsVersions                    []uint16
+	keyShares                      []tls.KeyShare
+	certCompressionAlgorithms      []tls.CertCompressionAlgo
+	options                        Ja3SpecOptions
+}
+
+// GetSpecFactoryFromJa3Options returns a factory of the ClientHelloSpec of the JA3 string ja3String completed by
+// options. The JA3 string is validated right away, returning a *Ja3FieldError or *UnknownExtensionError. The warnings
+// report options which are ignored or missing for the extensions of the JA3 string.
+func GetSpecFactoryFromJa3Options(ja3String string, options Ja3SpecOptions) (func() (tls.ClientHelloSpec, error), []string, error) {
+	ja3, err := parseJa3(ja3String)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	extensions, warnings, err := options.resolve()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	warnings = append(warnings, ja3.missingOptions(options)...)
+
+	factory := func() (tls.ClientHelloSpec, error) {
+		return stringToSpec(ja3, extensions)
+	}
+
+	if _, err := factory(); err != nil {
+		return nil, nil, err
+	}
+
+	return factory, warnings, nil
+}
+
+// GetSpecFactoryFromJa3String returns a factory of the ClientHelloSpec of the JA3 string ja3String completed by the
+// contents of the extensions.
+//
+// Deprecated: use GetSpecFactoryFromJa3Options, which also returns the warnings.
 func GetSpecFactoryFromJa3String(ja3String string, supportedSignatureAlgorithms, supportedDelegatedCredentialsAlgorithms, supportedVersions, keyShareCurves, supportedProtocolsALPN, supportedProtocolsALPS []string, echCandidateCipherSuites []CandidateCipherSuites, candidatePayloads []uint16, certCompressionAlgorithms []string, recordSizeLimit uint16) (func() (tls.ClientHelloSpec, error), error) {
-	return func() (tls.ClientHelloSpec, error) {
-		var mappedSignatureAlgorithms []tls.SignatureScheme
-
-		for _, supportedSignatureAlgorithm := range supportedSignatureAlgorithms {
-			signatureAlgorithm, ok := signatureAlgorithms[supportedSignatureAlgorithm]
-			if ok {
-				mappedSignatureAlgorithms = append(mappedSignatureAlgorithms, signatureAlgorithm)
-			} else {
-				supportedSignatureAlgorithmAsUint, err := strconv.ParseUint(supportedSignatureAlgorithm, 16, 16)
-				if err != nil {
-					return tls.ClientHelloSpec{}, fmt.Errorf("%s is not a valid supportedSignatureAlgorithm", supportedSignatureAlgorithm)
-				}
-
-				mappedSignatureAlgorithms = append(mappedSignatureAlgorithms, tls.SignatureScheme(uint16(supportedSignatureAlgorithmAsUint)))
-			}
-		}
+	factory, _, err := GetSpecFactoryFromJa3Options(ja3String, Ja3SpecOptions{
+		SignatureAlgorithms:            supportedSignatureAlgorithms,
+		DelegatedCredentialsAlgorithms: supportedDelegatedCredentialsAlgorithms,
+		SupportedVersions:              supportedVersions,
+		KeyShareCurves:                 keyShareCurves,
+		ALPNProtocols:                  supportedProtocolsALPN,
+		ALPSProtocols:                  supportedProtocolsALPS,
+		ECHCandidateCipherSuites:       echCandidateCipherSuites,
+		ECHCandidatePayloads:           candidatePayloads,
+		CertCompressionAlgorithms:      certCompressionAlgorithms,
+		RecordSizeLimit:                recordSizeLimit,
+	})
+
+	return factory, err
+}
+
+// parseJa3 parses the fields of a JA3 string.
+func parseJa3(ja3String string) (ja3Spec, error) {
+	parts := strings.Split(ja3String, ",")
+	if len(parts) != 5 {
+		return ja3Spec{}, &Ja3FieldError{Field: "format", Value: ja3String}
+	}
 
-		var mappedDelegatedCredentialsAlgorithms []tls.SignatureScheme
+	if _, err := strconv.ParseUint(parts[0], 10, 16); err != nil {
+		return ja3Spec{}, &Ja3FieldError{Field: "version", Value: parts[0]}
+	}
 
-		for _, supportedDelegatedCredentialsAlgorithm := range supportedDelegatedCredentialsAlgorithms {
-			delegatedCredentialsAlgorithm, ok := delegatedCredentialsAlgorithms[supportedDelegatedCredentialsAlgorithm]
-			if ok {
-				mappedDelegatedCredentialsAlgorithms = append(mappedDelegatedCredentialsAlgorithms, delegatedCredentialsAlgorithm)
-			} else {
-				supportedDelegatedCredentialsAlgorithmAsUint, err := strconv.ParseUint(supportedDelegatedCredentialsAlgorithm, 16, 16)
-				if err != nil {
-					return tls.ClientHelloSpec{}, fmt.Errorf("%s is not a valid supportedDelegatedCredentialsAlgorithm", supportedDelegatedCredentialsAlgorithm)
-				}
+	var ja3 ja3Spec
 
-				mappedDelegatedCredentialsAlgorithms = append(mappedDelegatedCredentialsAlgorithms, tls.SignatureScheme(uint16(supportedDelegatedCredentialsAlgorithmAsUint)))
-			}
+	if parts[1] == "" {
+		return ja3Spec{}, &Ja3FieldError{Field: "cipher suite", Value: parts[1]}
+	}
+
+	for _, c := range strings.Split(parts[1], "-") {
+		cid, err := strconv.ParseUint(c, 10, 16)
+		if err != nil {
+			return ja3Spec{}, &Ja3FieldError{Field: "cipher suite", Value: c}
 		}
 
-		var mappedHpkeSymmetricCipherSuites []tls.HPKESymmetricCipherSuite
-
-		for _, echCandidateCipherSuites := range echCandidateCipherSuites {
-			kdfId, ok1 := kdfIds[echCandidateCipherSuites.KdfId]
-
-			aeadId, ok2 := aeadIds[echCandidateCipherSuites.AeadId]
-			if ok1 && ok2 {
-				mappedHpkeSymmetricCipherSuites = append(mappedHpkeSymmetricCipherSuites, tls.HPKESymmetricCipherSuite{
-					KdfId:  kdfId,
-					AeadId: aeadId,
-				})
-			} else {
-				kdfId, err := strconv.ParseUint(echCandidateCipherSuites.KdfId, 16, 16)
-				if err != nil {
-					return tls.ClientHelloSpec{}, fmt.Errorf("%s is not a valid KdfId", echCandidateCipherSuites.KdfId)
-				}
-
-				aeadId, err := strconv.ParseUint(echCandidateCipherSuites.AeadId, 16, 16)
-				if err != nil {
-					return tls.ClientHelloSpec{}, fmt.Errorf("%s is not a valid aeadId", echCandidateCipherSuites.AeadId)
-				}
-
-				mappedHpkeSymmetricCipherSuites = append(mappedHpkeSymmetricCipherSuites, tls.HPKESymmetricCipherSuite{
-					KdfId:  uint16(kdfId),
-					AeadId: uint16(aeadId),
-				})
-			}
+		ja3.cipherSuites = append(ja3.cipherSuites, uint16(cid))
+	}
+
+	for _, e := range splitJa3List(parts[2]) {
+		eid, err := strconv.ParseUint(e, 10, 16)
+		if err != nil {
+			return ja3Spec{}, &Ja3FieldError{Field: "extension", Value: e}
 		}
 
-		var mappedTlsVersions []uint16
+		ja3.extensions = append(ja3.extensions, uint16(eid))
+	}
 
-		for _, version := range supportedVersions {
-			mappedVersion, ok := tlsVersions[version]
-			if ok {
-				mappedTlsVersions = append(mappedTlsVersions, mappedVersion)
-			}
+	for _, c := range splitJa3List(parts[3]) {
+		cid, err := strconv.ParseUint(c, 10, 16)
+		if err != nil {
+			return ja3Spec{}, &Ja3FieldError{Field: "curve", Value: c}
 		}
 
-		var mappedKeyShares []tls.KeyShare
+		ja3.curves = append(ja3.curves, tls.CurveID(cid))
+	}
+
+	for _, p := range splitJa3List(parts[4]) {
+		pid, err := strconv.ParseUint(p, 10, 8)
+		if err != nil {
+			return ja3Spec{}, &Ja3FieldError{Field: "point format", Value: p}
+		}
 
-		for _, keyShareCurve := range keyShareCurves {
-			resolvedKeyShare, ok := curves[keyShareCurve]
+		ja3.pointFormats = append(ja3.pointFormats, byte(pid))
+	}
 
-			if !ok {
-				continue
-			}
+	return ja3, nil
+}
 
-			mappedKeyShare := tls.KeyShare{Group: resolvedKeyShare}
+func splitJa3List(list string) []string {
+	if list == "" {
+		return nil
+	}
 
-			if keyShareCurve == "GREASE" {
-				mappedKeyShare.Data = []byte{0}
-			}
+	return strings.Split(list, "-")
+}
 
-			mappedKeyShares = append(mappedKeyShares, mappedKeyShare)
+// hasExtension reports whether the JA3 string defines the extension id.
+func (ja3 ja3Spec) hasExtension(id uint16) bool {
+	for _, extension := range ja3.extensions {
+		if extension == id {
+			return true
 		}
+	}
 
-		var mappedCertCompressionAlgorithms []tls.CertCompressionAlgo
+	return false
+}
 
-		for _, certCompressionAlgorithm := range certCompressionAlgorithms {
-			compressionAlgo, ok := certCompression[certCompressionAlgorithm]
-			if ok {
-				mappedCertCompressionAlgorithms = append(mappedCertCompressionAlgorithms, compressionAlgo)
-			}
+// missingOptions returns warnings for the extensions of the JA3 string without the options they are built from.
+func (ja3 ja3Spec) missingOptions(options Ja3SpecOptions) []string {
+	required := []struct {
+		extension uint16
+		name      string
+		missing   bool
+	}{
+		{tls.ExtensionSignatureAlgorithms, "SignatureAlgorithms", len(options.SignatureAlgorithms) == 0},
+		{tls.ExtensionSupportedVersions, "SupportedVersions", len(options.SupportedVersions) == 0},
+		{tls.ExtensionKeyShare, "KeyShareCurves", len(options.KeyShareCurves) == 0},
+		{tls.ExtensionALPN, "ALPNProtocols", len(options.ALPNProtocols) == 0},
+		{tls.ExtensionALPS, "ALPSProtocols", len(options.ALPSProtocols) == 0},
+		{tls.ExtensionALPSOld, "ALPSProtocols", len(options.ALPSProtocols) == 0},
+		{tls.ExtensionDelegatedCredentials, "DelegatedCredentialsAlgorithms", len(options.DelegatedCredentialsAlgorithms) == 0},
+		{tls.ExtensionRecordSizeLimit, "RecordSizeLimit", options.RecordSizeLimit == 0},
+	}
+
+	var warnings []string
+
+	for _, r := range required {
+		if _, ok := options.ExtensionPayloads[r.extension]; r.missing && !ok && ja3.hasExtension(r.extension) {
+			warnings = append(warnings, fmt.Sprintf("ja3 defines extension %d but %s is not set", r.extension, r.name))
 		}
+	}
 
-		return stringToSpec(ja3String, mappedSignatureAlgorithms, mappedDelegatedCredentialsAlgorithms, mappedTlsVersions, mappedKeyShares, mappedHpkeSymmetricCipherSuites, candidatePayloads, supportedProtocolsALPN, supportedProtocolsALPS, mappedCertCompressionAlgorithms, recordSizeLimit)
-	}, nil
+	return warnings
 }
 
-func stringToSpec(ja3 string, signatureAlgorithms []tls.SignatureScheme, delegatedCredentialsAlgorithms []tls.SignatureScheme, tlsVersions []uint16, keyShares []tls.KeyShare, hpkeSymmetricCipherSuites []tls.HPKESymmetricCipherSuite, candidatePayloads []uint16, supportedProtocolsALPN, supportedProtocolsALPS []string, certCompressionAlgorithms []tls.CertCompressionAlgo, recordSizeLimit uint16) (tls.ClientHelloSpec, error) {
-	extMap := getExtensionBaseMap()
-	ja3StringParts := strings.Split(ja3, ",")
+// resolve maps the names of the options to their values. Unknown names of versions, curves and certificate compression
+// algorithms are ignored with a warning. The options are copied, so that the caller may reuse them.
+func (o Ja3SpecOptions) resolve() (ja3Extensions, []string, error) {
+	extensions := ja3Extensions{options: o}
 
-	ciphers := strings.Split(ja3StringParts[1], "-")
-	extensions := strings.Split(ja3StringParts[2], "-")
-	curves := strings.Split(ja3StringParts[3], "-")
+	extensions.options.ALPNProtocols = slices.Clone(o.ALPNProtocols)
+	extensions.options.ALPSProtocols = slices.Clone(o.ALPSProtocols)
+	extensions.options.ECHCandidatePayloads = slices.Clone(o.ECHCandidatePayloads)
 
-	if len(curves) == 1 && curves[0] == "" {
-		curves = []string{}
+	if o.ExtensionPayloads != nil {
+		extensions.options.ExtensionPayloads = make(map[uint16][]byte, len(o.ExtensionPayloads))
+		for id, payload := range o.ExtensionPayloads {
+			extensions.options.ExtensionPayloads[id] = slices.Clone(payload)
+		}
 	}
 
-	pointFormats := strings.Split(ja3StringParts[4], "-")
-	if len(pointFormats) == 1 && pointFormats[0] == "" {
-		pointFormats = []string{}
+	var warnings []string
+
+	for _, supportedSignatureAlgorithm := range o.SignatureAlgorithms {
+		signatureAlgorithm, ok := signatureAlgorithms[supportedSignatureAlgorithm]
+		if ok {
+			extensions.signatureAlgorithms = append(extensions.signatureAlgorithms, signatureAlgorithm)
+		} else {
+			supportedSignatureAlgorithmAsUint, err := strconv.ParseUint(supportedSignatureAlgorithm, 16, 16)
+			if err != nil {
+				return ja3Extensions{}, nil, fmt.Errorf("%s is not a valid supportedSignatureAlgorithm", supportedSignatureAlgorithm)
+			}
+
+			extensions.signatureAlgorithms = append(extensions.signatureAlgorithms, tls.SignatureScheme(uint16(supportedSignatureAlgorithmAsUint)))
+		}
 	}
 
-	var targetCurves []tls.CurveID
-	for _, c := range curves {
-		cid, err := strconv.ParseUint(c, 10, 16)
-		if err != nil {
-			return tls.ClientHelloSpec{}, err
+	for _, supportedDelegatedCredentialsAlgorithm := range o.DelegatedCredentialsAlgorithms {
+		delegatedCredentialsAlgorithm, ok := delegatedCredentialsAlgorithms[supportedDelegatedCredentialsAlgorithm]
+		if ok {
+			extensions.delegatedCredentialsAlgorithms = append(extensions.delegatedCredentialsAlgorithms, delegatedCredentialsAlgorithm)
+		} else {
+			supportedDelegatedCredentialsAlgorithmAsUint, err := strconv.ParseUint(supportedDelegatedCredentialsAlgorithm, 16, 16)
+			if err != nil {
+				return ja3Extensions{}, nil, fmt.Errorf("%s is not a valid supportedDelegatedCredentialsAlgorithm", supportedDelegatedCredentialsAlgorithm)
+			}
+
+			extensions.delegatedCredentialsAlgorithms = append(extensions.delegatedCredentialsAlgorithms, tls.SignatureScheme(uint16(supportedDelegatedCredentialsAlgorithmAsUint)))
 		}
-		targetCurves = append(targetCurves, tls.CurveID(cid))
 	}
 
-	extMap[tls.ExtensionSupportedCurves] = &tls.SupportedCurvesExtension{Curves: targetCurves}
+	for _, echCandidateCipherSuites := range o.ECHCandidateCipherSuites {
+		kdfId, ok1 := kdfIds[echCandidateCipherSuites.KdfId]
+
+		aeadId, ok2 := aeadIds[echCandidateCipherSuites.AeadId]
+		if ok1 && ok2 {
+			extensions.hpkeSymmetricCipherSuites = append(extensions.hpkeSymmetricCipherSuites, tls.HPKESymmetricCipherSuite{
+				KdfId:  kdfId,
+				AeadId: aeadId,
+			})
+		} else {
+			kdfId, err := strconv.ParseUint(echCandidateCipherSuites.KdfId, 16, 16)
+			if err != nil {
+				return ja3Extensions{}, nil, fmt.Errorf("%s is not a valid KdfId", echCandidateCipherSuites.KdfId)
+			}
 
-	// parse point formats
-	var targetPointFormats []byte
-	for _, p := range pointFormats {
-		pid, err := strconv.ParseUint(p, 10, 8)
-		if err != nil {
-			return tls.ClientHelloSpec{}, err
+			aeadId, err := strconv.ParseUint(echCandidateCipherSuites.AeadId, 16, 16)
+			if err != nil {
+				return ja3Extensions{}, nil, fmt.Errorf("%s is not a valid aeadId", echCandidateCipherSuites.AeadId)
+			}
+
+			extensions.hpkeSymmetricCipherSuites = append(extensions.hpkeSymmetricCipherSuites, tls.HPKESymmetricCipherSuite{
+				KdfId:  uint16(kdfId),
+				AeadId: uint16(aeadId),
+			})
 		}
-		targetPointFormats = append(targetPointFormats, byte(pid))
 	}
 
-	if len(certCompressionAlgorithms) == 0 && strings.Contains(ja3StringParts[2], fmt.Sprintf("%d", tls.ExtensionCompressCertificate)) {
-		fmt.Println("attention our ja3 defines ExtensionCompressCertificate but you did not specify certCompression")
+	for _, version := range o.SupportedVersions {
+		mappedVersion, ok := tlsVersions[version]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("unknown supported version %s is ignored", version))
+			continue
+		}
+
+		extensions.tlsVersions = append(extensions.tlsVersions, mappedVersion)
 	}
 
-	if len(certCompressionAlgorithms) != 0 {
-		extMap[tls.ExtensionCompressCertificate] = &tls.UtlsCompressCertExtension{Algorithms: certCompressionAlgorithms}
+	for _, keyShareCurve := range o.KeyShareCurves {
+		resolvedKeyShare, ok := curves[keyShareCurve]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("unknown key share curve %s is ignored", keyShareCurve))
+			continue
+		}
+
+		mappedKeyShare := tls.KeyShare{Group: resolvedKeyShare}
+
+		if keyShareCurve == "GREASE" {
+			mappedKeyShare.Data = []byte{0}
+		}
+
+		extensions.keyShares = append(extensions.keyShares, mappedKeyShare)
+	}
+
+	for _, certCompressionAlgorithm := range o.CertCompressionAlgorithms {
+		compressionAlgo, ok := certCompression[certCompressionAlgorithm]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("unknown certificate compression algorithm %s is ignored", certCompressionAlgorithm))
+			continue
+		}
+
+		extensions.certCompressionAlgorithms = append(extensions.certCompressionAlgorithms, compressionAlgo)
+	}
+
+	return extensions, warnings, nil
+}
+
+// stringToSpec builds the ClientHelloSpec of ja3 with extensions. Each call copies the values the extensions may
+// modify, like the GREASE placeholders, so that every spec can be applied.
+func stringToSpec(ja3 ja3Spec, extensions ja3Extensions) (tls.ClientHelloSpec, error) {
+	extMap := getExtensionBaseMap()
+
+	extMap[tls.ExtensionSupportedCurves] = &tls.SupportedCurvesExtension{Curves: append([]tls.CurveID{}, ja3.curves...)}
+
+	if len(extensions.certCompressionAlgorithms) != 0 {
+		extMap[tls.ExtensionCompressCertificate] = &tls.UtlsCompressCertExtension{Algorithms: extensions.certCompressionAlgorithms}
 	}
 
-	extMap[tls.ExtensionKeyShare] = &tls.KeyShareExtension{KeyShares: keyShares}
-	extMap[tls.ExtensionSupportedPoints] = &tls.SupportedPointsExtension{SupportedPoints: targetPointFormats}
+	extMap[tls.ExtensionKeyShare] = &tls.KeyShareExtension{KeyShares: append([]tls.KeyShare{}, extensions.keyShares...)}
+	extMap[tls.ExtensionSupportedPoints] = &tls.SupportedPointsExtension{SupportedPoints: append([]byte{}, ja3.pointFormats...)}
 	extMap[tls.ExtensionECH] = &tls.GREASEEncryptedClientHelloExtension{
-		CandidateCipherSuites: hpkeSymmetricCipherSuites,
-		CandidatePayloadLens:  candidatePayloads,
+		CandidateCipherSuites: extensions.hpkeSymmetricCipherSuites,
+		CandidatePayloadLens:  extensions.options.ECHCandidatePayloads,
 	}
-	extMap[tls.ExtensionSupportedVersions] = &tls.SupportedVersionsExtension{Versions: tlsVersions}
+	extMap[tls.ExtensionSupportedVersions] = &tls.SupportedVersionsExtension{Versions: append([]uint16{}, extensions.tlsVersions...)}
 	extMap[tls.ExtensionSignatureAlgorithms] = &tls.SignatureAlgorithmsExtension{
-		SupportedSignatureAlgorithms: signatureAlgorithms,
+		SupportedSignatureAlgorithms: extensions.signatureAlgorithms,
 	}
 
 	extMap[tls.ExtensionDelegatedCredentials] = &tls.DelegatedCredentialsExtension{
-		SupportedSignatureAlgorithms: delegatedCredentialsAlgorithms,
+		SupportedSignatureAlgorithms: extensions.delegatedCredentialsAlgorithms,
 	}
 
 	extMap[tls.ExtensionALPN] = &tls.ALPNExtension{
-		AlpnProtocols: supportedProtocolsALPN,
+		AlpnProtocols: extensions.options.ALPNProtocols,
 	}
 
 	extMap[tls.ExtensionALPSOld] = &tls.ApplicationSettingsExtension{
-		SupportedProtocols: supportedProtocolsALPS,
+		SupportedProtocols: extensions.options.ALPSProtocols,
 	}
 
 	extMap[tls.ExtensionALPS] = &tls.ApplicationSettingsExtensionNew{
-		SupportedProtocols: supportedProtocolsALPS,
+		SupportedProtocols: extensions.options.ALPSProtocols,
 	}
 
 	extMap[tls.ExtensionRecordSizeLimit] = &tls.FakeRecordSizeLimitExtension{
-		Limit: recordSizeLimit,
+		Limit: extensions.options.RecordSizeLimit,
 	}
 
-	var exts []tls.TLSExtension
-	for _, e := range extensions {
-		eId, err := strconv.ParseUint(e, 10, 16)
-		if err != nil {
-			return tls.ClientHelloSpec{}, err
-		}
+	for id, payload := range extensions.options.ExtensionPayloads {
+		extMap[id] = &tls.GenericExtension{Id: id, Data: append([]byte{}, payload...)}
+	}
 
-		if uint16(eId) == tls.GREASE_PLACEHOLDER {
+	var exts []tls.TLSExtension
+	for _, eId := range ja3.extensions {
+		if eId == tls.GREASE_PLACEHOLDER {
 			// if we use multiple grease extensions with need to generate always a new value. therefore we are creating a new instance here
 			exts = append(exts, &tls.UtlsGREASEExtension{})
 			continue
 		}
 
-		te, ok := extMap[uint16(eId)]
+		te, ok := extMap[eId]
 		if !ok {
-			return tls.ClientHelloSpec{}, fmt.Errorf("unknown extension with id %s provided", e)
+			return tls.ClientHelloSpec{}, &UnknownExtensionError{Id: eId}
 		}
 		exts = append(exts, te)
 	}
 
-	var suites []uint16
-	for _, c := range ciphers {
-		cid, err := strconv.ParseUint(c, 10, 16)
-		if err != nil {
-			return tls.ClientHelloSpec{}, err
-		}
-		suites = append(suites, uint16(cid))
-	}
-
 	return tls.ClientHelloSpec{
-		CipherSuites:       suites,
+		CipherSuites:       append([]uint16{}, ja3.cipherSuites...),
 		CompressionMethods: []byte{tls.CompressionNone},
 		Extensions:         exts,
 		GetSessionID:       sha256.Sum256,
@@ -1,15 +1,21 @@
 package httpkit
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/url"
+	"sort"
 	"strings"
 	"sync"
-
-	"maps"
+	"time"
 
 	http "github.com/bogdanfinn/fhttp"
 	"github.com/bogdanfinn/fhttp/cookiejar"
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
 )
 
 const CookieExpired = -1
@@ -18,10 +24,14 @@ const CookieExpired = -1
 type CookieJarOption func(config *cookieJarConfig)
 
 type cookieJarConfig struct {
-	logger       Logger
-	skipExisting bool
-	debug        bool
-	allowEmpty   bool
+	logger           Logger
+	publicSuffixList cookiejar.PublicSuffixList
+	janitorCtx       context.Context
+	autosavePath     string
+	janitorInterval  time.Duration
+	skipExisting     bool
+	debug            bool
+	allowEmpty       bool
 }
 
 // WithSkipExisting returns a CookieJarOption that skips existing cookies in the jar (default: false).
@@ -54,30 +64,99 @@ func WithLogger(logger Logger) CookieJarOption {
 	}
 }
 
+// WithPublicSuffixList returns a CookieJarOption that sets the public suffix list used to reject cookies set for a
+// public suffix such as "co.uk" (default: publicsuffix.List). A nil list only treats top level domains as public suffixes.
+func WithPublicSuffixList(list cookiejar.PublicSuffixList) CookieJarOption {
+	return func(config *cookieJarConfig) {
+		config.publicSuffixList = list
+	}
+}
+
 // CookieJar is the interface that wraps the basic CookieJar methods, including additional helpers.
 type CookieJar interface {
 	http.CookieJar
 	CookiesMap() map[string][]*http.Cookie
 	Cookie(u *url.URL, name string) *http.Cookie
+	Export(w io.Writer, format CookieFormat) error
+	Import(r io.Reader, format CookieFormat) error
+	Partition(key string) CookieJar
+	CookiesFor(u *url.URL, fetch FetchContext) []*http.Cookie
+	SetCookiesFor(u *url.URL, cookies []*http.Cookie, fetch FetchContext)
+	Delete(u *url.URL, name string) bool
+	ClearDomain(domain string) int
+	Clear()
+	OnChange(onChange func(change CookieChange)) (unsubscribe func())
+	Flush() error
 }
 
+// cookieJar stores cookies according to RFC 6265. Cookies are keyed by the registrable domain (eTLD+1) of the host which
+// set them and are only returned for requests matching their domain, path and secure attributes.
+//
+// Partitioned cookies (CHIPS) are additionally keyed by the top-level site they were set from and are only returned for
+// requests made from that top-level site. The SameSite attribute is enforced for cross-site requests, see FetchContext.
 type cookieJar struct {
-	jar     *cookiejar.Jar
-	config  *cookieJarConfig
-	cookies map[string][]*http.Cookie
+	*cookieStore
+	// partition isolates the cookies of the jar from the ones of the other partitions of the store.
+	partition string
+}
+
+// cookieStore holds the cookies of a jar and of all of its partitions.
+type cookieStore struct {
+	config *cookieJarConfig
+	// entries are keyed by partition and eTLD+1 (see cookieStoreKey) and then by the id of the cookie.
+	entries    map[string]map[string]cookieEntry
+	nextSeqNum uint64
+	observers  map[uint64]cookieObserver
+	// pendingChanges are the changes made while holding the lock, which are reported to the observers once it is released.
+	pendingChanges []pendingCookieChange
+	nextObserverId uint64
+	// autosaveTimer is the pending write of the autosave file, nil when there is none.
+	autosaveTimer *time.Timer
+	autosaveLck   sync.Mutex
+	saveLck       sync.Mutex
 	sync.RWMutex
 }
 
+// cookieEntry is the stored representation of a cookie, holding the fields of the cookie storage model of RFC 6265, section 5.3.
+type cookieEntry struct {
+	// Partition is the partition of the jar holding the entry, empty for the default one.
+	Partition string
+	// TopLevelSite is the partition key of a partitioned cookie, empty for unpartitioned cookies.
+	TopLevelSite string
+	Name         string
+	Value        string
+	Domain       string
+	Path         string
+	SameSite     http.SameSite
+	Secure       bool
+	HttpOnly     bool
+	Persistent   bool
+	HostOnly     bool
+	Expires      time.Time
+	Creation     time.Time
+	LastAccess   time.Time
+
+	// seqNum keeps cookies with equal path length and creation time in a deterministic order.
+	seqNum uint64
+}
+
+// endOfTime is the expiry of session cookies.
+var endOfTime = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+
+var (
+	errIllegalCookieDomain   = errors.New("illegal cookie domain attribute")
+	errMalformedCookieDomain = errors.New("malformed cookie domain attribute")
+	errNoCookieHostname      = errors.New("no host name available (IP only)")
+	errInsecurePartitioned   = errors.New("partitioned cookie without secure attribute")
+	errCrossSiteSameSite     = errors.New("samesite cookie in the response to a cross-site subresource request")
+)
+
 // NewCookieJar creates a new empty cookie jar with the given options.
-// Returns nil if the underlying cookiejar.Jar cannot be created.
 func NewCookieJar(options ...CookieJarOption) CookieJar {
-	underlyingJar, err := cookiejar.New(nil)
-	if err != nil {
-		return nil
+	config := &cookieJarConfig{
+		publicSuffixList: publicsuffix.List,
 	}
 
-	config := &cookieJarConfig{}
-
 	for _, opt := range options {
 		opt(config)
 	}
@@ -90,165 +169,515 @@ func NewCookieJar(options ...CookieJarOption) CookieJar {
 		config.logger = NewDebugLogger(config.logger)
 	}
 
-	return &cookieJar{
-		jar:     underlyingJar,
-		config:  config,
-		cookies: make(map[string][]*http.Cookie),
+	jar := &cookieJar{
+		cookieStore: &cookieStore{
+			config:  config,
+			entries: make(map[string]map[string]cookieEntry),
+		},
+	}
+
+	if config.autosavePath != "" {
+		jar.load()
+	}
+
+	if config.janitorCtx != nil && config.janitorInterval > 0 {
+		go jar.runJanitor(config.janitorCtx, config.janitorInterval)
 	}
+
+	return jar
 }
 
 // SetCookies sets the cookies for the given URL according to the rules defined in the config.
+// Cookies with a negative MaxAge or an Expires in the past remove the stored cookie.
+// The response is considered same-site, see SetCookiesFor for cross-site responses.
 func (jar *cookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
-	jar.Lock()
-	defer jar.Unlock()
+	jar.SetCookiesFor(u, cookies, FetchContext{})
+}
 
-	cookies = jar.nonEmpty(cookies)
-	cookies = jar.unique(cookies)
+// setCookies stores the cookies received from u in the response to request and reports whether the jar was modified.
+func (jar *cookieJar) setCookies(u *url.URL, cookies []*http.Cookie, now time.Time, request cookieRequest) bool {
+	if len(cookies) == 0 || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
 
-	hostKey := jar.buildCookieHostKey(u)
-	existing := jar.cookies[hostKey]
+	host, err := canonicalCookieHost(u.Host)
+	if err != nil {
+		jar.config.logger.Debug("[SetCookies] Invalid host '%s': %s", u.Host, err.Error())
+		return false
+	}
+
+	key := cookieStoreKey(jar.partition, cookieJarKey(host, jar.config.publicSuffixList))
+	defPath := defaultCookiePath(u.Path)
+
+	submap := jar.entries[key]
+	modified := false
+
+	for _, cookie := range cookies {
+		if cookie.Value == "" && !jar.config.allowEmpty {
+			jar.config.logger.Debug("[SetCookies] Cookie '%s' is empty and will be filtered out.", cookie.Name)
+			continue
+		}
+
+		e, remove, err := jar.newEntry(cookie, now, defPath, host, request)
+		if err != nil {
+			jar.config.logger.Debug("[SetCookies] Cookie '%s' rejected: %s", cookie.Name, err.Error())
+			continue
+		}
+
+		id := e.id()
+		old, exists := submap[id]
+		exists = exists && old.Expires.After(now)
+
+		if exists && jar.config.skipExisting {
+			jar.config.logger.Debug("[SetCookies] Cookie '%s' already exists in jar. Skipping.", cookie.Name)
+			continue
+		}
 
-	if jar.config.skipExisting {
-		var newCookies []*http.Cookie
-		for _, cookie := range cookies {
-			if findCookieByName(existing, cookie.Name) != nil {
-				jar.config.logger.Debug("[SetCookies] Cookie '%s' already exists in jar. Skipping.", cookie.Name)
-				continue
+		if remove {
+			if exists {
+				jar.config.logger.Debug("[SetCookies] Cookie '%s' expired. Removing it from jar.", cookie.Name)
+				jar.recordChange(CookieRemoved, old, u)
+				modified = true
 			}
-			jar.config.logger.Debug("[SetCookies] Adding new cookie '%s' to jar.", cookie.Name)
-			newCookies = append(newCookies, cookie)
+
+			delete(submap, id)
+			continue
 		}
-		cookies = append(existing, newCookies...)
-	} else {
-		var existingCookies []*http.Cookie
-		for _, cookie := range existing {
-			if findCookieByName(cookies, cookie.Name) != nil {
-				jar.config.logger.Debug("[SetCookies] Cookie '%s' already exists in jar. Skipping.", cookie.Name)
-				continue
+
+		if submap == nil {
+			submap = make(map[string]cookieEntry)
+		}
+
+		if exists {
+			e.Creation = old.Creation
+			e.seqNum = old.seqNum
+
+			if !e.sameCookie(&old) {
+				jar.recordChange(CookieUpdated, e, u)
 			}
-			jar.config.logger.Debug("[SetCookies] Adding existing cookie '%s' to jar.", cookie.Name)
-			existingCookies = append(existingCookies, cookie)
+		} else {
+			jar.config.logger.Debug("[SetCookies] Adding new cookie '%s' to jar.", cookie.Name)
+
+			e.Creation = now
+			e.seqNum = jar.nextSeqNum
+			jar.nextSeqNum++
+
+			jar.recordChange(CookieAdded, e, u)
 		}
-		cookies = append(existingCookies, cookies...)
+
+		e.LastAccess = now
+		submap[id] = e
+		modified = true
 	}
 
-	jar.jar.SetCookies(u, cookies)
-	jar.cookies[hostKey] = cookies
+	if len(submap) == 0 {
+		delete(jar.entries, key)
+	} else {
+		jar.entries[key] = submap
+	}
+
+	return modified
 }
 
-// Cookies returns the cookies for the given url, filtering out expired cookies.
+// Cookies returns the cookies to send in a request for the given url, filtering out expired cookies.
+// Cookies are sorted by longest path first and then by earliest creation time (RFC 6265, section 5.4).
+// The request is considered same-site, see CookiesFor for cross-site requests.
 func (jar *cookieJar) Cookies(u *url.URL) []*http.Cookie {
-	jar.RLock()
-	defer jar.RUnlock()
+	return jar.CookiesFor(u, FetchContext{})
+}
 
-	hostKey := jar.buildCookieHostKey(u)
-	cookies := jar.cookies[hostKey]
+// cookies returns the entries to send in request to u.
+func (jar *cookieJar) cookies(u *url.URL, now time.Time, request cookieRequest) []cookieEntry {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
 
-	return jar.notExpired(cookies)
+	host, err := canonicalCookieHost(u.Host)
+	if err != nil {
+		return nil
+	}
+
+	key := cookieStoreKey(jar.partition, cookieJarKey(host, jar.config.publicSuffixList))
+
+	submap := jar.entries[key]
+	if submap == nil {
+		return nil
+	}
+
+	https := u.Scheme == "https"
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	var selected []cookieEntry
+
+	for id, e := range submap {
+		if !e.Expires.After(now) {
+			jar.config.logger.Debug("[Cookies] Cookie '%s' in jar expired. Will be excluded from request.", e.Name)
+			delete(submap, id)
+			jar.recordChange(CookieRemoved, e, nil)
+			continue
+		}
+
+		if !e.shouldSend(https, host, path) || !e.sameSiteAllows(request, now) || (e.TopLevelSite != "" && e.TopLevelSite != request.topLevelSite) {
+			continue
+		}
+
+		e.LastAccess = now
+		submap[id] = e
+		selected = append(selected, e)
+	}
+
+	if len(submap) == 0 {
+		delete(jar.entries, key)
+	}
+
+	sort.Slice(selected, func(i, j int) bool {
+		s := selected
+		if len(s[i].Path) != len(s[j].Path) {
+			return len(s[i].Path) > len(s[j].Path)
+		}
+
+		if !s[i].Creation.Equal(s[j].Creation) {
+			return s[i].Creation.Before(s[j].Creation)
+		}
+
+		return s[i].seqNum < s[j].seqNum
+	})
+
+	return selected
 }
 
-// CookiesMap returns all cookies in the jar, grouped by host key.
+// CookiesMap returns all cookies in the jar, grouped by registrable domain (eTLD+1) and sorted by insertion order.
+// The cookies of the other partitions are not included.
 func (jar *cookieJar) CookiesMap() map[string][]*http.Cookie {
 	jar.RLock()
 	defer jar.RUnlock()
 
-	copied := make(map[string][]*http.Cookie)
-	maps.Copy(copied, jar.cookies)
+	grouped := make(map[string][]cookieEntry)
+
+	for _, e := range jar.allEntries(time.Now()) {
+		if e.Partition == jar.partition {
+			key := cookieJarKey(e.Domain, jar.config.publicSuffixList)
+			grouped[key] = append(grouped[key], e)
+		}
+	}
+
+	copied := make(map[string][]*http.Cookie, len(grouped))
+	for key, entries := range grouped {
+		copied[key] = toCookies(entries)
+	}
 
 	return copied
 }
 
-// Cookie returns the cookie with the given name for the given url or nil if not found.
+// Partition returns a view of the jar holding an isolated set of cookies, e.g. for one of the many identities sharing a jar.
+// Partitions share the options and the autosave file of the jar. Partitioning a partition creates a nested partition:
+// Delete, ClearDomain and Clear only remove the cookies of their own partition, while Export and OnChange include the
+// nested partitions, named by their keys escaped like URL path segments and joined with "/", e.g. "alice/tab". The key
+// may contain any character but must not be empty, Partition panics if it is.
+func (jar *cookieJar) Partition(key string) CookieJar {
+	if key == "" {
+		panic("httpkit: empty cookie jar partition key")
+	}
+
+	return &cookieJar{
+		cookieStore: jar.cookieStore,
+		partition:   joinCookiePartition(jar.partition, url.PathEscape(key)),
+	}
+}
+
+// site returns the site (scheme and registrable domain) of u, as compared by the SameSite rules and used as partition key.
+func (jar *cookieJar) site(u *url.URL) string {
+	host, err := canonicalCookieHost(u.Host)
+	if err != nil {
+		return ""
+	}
+
+	return u.Scheme + "://" + cookieJarKey(host, jar.config.publicSuffixList)
+}
+
+// Cookie returns the cookie with the given name which would be sent to the given url or nil if not found.
 func (jar *cookieJar) Cookie(u *url.URL, name string) *http.Cookie {
-	jar.RLock()
-	defer jar.RUnlock()
+	jar.Lock()
+	entries := jar.cookies(u, time.Now(), jar.requestFor(u, FetchContext{}))
+	changes := jar.takeChanges()
+	jar.Unlock()
 
-	hostKey := jar.buildCookieHostKey(u)
-	cookies := jar.cookies[hostKey]
+	jar.notify(changes)
 
-	for _, cookie := range cookies {
-		if cookie.Name == name && cookie.MaxAge > CookieExpired {
-			return cookie
+	for _, e := range entries {
+		if e.Name == name {
+			return e.cookie()
 		}
 	}
 
 	return nil
 }
 
-// buildCookieHostKey builds a host key for the cookie jar based on the URL.
-// It uses the last two parts of the host (e.g. "example.com" or "sub.example.com") as the key.
-func (jar *cookieJar) buildCookieHostKey(u *url.URL) string {
-	hostParts := strings.Split(u.Host, ".")
+// newEntry creates an entry from a cookie received from host. remove reports whether the cookie asks to be deleted,
+// in which case only the id of the entry is valid.
+func (jar *cookieJar) newEntry(c *http.Cookie, now time.Time, defPath, host string, request cookieRequest) (e cookieEntry, remove bool, err error) {
+	if request.crossSite && !request.navigation && c.SameSite != http.SameSiteNoneMode {
+		return e, false, errCrossSiteSameSite
+	}
+
+	e.Name = c.Name
+	e.Partition = jar.partition
+
+	if isPartitioned(c) {
+		// like Chrome, partitioned cookies must be secure
+		if !c.Secure {
+			return e, false, errInsecurePartitioned
+		}
 
-	if len(hostParts) >= 2 {
-		return fmt.Sprintf("%s.%s", hostParts[len(hostParts)-2], hostParts[len(hostParts)-1])
+		e.TopLevelSite = request.topLevelSite
 	}
 
-	return u.Host
+	if c.Path == "" || c.Path[0] != '/' {
+		e.Path = defPath
+	} else {
+		e.Path = c.Path
+	}
+
+	e.Domain, e.HostOnly, err = jar.domainAndType(host, c.Domain)
+	if err != nil {
+		return e, false, err
+	}
+
+	// MaxAge takes precedence over Expires and is converted to an absolute expiry.
+	if c.MaxAge < 0 {
+		return e, true, nil
+	} else if c.MaxAge > 0 {
+		e.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		e.Persistent = true
+	} else if expires, ok := cookieExpiry(c.Expires, c.RawExpires); ok {
+		if !expires.After(now) {
+			return e, true, nil
+		}
+
+		e.Expires = expires
+		e.Persistent = true
+	} else {
+		e.Expires = endOfTime
+	}
+
+	e.Value = c.Value
+	e.Secure = c.Secure
+	e.HttpOnly = c.HttpOnly
+	e.SameSite = c.SameSite
+
+	return e, false, nil
 }
 
-// unique filters out duplicate cookies by name and keeps the last one.
-func (jar *cookieJar) unique(cookies []*http.Cookie) []*http.Cookie {
-	seen := make(map[string]bool, len(cookies))
-	filteredCookies := make([]*http.Cookie, 0, len(cookies))
+// domainAndType determines the domain of a cookie received from host and whether it is a host-only cookie (RFC 6265, section 5.3).
+func (jar *cookieJar) domainAndType(host, domain string) (string, bool, error) {
+	if domain == "" {
+		return host, true, nil
+	}
 
-	for i := len(cookies) - 1; i >= 0; i-- {
-		c := cookies[i]
-		if seen[c.Name] {
-			continue
+	if net.ParseIP(host) != nil {
+		return "", false, errNoCookieHostname
+	}
+
+	domain = strings.TrimPrefix(domain, ".")
+	if len(domain) == 0 || domain[0] == '.' || domain[len(domain)-1] == '.' {
+		return "", false, errMalformedCookieDomain
+	}
+
+	domain = strings.ToLower(domain)
+
+	// cookies can not be set for a public suffix, except by the public suffix itself, as a host cookie
+	if jar.config.publicSuffixList != nil {
+		if ps := jar.config.publicSuffixList.PublicSuffix(domain); ps != "" && !hasDotSuffix(domain, ps) {
+			if host == domain {
+				return host, true, nil
+			}
+
+			return "", false, errIllegalCookieDomain
 		}
-		filteredCookies = append(filteredCookies, c)
-		seen[c.Name] = true
 	}
 
-	return filteredCookies
+	if host != domain && !hasDotSuffix(host, domain) {
+		return "", false, errIllegalCookieDomain
+	}
+
+	return domain, false, nil
+}
+
+// id returns the topLevelSite;domain;path;name tuple which identifies the entry in its partition.
+func (e *cookieEntry) id() string {
+	return fmt.Sprintf("%s;%s;%s;%s", e.TopLevelSite, e.Domain, e.Path, e.Name)
+}
+
+// shouldSend reports whether the entry is sent in a request to host/path. The caller checks the expiry.
+func (e *cookieEntry) shouldSend(https bool, host, path string) bool {
+	return e.domainMatch(host) && e.pathMatch(path) && (https || !e.Secure)
+}
+
+// domainMatch implements "domain-match" of RFC 6265, section 5.1.3.
+func (e *cookieEntry) domainMatch(host string) bool {
+	if e.Domain == host {
+		return true
+	}
+
+	return !e.HostOnly && hasDotSuffix(host, e.Domain)
 }
 
-// nonEmpty filters out empty cookies if allowEmpty is false.
-func (jar *cookieJar) nonEmpty(cookies []*http.Cookie) []*http.Cookie {
-	if jar.config.allowEmpty {
-		return cookies
+// pathMatch implements "path-match" of RFC 6265, section 5.1.4.
+func (e *cookieEntry) pathMatch(requestPath string) bool {
+	if requestPath == e.Path {
+		return true
 	}
 
-	filteredCookies := cookies[:0]
-	for _, cookie := range cookies {
-		if cookie.Value == "" {
-			jar.config.logger.Debug("[nonEmpty] Cookie '%s' is empty and will be filtered out.", cookie.Name)
-			continue
+	if strings.HasPrefix(requestPath, e.Path) {
+		return e.Path[len(e.Path)-1] == '/' || requestPath[len(e.Path)] == '/'
+	}
+
+	return false
+}
+
+func (e *cookieEntry) cookie() *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     e.Name,
+		Value:    e.Value,
+		Path:     e.Path,
+		Secure:   e.Secure,
+		HttpOnly: e.HttpOnly,
+		SameSite: e.SameSite,
+	}
+
+	if !e.HostOnly {
+		cookie.Domain = e.Domain
+	}
+
+	if e.Persistent {
+		cookie.Expires = e.Expires
+	}
+
+	if e.TopLevelSite != "" {
+		cookie.Unparsed = []string{partitionedAttribute}
+	}
+
+	return cookie
+}
+
+// partitionedAttribute is the attribute of partitioned cookies (CHIPS), which the cookie parser keeps in http.Cookie.Unparsed.
+const partitionedAttribute = "Partitioned"
+
+func isPartitioned(c *http.Cookie) bool {
+	for _, attribute := range c.Unparsed {
+		if strings.EqualFold(strings.TrimSpace(attribute), partitionedAttribute) {
+			return true
 		}
-		filteredCookies = append(filteredCookies, cookie)
 	}
 
-	return filteredCookies
+	return false
 }
 
-// notExpired filters out expired cookies.
-func (jar *cookieJar) notExpired(cookies []*http.Cookie) []*http.Cookie {
-	filteredCookies := cookies[:0]
-	for _, cookie := range cookies {
-		if cookie.MaxAge <= CookieExpired {
-			jar.config.logger.Debug("[notExpired] Cookie '%s' in jar has max age <= 0. Will be excluded from request.", cookie.Name)
-			continue
+// cookieStoreKey returns the key of the entries of the given partition and registrable domain in the store.
+func cookieStoreKey(partition, key string) string {
+	if partition == "" {
+		return key
+	}
+
+	return partition + "|" + key
+}
+
+// joinCookiePartition returns the partition nested in parent at the relative partition path, made of the escaped keys
+// of the nested partitions joined with "/".
+func joinCookiePartition(parent, path string) string {
+	if parent == "" || path == "" {
+		return parent + path
+	}
+
+	return parent + "/" + path
+}
+
+func toCookies(entries []cookieEntry) []*http.Cookie {
+	cookies := make([]*http.Cookie, 0, len(entries))
+	for i := range entries {
+		cookies = append(cookies, entries[i].cookie())
+	}
+
+	return cookies
+}
+
+// canonicalCookieHost strips the port and the trailing dot of host and converts it to its lower case ASCII form.
+func canonicalCookieHost(host string) (string, error) {
+	host = strings.ToLower(host)
+
+	if hasPort(host) {
+		var err error
+		if host, _, err = net.SplitHostPort(host); err != nil {
+			return "", err
 		}
+	}
+
+	host = strings.TrimSuffix(host, ".")
 
-		// TODO: The cookie parser does not parse the Expires field correctly from the Set-Cookie header.
-		// Once fixed, consider also filtering cookies by expiration date here.
-		// if cookie.Expires.Before(now) {
-		// 	jar.config.logger.Debug("[notExpired] Cookie '%s' in jar expired. Will be excluded from request.", cookie.Name)
-		// 	continue
-		// }
+	return idna.ToASCII(host)
+}
+
+// hasPort reports whether host, a host name or an IPv4 or IPv6 address, contains a port.
+func hasPort(host string) bool {
+	colons := strings.Count(host, ":")
+	if colons == 0 {
+		return false
+	}
 
-		filteredCookies = append(filteredCookies, cookie)
+	if colons == 1 {
+		return true
 	}
-	return filteredCookies
+
+	return host[0] == '[' && strings.Contains(host, "]:")
 }
 
-// findCookieByName returns the cookie with the given name if it exists in the slice.
-func findCookieByName(cookies []*http.Cookie, name string) *http.Cookie {
-	for _, c := range cookies {
-		if c.Name == name {
-			return c
+// cookieJarKey returns the registrable domain (eTLD+1) of host, which cookies are stored under.
+func cookieJarKey(host string, publicSuffixList cookiejar.PublicSuffixList) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+
+	var i int
+	if publicSuffixList == nil {
+		i = strings.LastIndex(host, ".")
+		if i <= 0 {
+			return host
+		}
+	} else {
+		suffix := publicSuffixList.PublicSuffix(host)
+		if suffix == host {
+			return host
+		}
+
+		i = len(host) - len(suffix)
+		if i <= 0 || host[i-1] != '.' {
+			return host
 		}
 	}
-	return nil
+
+	prevDot := strings.LastIndex(host[:i-1], ".")
+
+	return host[prevDot+1:]
+}
+
+// defaultCookiePath returns the directory part of the path of a URL (RFC 6265, section 5.1.4).
+func defaultCookiePath(path string) string {
+	if len(path) == 0 || path[0] != '/' {
+		return "/"
+	}
+
+	i := strings.LastIndex(path, "/")
+	if i == 0 {
+		return "/"
+	}
+
+	return path[:i]
+}
+
+// hasDotSuffix reports whether s ends in "."+suffix.
+func hasDotSuffix(s, suffix string) bool {
+	return len(s) > len(suffix) && s[len(s)-len(suffix)-1] == '.' && s[len(s)-len(suffix):] == suffix
 }
@@ -0,0 +1,221 @@
+package httpkit
+
+import (
+	"net/url"
+	"strings"
+
+	http "github.com/bogdanfinn/fhttp"
+)
+
+// CookieChangeKind is the kind of a CookieChange.
+type CookieChangeKind int
+
+const (
+	// CookieAdded is reported when a cookie which was not in the jar is stored.
+	CookieAdded CookieChangeKind = iota
+	// CookieUpdated is reported when a stored cookie is replaced by a cookie with a different value or attributes.
+	CookieUpdated
+	// CookieRemoved is reported when a cookie is removed from the jar, because it was expired by a server or deleted,
+	// or because it expired.
+	CookieRemoved
+)
+
+func (k CookieChangeKind) String() string {
+	switch k {
+	case CookieAdded:
+		return "added"
+	case CookieUpdated:
+		return "updated"
+	case CookieRemoved:
+		return "removed"
+	default:
+		return "unknown"
+	}
+}
+
+// CookieChange describes a change of the cookies of a jar, reported to the observers registered with OnChange.
+type CookieChange struct {
+	// URL is the URL of the response which set or removed the cookie. It is nil for changes which did not originate
+	// from a response: Delete, ClearDomain, Clear, Import and expiry.
+	URL *url.URL
+	// Cookie is the cookie as stored. For removed cookies, it is the cookie as it was stored before the removal.
+	Cookie *http.Cookie
+	// Partition is the partition of the cookie, relative to the partition of the observed jar.
+	Partition string
+	Kind      CookieChangeKind
+}
+
+// cookieObserver is an observer registered with OnChange on the jar of the given partition.
+type cookieObserver struct {
+	onChange  func(change CookieChange)
+	partition string
+}
+
+// OnChange registers onChange to be called for every change of the cookies of the jar and of its nested partitions.
+// onChange is called after the change is applied, outside of the lock of the jar, so it may use the jar. It may be
+// called concurrently when the jar is used concurrently. The returned function unregisters the observer.
+func (jar *cookieJar) OnChange(onChange func(change CookieChange)) (unsubscribe func()) {
+	jar.Lock()
+	defer jar.Unlock()
+
+	if jar.observers == nil {
+		jar.observers = make(map[uint64]cookieObserver)
+	}
+
+	id := jar.nextObserverId
+	jar.nextObserverId++
+	jar.observers[id] = cookieObserver{onChange: onChange, partition: jar.partition}
+
+	return func() {
+		jar.Lock()
+		defer jar.Unlock()
+
+		delete(jar.observers, id)
+	}
+}
+
+// Delete removes the cookies with the given name which domain and path match u, whatever their secure and SameSite
+// attributes and their top-level site are. It reports whether a cookie was removed.
+func (jar *cookieJar) Delete(u *url.URL, name string) bool {
+	host, err := canonicalCookieHost(u.Host)
+	if err != nil {
+		return false
+	}
+
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	return jar.remove(func(e *cookieEntry) bool {
+		return e.Partition == jar.partition && e.Name == name && e.domainMatch(host) && e.pathMatch(path)
+	}) > 0
+}
+
+// ClearDomain removes the cookies of the given domain and of its subdomains and returns how many were removed.
+func (jar *cookieJar) ClearDomain(domain string) int {
+	domain, err := canonicalCookieHost(strings.TrimPrefix(domain, "."))
+	if err != nil {
+		return 0
+	}
+
+	return jar.remove(func(e *cookieEntry) bool {
+		return e.Partition == jar.partition && (e.Domain == domain || hasDotSuffix(e.Domain, domain))
+	})
+}
+
+// Clear removes all cookies of the jar. The cookies of its nested partitions are kept.
+func (jar *cookieJar) Clear() {
+	jar.remove(func(e *cookieEntry) bool {
+		return e.Partition == jar.partition
+	})
+}
+
+// remove removes the entries matching match, notifies the observers and saves the jar. It returns how many entries were removed.
+func (jar *cookieJar) remove(match func(e *cookieEntry) bool) int {
+	jar.Lock()
+	removed := jar.removeEntries(match)
+	changes := jar.takeChanges()
+	jar.Unlock()
+
+	jar.notify(changes)
+
+	if removed > 0 {
+		jar.autosave()
+	}
+
+	return removed
+}
+
+// removeEntries removes the entries matching match from every partition of the store and returns how many were removed.
+func (jar *cookieJar) removeEntries(match func(e *cookieEntry) bool) int {
+	removed := 0
+
+	for key, submap := range jar.entries {
+		for id, e := range submap {
+			if match(&e) {
+				delete(submap, id)
+				jar.recordChange(CookieRemoved, e, nil)
+				removed++
+			}
+		}
+
+		if len(submap) == 0 {
+			delete(jar.entries, key)
+		}
+	}
+
+	return removed
+}
+
+// recordChange queues a change for the observers, which are notified with notify once the lock is released.
+func (jar *cookieJar) recordChange(kind CookieChangeKind, e cookieEntry, u *url.URL) {
+	if len(jar.observers) == 0 {
+		return
+	}
+
+	jar.pendingChanges = append(jar.pendingChanges, pendingCookieChange{entry: e, url: u, kind: kind})
+}
+
+// takeChanges returns and resets the queued changes. It must be called with the lock held.
+func (jar *cookieJar) takeChanges() []pendingCookieChange {
+	changes := jar.pendingChanges
+	jar.pendingChanges = nil
+
+	return changes
+}
+
+// notify reports changes to the observers of their partition.
+func (jar *cookieJar) notify(changes []pendingCookieChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	jar.RLock()
+	observers := make([]cookieObserver, 0, len(jar.observers))
+	for _, observer := range jar.observers {
+		observers = append(observers, observer)
+	}
+	jar.RUnlock()
+
+	for _, change := range changes {
+		for _, observer := range observers {
+			if !inCookiePartition(change.entry.Partition, observer.partition) {
+				continue
+			}
+
+			observer.onChange(CookieChange{
+				URL:       change.url,
+				Cookie:    change.entry.cookie(),
+				Partition: relativeCookiePartition(change.entry.Partition, observer.partition),
+				Kind:      change.kind,
+			})
+		}
+	}
+}
+
+type pendingCookieChange struct {
+	url   *url.URL
+	entry cookieEntry
+	kind  CookieChangeKind
+}
+
+// sameCookie reports whether the entries hold the same cookie, ignoring the access times.
+func (e *cookieEntry) sameCookie(other *cookieEntry) bool {
+	return e.Value == other.Value && e.SameSite == other.SameSite && e.Secure == other.Secure &&
+		e.HttpOnly == other.HttpOnly && e.Persistent == other.Persistent && e.Expires.Equal(other.Expires)
+}
+
+// inCookiePartition reports whether partition is parent or one of its nested partitions.
+func inCookiePartition(partition, parent string) bool {
+	return parent == "" || partition == parent || strings.HasPrefix(partition, parent+"/")
+}
+
+// relativeCookiePartition returns partition relative to its parent partition.
+func relativeCookiePartition(partition, parent string) string {
+	if parent == "" {
+		return partition
+	}
+
+	return strings.TrimPrefix(strings.TrimPrefix(partition, parent), "/")
+}
@@ -0,0 +1,152 @@
+package httpkit
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCookieJar_GivenObserver_WhenSetCookies_ThenChangesAreReported(t *testing.T) {
+	jar := NewCookieJar()
+
+	var changes []CookieChange
+	unsubscribe := jar.OnChange(func(change CookieChange) {
+		changes = append(changes, change)
+	})
+
+	jar.SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "first"}})
+	jar.SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "first"}})
+	jar.SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "second"}})
+	jar.SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "second", MaxAge: Expired}})
+
+	if assert.Len(t, changes, 3, "Expected setting an identical cookie not to be reported") {
+		assert.Equal(t, CookieAdded, changes[0].Kind)
+		assert.Equal(t, "first", changes[0].Cookie.Value)
+		assert.Equal(t, urlObject, changes[0].URL)
+		assert.Equal(t, CookieUpdated, changes[1].Kind)
+		assert.Equal(t, "second", changes[1].Cookie.Value)
+		assert.Equal(t, CookieRemoved, changes[2].Kind)
+		assert.Equal(t, "second", changes[2].Cookie.Value)
+	}
+
+	unsubscribe()
+	jar.SetCookies(urlObject, []*http.Cookie{{Name: "2", Value: "first"}})
+
+	assert.Len(t, changes, 3, "Expected no change to be reported after unsubscribe")
+}
+
+func TestCookieJar_GivenExpiredCookie_WhenCookie_ThenTheRemovalIsReported(t *testing.T) {
+	jar := NewCookieJar()
+	jar.SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "first", Expires: time.Now().Add(20 * time.Millisecond)}})
+
+	var changes []CookieChange
+	jar.OnChange(func(change CookieChange) {
+		changes = append(changes, change)
+	})
+
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Nil(t, jar.Cookie(urlObject, "1"))
+	if assert.Len(t, changes, 1, "Expected the expired cookie to be reported once") {
+		assert.Equal(t, CookieRemoved, changes[0].Kind)
+		assert.Equal(t, "first", changes[0].Cookie.Value)
+	}
+
+	jar.Cookies(urlObject)
+
+	assert.Len(t, changes, 1, "Expected the removal not to be queued again")
+}
+
+func TestCookieJar_GivenCookies_WhenDelete_ThenOnlyMatchingCookiesAreRemoved(t *testing.T) {
+	jar := NewCookieJar()
+	jar.SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "first"}, {Name: "2", Value: "second"}})
+
+	var changes []CookieChange
+	jar.OnChange(func(change CookieChange) {
+		changes = append(changes, change)
+	})
+
+	assert.True(t, jar.Delete(urlObject, "1"))
+	assert.False(t, jar.Delete(urlObject, "1"), "Expected deleting a missing cookie to report false")
+
+	assert.Equal(t, []string{"2"}, cookieNames(jar.Cookies(urlObject)))
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, CookieRemoved, changes[0].Kind)
+		assert.Nil(t, changes[0].URL)
+	}
+}
+
+func TestCookieJar_GivenCookiesOfSeveralDomains_WhenClearDomain_ThenDomainAndSubdomainsAreCleared(t *testing.T) {
+	jar := NewCookieJar()
+	subUrl := &url.URL{Scheme: "http", Host: "www.test.com", Path: "/"}
+	otherUrl := &url.URL{Scheme: "http", Host: "other.com", Path: "/"}
+
+	jar.SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "root", Domain: "test.com"}})
+	jar.SetCookies(subUrl, []*http.Cookie{{Name: "2", Value: "sub"}})
+	jar.SetCookies(otherUrl, []*http.Cookie{{Name: "3", Value: "other"}})
+
+	assert.Equal(t, 1, jar.ClearDomain("www.test.com"))
+	assert.Equal(t, []string{"1"}, cookieNames(jar.Cookies(subUrl)))
+
+	assert.Equal(t, 1, jar.ClearDomain(".test.com"))
+	assert.Empty(t, jar.Cookies(urlObject))
+	assert.Len(t, jar.Cookies(otherUrl), 1)
+}
+
+func TestCookieJar_GivenPartitions_WhenClear_ThenOnlyItsPartitionIsCleared(t *testing.T) {
+	jar := NewCookieJar()
+	alice := jar.Partition("alice")
+
+	jar.SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "root"}})
+	alice.SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "alice"}})
+	alice.Partition("tab").SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "tab"}})
+
+	var partitions []string
+	jar.OnChange(func(change CookieChange) {
+		partitions = append(partitions, change.Partition)
+	})
+
+	alice.Clear()
+
+	assert.Equal(t, []string{"alice"}, partitions)
+	assert.Nil(t, alice.Cookie(urlObject, "1"))
+	assert.Equal(t, "tab", alice.Partition("tab").Cookie(urlObject, "1").Value)
+	assert.Equal(t, "root", jar.Cookie(urlObject, "1").Value)
+
+	jar.Clear()
+
+	assert.Nil(t, jar.Cookie(urlObject, "1"))
+	assert.Equal(t, "tab", alice.Partition("tab").Cookie(urlObject, "1").Value, "Expected the root jar to keep its partitions")
+}
+
+func TestCookieJar_GivenPartitions_WhenDeleteAndClearDomain_ThenOnlyItsPartitionIsCleared(t *testing.T) {
+	jar := NewCookieJar()
+	alice := jar.Partition("alice")
+
+	for _, partition := range []CookieJar{jar, alice, alice.Partition("tab")} {
+		partition.SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "1"}, {Name: "2", Value: "2"}})
+	}
+
+	assert.True(t, alice.Delete(urlObject, "1"))
+	assert.Equal(t, 1, alice.ClearDomain("test.com"))
+
+	assert.Empty(t, alice.Cookies(urlObject))
+	assert.Len(t, jar.Cookies(urlObject), 2, "Expected the parent partition to be kept")
+	assert.Len(t, alice.Partition("tab").Cookies(urlObject), 2, "Expected the nested partition to be kept")
+}
+
+func TestCookieJar_GivenObserver_WhenObserverUsesJar_ThenNoDeadlock(t *testing.T) {
+	jar := NewCookieJar()
+
+	var seen []*http.Cookie
+	jar.OnChange(func(change CookieChange) {
+		seen = jar.Cookies(urlObject)
+	})
+
+	jar.SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "first"}})
+
+	assert.Len(t, seen, 1)
+}
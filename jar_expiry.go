@@ -0,0 +1,174 @@
+package httpkit
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// WithJanitor returns a CookieJarOption that purges expired cookies from the jar every interval, until ctx is done.
+// Expired cookies are never sent without a janitor, they are only removed lazily when the cookies of their domain are read.
+func WithJanitor(ctx context.Context, interval time.Duration) CookieJarOption {
+	return func(config *cookieJarConfig) {
+		config.janitorCtx = ctx
+		config.janitorInterval = interval
+	}
+}
+
+func (jar *cookieJar) runJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			jar.Lock()
+			purged := jar.purgeExpired(time.Now())
+			changes := jar.takeChanges()
+			jar.Unlock()
+
+			jar.notify(changes)
+
+			if purged > 0 {
+				jar.config.logger.Debug("[Janitor] Purged %d expired cookie(s) from jar.", purged)
+				jar.autosave()
+			}
+		}
+	}
+}
+
+// purgeExpired removes the entries expired at now and returns how many were removed.
+func (jar *cookieJar) purgeExpired(now time.Time) int {
+	return jar.removeEntries(func(e *cookieEntry) bool {
+		return !e.Expires.After(now)
+	})
+}
+
+// cookieExpiry returns the expiry of a cookie from its Expires attribute. The raw attribute is parsed with the lenient
+// algorithm of RFC 6265, section 5.1.1 when the cookie parser could not parse it, e.g. for RFC 850 or asctime dates.
+func cookieExpiry(expires time.Time, rawExpires string) (time.Time, bool) {
+	if !expires.IsZero() {
+		return expires, true
+	}
+
+	if rawExpires == "" {
+		return time.Time{}, false
+	}
+
+	return parseCookieDate(rawExpires)
+}
+
+var cookieDateMonths = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April, "may": time.May, "jun": time.June,
+	"jul": time.July, "aug": time.August, "sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+// parseCookieDate parses a cookie-date as defined by RFC 6265, section 5.1.1.
+func parseCookieDate(value string) (time.Time, bool) {
+	var hour, minute, second, day, year int
+	var month time.Month
+	var foundTime, foundDay, foundMonth, foundYear bool
+
+	for _, token := range strings.FieldsFunc(value, isCookieDateDelimiter) {
+		if !foundTime {
+			if h, m, s, ok := parseCookieTime(token); ok {
+				hour, minute, second, foundTime = h, m, s, true
+				continue
+			}
+		}
+
+		if !foundDay {
+			if d, n := leadingDigits(token, 1, 2); n > 0 {
+				day, foundDay = d, true
+				continue
+			}
+		}
+
+		if !foundMonth && len(token) >= 3 {
+			if m, ok := cookieDateMonths[strings.ToLower(token[:3])]; ok {
+				month, foundMonth = m, true
+				continue
+			}
+		}
+
+		if !foundYear {
+			if y, n := leadingDigits(token, 2, 4); n > 0 {
+				year, foundYear = y, true
+				continue
+			}
+		}
+	}
+
+	if !foundTime || !foundDay || !foundMonth || !foundYear {
+		return time.Time{}, false
+	}
+
+	if year >= 70 && year <= 99 {
+		year += 1900
+	} else if year >= 0 && year <= 69 {
+		year += 2000
+	}
+
+	if day < 1 || day > 31 || year < 1601 || hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}, false
+	}
+
+	date := time.Date(year, month, day, hour, minute, second, 0, time.UTC)
+	if date.Day() != day {
+		// e.g. February 30th
+		return time.Time{}, false
+	}
+
+	return date, true
+}
+
+// parseCookieTime parses a time token: 1*2DIGIT ":" 1*2DIGIT ":" 1*2DIGIT ( non-digit *OCTET ).
+func parseCookieTime(token string) (int, int, int, bool) {
+	parts := strings.SplitN(token, ":", 3)
+	if len(parts) != 3 {
+		return 0, 0, 0, false
+	}
+
+	hour, n := leadingDigits(parts[0], 1, 2)
+	if n != len(parts[0]) {
+		return 0, 0, 0, false
+	}
+
+	minute, n := leadingDigits(parts[1], 1, 2)
+	if n != len(parts[1]) {
+		return 0, 0, 0, false
+	}
+
+	second, n := leadingDigits(parts[2], 1, 2)
+	if n == 0 {
+		return 0, 0, 0, false
+	}
+
+	return hour, minute, second, true
+}
+
+// leadingDigits parses the minDigits to maxDigits leading digits of token, which must be followed by a non-digit or the end of the token.
+// It returns the value and the number of digits, zero if the token does not match.
+func leadingDigits(token string, minDigits, maxDigits int) (int, int) {
+	value, n := 0, 0
+	for n < len(token) && token[n] >= '0' && token[n] <= '9' {
+		if n == maxDigits {
+			return 0, 0
+		}
+
+		value = value*10 + int(token[n]-'0')
+		n++
+	}
+
+	if n < minDigits {
+		return 0, 0
+	}
+
+	return value, n
+}
+
+func isCookieDateDelimiter(r rune) bool {
+	return r == 0x09 || (r >= 0x20 && r <= 0x2f) || (r >= 0x3b && r <= 0x40) || (r >= 0x5b && r <= 0x60) || (r >= 0x7b && r <= 0x7e)
+}
@@ -0,0 +1,93 @@
+package httpkit
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCookieDate(t *testing.T) {
+	expected := time.Date(1994, time.November, 6, 8, 49, 37, 0, time.UTC)
+
+	for _, value := range []string{
+		"Sun, 06 Nov 1994 08:49:37 GMT",
+		"Sunday, 06-Nov-94 08:49:37 GMT",
+		"Sun Nov  6 08:49:37 1994",
+		"06 nov 1994 8:49:37",
+	} {
+		date, ok := parseCookieDate(value)
+
+		assert.True(t, ok, "Expected %q to be parsed", value)
+		assert.Equal(t, expected, date, "Unexpected date for %q", value)
+	}
+
+	for _, value := range []string{"", "Sun, 30 Feb 2025 08:49:37 GMT", "Sun, 06 Nov 1994", "Sun, 06 Nov 1994 24:00:00 GMT"} {
+		_, ok := parseCookieDate(value)
+
+		assert.False(t, ok, "Expected %q to be rejected", value)
+	}
+}
+
+func TestCookieJar_GivenSetCookieHeaders_WhenExpiresIsInThePast_ThenCookieIsRemoved(t *testing.T) {
+	jar := NewCookieJar()
+	jar.SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "first"}, {Name: "2", Value: "second"}})
+
+	resp := &http.Response{Header: http.Header{"Set-Cookie": {
+		"1=gone; Expires=Sunday, 06-Nov-94 08:49:37 GMT",
+		"2=kept; Expires=Friday, 01-Jan-99 00:00:00 GMT; Max-Age=3600",
+		"3=new; Expires=Wed, 01 Jan 2098 00:00:00 GMT",
+	}}}
+
+	jar.SetCookies(urlObject, resp.Cookies())
+
+	assert.Nil(t, jar.Cookie(urlObject, "1"), "Expected cookie with past RFC 850 expiry to be removed")
+	assert.Equal(t, "kept", jar.Cookie(urlObject, "2").Value, "Expected Max-Age to take precedence over Expires")
+	assert.Equal(t, time.Date(2098, time.January, 1, 0, 0, 0, 0, time.UTC), jar.Cookie(urlObject, "3").Expires)
+}
+
+func TestCookieJar_GivenMaxAge_WhenSetCookies_ThenExpiryIsAbsolute(t *testing.T) {
+	jar := NewCookieJar().(*cookieJar)
+	now := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	jar.setCookies(urlObject, []*http.Cookie{{Name: "1", Value: "first", MaxAge: 60}}, now, jar.requestFor(urlObject, FetchContext{}))
+
+	assert.Equal(t, now.Add(time.Minute), jar.entries["test.com"][";test.com;/;1"].Expires)
+	assert.Len(t, jar.cookies(urlObject, now.Add(59*time.Second), jar.requestFor(urlObject, FetchContext{})), 1)
+	assert.Len(t, jar.cookies(urlObject, now.Add(time.Minute), jar.requestFor(urlObject, FetchContext{})), 0, "Expected cookie to expire after Max-Age")
+}
+
+func TestCookieJar_GivenJanitor_WhenCookiesExpire_ThenTheyArePurged(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	jar := NewCookieJar(WithJanitor(ctx, 5*time.Millisecond)).(*cookieJar)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			u := &url.URL{Scheme: "https", Host: fmt.Sprintf("host%d.test.com", i), Path: "/"}
+			for j := 0; j < 50; j++ {
+				jar.SetCookies(u, []*http.Cookie{{Name: fmt.Sprint(j), Value: "value", Expires: time.Now().Add(20 * time.Millisecond)}})
+				jar.Cookies(u)
+				jar.CookiesMap()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Eventually(t, func() bool {
+		jar.RLock()
+		defer jar.RUnlock()
+
+		return len(jar.entries) == 0
+	}, time.Second, 5*time.Millisecond, "Expected janitor to purge expired cookies")
+}
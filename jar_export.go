@@ -0,0 +1,509 @@
+package httpkit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	http "github.com/bogdanfinn/fhttp"
+)
+
+// CookieFormat is a serialization format of the cookies of a jar.
+type CookieFormat int
+
+const (
+	// CookieFormatJSON is a lossless JSON array of cookies, keeping every attribute as well as the creation and last access times.
+	CookieFormatJSON CookieFormat = iota
+	// CookieFormatNetscape is the cookies.txt format of Netscape, used by curl, wget and browser extensions.
+	// It does not hold the SameSite attribute nor the creation and last access times.
+	CookieFormatNetscape
+)
+
+// autosaveDelay is how long the jar waits for further changes before writing its autosave file.
+const autosaveDelay = 500 * time.Millisecond
+
+// netscapeHttpOnlyPrefix marks HttpOnly cookies in the Netscape format, as written by curl.
+const netscapeHttpOnlyPrefix = "#HttpOnly_"
+
+// WithAutosave returns a CookieJarOption that writes the jar in the JSON format to the file at path whenever its cookies change.
+// The file is written in the background, at most half a second after a change, see CookieJar.Flush to write it at once.
+// Cookies already stored in the file are loaded when the jar is created.
+func WithAutosave(path string) CookieJarOption {
+	return func(config *cookieJarConfig) {
+		config.autosavePath = path
+	}
+}
+
+type jsonCookie struct {
+	Expires    *time.Time `json:"expires,omitempty"`
+	Creation   time.Time  `json:"creation"`
+	LastAccess time.Time  `json:"lastAccess"`
+	// Partition is relative to the partition of the exporting jar, empty for the cookies of the jar itself.
+	Partition    string `json:"partition,omitempty"`
+	TopLevelSite string `json:"topLevelSite,omitempty"`
+	Name         string `json:"name"`
+	Value        string `json:"value"`
+	Domain       string `json:"domain"`
+	Path         string `json:"path"`
+	SameSite     string `json:"sameSite,omitempty"`
+	HostOnly     bool   `json:"hostOnly"`
+	Secure       bool   `json:"secure"`
+	HttpOnly     bool   `json:"httpOnly"`
+}
+
+// Export writes all unexpired cookies of the jar to w in the given format.
+// The JSON format also holds the cookies of the nested partitions of the jar and its partitioned (CHIPS) cookies, which
+// the Netscape format cannot represent.
+func (jar *cookieJar) Export(w io.Writer, format CookieFormat) error {
+	jar.RLock()
+	entries := jar.allEntries(time.Now())
+	jar.RUnlock()
+
+	switch format {
+	case CookieFormatJSON:
+		return exportJSONCookies(w, jar.nestedEntries(entries))
+	case CookieFormatNetscape:
+		return exportNetscapeCookies(w, jar.ownEntries(entries))
+	default:
+		return fmt.Errorf("unsupported cookie format: %d", format)
+	}
+}
+
+// Import reads cookies in the given format from r and adds them to the jar, replacing the cookies with the same name,
+// domain and path. Expired cookies are ignored.
+func (jar *cookieJar) Import(r io.Reader, format CookieFormat) error {
+	var entries []cookieEntry
+	var err error
+
+	switch format {
+	case CookieFormatJSON:
+		entries, err = importJSONCookies(r)
+	case CookieFormatNetscape:
+		entries, err = importNetscapeCookies(r)
+	default:
+		return fmt.Errorf("unsupported cookie format: %d", format)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for i := range entries {
+		entries[i].Partition = joinCookiePartition(jar.partition, entries[i].Partition)
+	}
+
+	jar.Lock()
+	modified := jar.addEntries(entries, time.Now())
+	changes := jar.takeChanges()
+	jar.Unlock()
+
+	jar.notify(changes)
+
+	if modified {
+		jar.autosave()
+	}
+
+	return nil
+}
+
+// allEntries returns the unexpired entries of the store sorted by insertion order.
+func (jar *cookieJar) allEntries(now time.Time) []cookieEntry {
+	var entries []cookieEntry
+
+	for _, submap := range jar.entries {
+		for _, e := range submap {
+			if e.Expires.After(now) {
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].seqNum < entries[j].seqNum
+	})
+
+	return entries
+}
+
+// nestedEntries returns the entries of the partition of the jar and of its nested partitions, with partitions made
+// relative to the one of the jar.
+func (jar *cookieJar) nestedEntries(entries []cookieEntry) []cookieEntry {
+	var nested []cookieEntry
+
+	for _, e := range entries {
+		if inCookiePartition(e.Partition, jar.partition) {
+			e.Partition = relativeCookiePartition(e.Partition, jar.partition)
+			nested = append(nested, e)
+		}
+	}
+
+	return nested
+}
+
+// ownEntries returns the unpartitioned entries of the partition of the jar.
+func (jar *cookieJar) ownEntries(entries []cookieEntry) []cookieEntry {
+	var own []cookieEntry
+
+	for _, e := range entries {
+		if e.Partition == jar.partition && e.TopLevelSite == "" {
+			own = append(own, e)
+		}
+	}
+
+	return own
+}
+
+// addEntries stores imported entries as is, without the checks applied to cookies received from a server.
+func (jar *cookieJar) addEntries(entries []cookieEntry, now time.Time) bool {
+	modified := false
+
+	for _, e := range entries {
+		if !e.Expires.After(now) {
+			continue
+		}
+
+		if e.Creation.IsZero() {
+			e.Creation = now
+		}
+
+		if e.LastAccess.IsZero() {
+			e.LastAccess = now
+		}
+
+		key := cookieStoreKey(e.Partition, cookieJarKey(e.Domain, jar.config.publicSuffixList))
+
+		submap := jar.entries[key]
+		if submap == nil {
+			submap = make(map[string]cookieEntry)
+			jar.entries[key] = submap
+		}
+
+		if old, ok := submap[e.id()]; ok {
+			e.seqNum = old.seqNum
+
+			if !e.sameCookie(&old) {
+				jar.recordChange(CookieUpdated, e, nil)
+			}
+		} else {
+			e.seqNum = jar.nextSeqNum
+			jar.nextSeqNum++
+
+			jar.recordChange(CookieAdded, e, nil)
+		}
+
+		submap[e.id()] = e
+		modified = true
+	}
+
+	return modified
+}
+
+// load imports the autosave file if it exists.
+func (jar *cookieJar) load() {
+	file, err := os.Open(jar.config.autosavePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			jar.config.logger.Error("failed to open cookie jar file %s: %s", jar.config.autosavePath, err.Error())
+		}
+
+		return
+	}
+	defer file.Close()
+
+	entries, err := importJSONCookies(file)
+	if err != nil {
+		jar.config.logger.Error("failed to load cookie jar file %s: %s", jar.config.autosavePath, err.Error())
+		return
+	}
+
+	jar.Lock()
+	jar.addEntries(entries, time.Now())
+	jar.Unlock()
+}
+
+// autosave schedules a write of the jar to the autosave file, if configured. The changes made within autosaveDelay are
+// written together, in the background, so that storing cookies never waits on the disk.
+func (jar *cookieJar) autosave() {
+	if jar.config.autosavePath == "" {
+		return
+	}
+
+	jar.autosaveLck.Lock()
+	defer jar.autosaveLck.Unlock()
+
+	if jar.autosaveTimer == nil {
+		jar.autosaveTimer = time.AfterFunc(autosaveDelay, jar.runAutosave)
+	}
+}
+
+func (jar *cookieJar) runAutosave() {
+	jar.autosaveLck.Lock()
+	jar.autosaveTimer = nil
+	jar.autosaveLck.Unlock()
+
+	if err := jar.writeAutosave(); err != nil {
+		jar.config.logger.Error("failed to save cookie jar to %s: %s", jar.config.autosavePath, err.Error())
+	}
+}
+
+// Flush writes the jar to the autosave file without waiting for the pending changes to be saved in the background.
+// It should be called before exiting, it does nothing for a jar without autosave.
+func (jar *cookieJar) Flush() error {
+	if jar.config.autosavePath == "" {
+		return nil
+	}
+
+	jar.autosaveLck.Lock()
+	if jar.autosaveTimer != nil && jar.autosaveTimer.Stop() {
+		jar.autosaveTimer = nil
+	}
+	jar.autosaveLck.Unlock()
+
+	return jar.writeAutosave()
+}
+
+// writeAutosave writes the jar to the autosave file. The file is replaced atomically.
+func (jar *cookieJar) writeAutosave() error {
+	jar.saveLck.Lock()
+	defer jar.saveLck.Unlock()
+
+	return jar.save(jar.config.autosavePath)
+}
+
+func (jar *cookieJar) save(path string) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	// the file holds the cookies of every partition of the store, whichever partition changed
+	root := &cookieJar{cookieStore: jar.cookieStore}
+
+	if err = root.Export(file, CookieFormatJSON); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	if err = file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+func exportJSONCookies(w io.Writer, entries []cookieEntry) error {
+	cookies := make([]jsonCookie, 0, len(entries))
+
+	for _, e := range entries {
+		cookie := jsonCookie{
+			Partition:    e.Partition,
+			TopLevelSite: e.TopLevelSite,
+			Name:         e.Name,
+			Value:        e.Value,
+			Domain:       e.Domain,
+			Path:         e.Path,
+			SameSite:     sameSiteString(e.SameSite),
+			HostOnly:     e.HostOnly,
+			Secure:       e.Secure,
+			HttpOnly:     e.HttpOnly,
+			Creation:     e.Creation,
+			LastAccess:   e.LastAccess,
+		}
+
+		if e.Persistent {
+			expires := e.Expires
+			cookie.Expires = &expires
+		}
+
+		cookies = append(cookies, cookie)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(cookies)
+}
+
+func importJSONCookies(r io.Reader) ([]cookieEntry, error) {
+	var cookies []jsonCookie
+	if err := json.NewDecoder(r).Decode(&cookies); err != nil {
+		return nil, fmt.Errorf("invalid JSON cookies: %w", err)
+	}
+
+	entries := make([]cookieEntry, 0, len(cookies))
+
+	for i, cookie := range cookies {
+		if cookie.Name == "" || cookie.Domain == "" {
+			return nil, fmt.Errorf("invalid JSON cookie at index %d: name and domain are required", i)
+		}
+
+		e := cookieEntry{
+			Partition:    cookie.Partition,
+			TopLevelSite: cookie.TopLevelSite,
+			Name:         cookie.Name,
+			Value:        cookie.Value,
+			Domain:       strings.ToLower(strings.TrimPrefix(cookie.Domain, ".")),
+			Path:         cookie.Path,
+			SameSite:     parseSameSite(cookie.SameSite),
+			HostOnly:     cookie.HostOnly,
+			Secure:       cookie.Secure,
+			HttpOnly:     cookie.HttpOnly,
+			Creation:     cookie.Creation,
+			LastAccess:   cookie.LastAccess,
+			Expires:      endOfTime,
+		}
+
+		if e.Path == "" {
+			e.Path = "/"
+		}
+
+		if cookie.Expires != nil {
+			e.Expires = *cookie.Expires
+			e.Persistent = true
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+func exportNetscapeCookies(w io.Writer, entries []cookieEntry) error {
+	bw := bufio.NewWriter(w)
+
+	_, _ = bw.WriteString("# Netscape HTTP Cookie File\n\n")
+
+	for _, e := range entries {
+		domain := e.Domain
+		if !e.HostOnly {
+			domain = "." + domain
+		}
+
+		if e.HttpOnly {
+			domain = netscapeHttpOnlyPrefix + domain
+		}
+
+		var expires int64
+		if e.Persistent {
+			expires = e.Expires.Unix()
+		}
+
+		_, _ = fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", domain, netscapeBool(!e.HostOnly), e.Path, netscapeBool(e.Secure), expires, e.Name, e.Value)
+	}
+
+	return bw.Flush()
+}
+
+func importNetscapeCookies(r io.Reader) ([]cookieEntry, error) {
+	var entries []cookieEntry
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		httpOnly := strings.HasPrefix(line, netscapeHttpOnlyPrefix)
+		if httpOnly {
+			line = strings.TrimPrefix(line, netscapeHttpOnlyPrefix)
+		}
+
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) == 6 {
+			// cookies without a value are written without the trailing tab by some tools
+			fields = append(fields, "")
+		}
+
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("invalid Netscape cookie at line %d: expected 7 tab separated fields, got %d", lineNumber, len(fields))
+		}
+
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Netscape cookie at line %d: invalid expiry %q", lineNumber, fields[4])
+		}
+
+		e := cookieEntry{
+			Domain:   strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+			HostOnly: !strings.EqualFold(fields[1], "TRUE"),
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Name:     fields[5],
+			Value:    fields[6],
+			HttpOnly: httpOnly,
+			Expires:  endOfTime,
+		}
+
+		if e.Domain == "" || e.Name == "" {
+			return nil, fmt.Errorf("invalid Netscape cookie at line %d: name and domain are required", lineNumber)
+		}
+
+		if e.Path == "" {
+			e.Path = "/"
+		}
+
+		if expires > 0 {
+			e.Expires = time.Unix(expires, 0)
+			e.Persistent = true
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, scanner.Err()
+}
+
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+
+	return "FALSE"
+}
+
+func sameSiteString(sameSite http.SameSite) string {
+	switch sameSite {
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteNoneMode:
+		return "None"
+	case http.SameSiteDefaultMode:
+		return "Default"
+	default:
+		return ""
+	}
+}
+
+func parseSameSite(sameSite string) http.SameSite {
+	switch strings.ToLower(sameSite) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	case "default":
+		return http.SameSiteDefaultMode
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,111 @@
+package httpkit
+
+import (
+	"bytes"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCookieJar_GivenCookies_WhenExportAndImportJSON_ThenCookiesAreKept(t *testing.T) {
+	jar := NewCookieJar()
+	secureUrl := &url.URL{Scheme: "https", Host: "www.test.com", Path: "/"}
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	jar.SetCookies(secureUrl, []*http.Cookie{
+		{Name: "session", Value: "1", HttpOnly: true, SameSite: http.SameSiteStrictMode},
+		{Name: "persistent", Value: "2", Domain: "test.com", Path: "/account", Secure: true, Expires: expires},
+	})
+
+	var exported bytes.Buffer
+	if err := jar.Export(&exported, CookieFormatJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	imported := NewCookieJar()
+	if err := imported.Import(bytes.NewReader(exported.Bytes()), CookieFormatJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	var reexported bytes.Buffer
+	if err := imported.Export(&reexported, CookieFormatJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.JSONEq(t, exported.String(), reexported.String(), "Expected JSON export to be lossless")
+	assert.Equal(t, http.SameSiteStrictMode, imported.Cookie(secureUrl, "session").SameSite)
+	assert.True(t, imported.CookiesMap()["test.com"][1].Expires.Equal(expires))
+}
+
+func TestCookieJar_GivenNetscapeFile_WhenImport_ThenCookiesAreAdded(t *testing.T) {
+	cookiesTxt := "# Netscape HTTP Cookie File\n" +
+		"# This file was generated by libcurl! Edit at your own risk.\n\n" +
+		"#HttpOnly_.test.com\tTRUE\t/\tTRUE\t0\tsession\tabc\n" +
+		"www.test.com\tFALSE\t/account\tFALSE\t4102444800\tpersistent\tdef\n" +
+		"old.test.com\tFALSE\t/\tFALSE\t1\texpired\tghi\n"
+
+	jar := NewCookieJar()
+	if err := jar.Import(strings.NewReader(cookiesTxt), CookieFormatNetscape); err != nil {
+		t.Fatal(err)
+	}
+
+	session := jar.Cookie(&url.URL{Scheme: "https", Host: "api.test.com", Path: "/"}, "session")
+	if assert.NotNil(t, session, "Expected domain cookie to match subdomains") {
+		assert.True(t, session.HttpOnly)
+		assert.True(t, session.Secure)
+	}
+
+	assert.Equal(t, 2, len(jar.Cookies(&url.URL{Scheme: "https", Host: "www.test.com", Path: "/account"})))
+	assert.Equal(t, 2, len(jar.CookiesMap()["test.com"]), "Expected expired cookie to be ignored")
+
+	var buf bytes.Buffer
+	if err := jar.Export(&buf, CookieFormatNetscape); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Contains(t, buf.String(), "#HttpOnly_.test.com\tTRUE\t/\tTRUE\t0\tsession\tabc\n")
+	assert.Contains(t, buf.String(), "www.test.com\tFALSE\t/account\tFALSE\t4102444800\tpersistent\tdef\n")
+}
+
+func TestCookieJar_GivenInvalidNetscapeLine_WhenImport_ThenErrorIsReturned(t *testing.T) {
+	jar := NewCookieJar()
+
+	err := jar.Import(strings.NewReader("test.com\tFALSE\t/\n"), CookieFormatNetscape)
+
+	assert.ErrorContains(t, err, "line 1")
+}
+
+func TestCookieJar_GivenAutosave_WhenSetCookies_ThenJarIsRestoredFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.json")
+
+	jar := NewCookieJar(WithAutosave(path))
+	jar.SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "first"}})
+	assert.NoError(t, jar.Flush())
+
+	restored := NewCookieJar(WithAutosave(path))
+
+	if assert.NotNil(t, restored.Cookie(urlObject, "1"), "Expected cookie to be restored") {
+		assert.Equal(t, "first", restored.Cookie(urlObject, "1").Value)
+	}
+}
+
+func TestCookieJar_GivenAutosave_WhenSetCookies_ThenFileIsWrittenInBackground(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.json")
+
+	jar := NewCookieJar(WithAutosave(path))
+	jar.SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "first"}})
+	jar.SetCookies(urlObject, []*http.Cookie{{Name: "2", Value: "second"}})
+
+	assert.Eventually(t, func() bool {
+		return NewCookieJar(WithAutosave(path)).Cookie(urlObject, "2") != nil
+	}, 5*time.Second, 50*time.Millisecond, "Expected cookies to be saved without Flush")
+}
+
+func TestCookieJar_GivenNoAutosave_WhenFlush_ThenNoError(t *testing.T) {
+	assert.NoError(t, NewCookieJar().Flush())
+}
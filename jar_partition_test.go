@@ -0,0 +1,113 @@
+package httpkit
+
+import (
+	"bytes"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+var secureUrlObject = &url.URL{Scheme: "https", Host: "embed.com", Path: "/"}
+
+func TestCookieJar_GivenPartitions_WhenSetCookies_ThenCookiesAreIsolated(t *testing.T) {
+	jar := NewCookieJar()
+	alice := jar.Partition("alice")
+	bob := jar.Partition("bob")
+
+	alice.SetCookies(urlObject, []*http.Cookie{{Name: "session", Value: "alice"}})
+	bob.SetCookies(urlObject, []*http.Cookie{{Name: "session", Value: "bob"}})
+
+	assert.Equal(t, "alice", alice.Cookie(urlObject, "session").Value)
+	assert.Equal(t, "bob", bob.Cookie(urlObject, "session").Value)
+	assert.Nil(t, jar.Cookie(urlObject, "session"), "Expected the default partition to be isolated")
+	assert.Nil(t, alice.Partition("tab").Cookie(urlObject, "session"), "Expected nested partitions to be isolated")
+	assert.Len(t, alice.CookiesMap()["test.com"], 1)
+}
+
+func TestCookieJar_GivenSeparatorInKey_WhenPartition_ThenPartitionIsNotNested(t *testing.T) {
+	jar := NewCookieJar()
+
+	jar.Partition("a/b").SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "a/b"}})
+	jar.Partition("a").Partition("b").SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "a,b"}})
+
+	assert.Equal(t, "a/b", jar.Partition("a/b").Cookie(urlObject, "1").Value)
+	assert.Equal(t, "a,b", jar.Partition("a").Partition("b").Cookie(urlObject, "1").Value)
+
+	var buf bytes.Buffer
+	assert.NoError(t, jar.Export(&buf, CookieFormatJSON))
+
+	imported := NewCookieJar()
+	assert.NoError(t, imported.Import(&buf, CookieFormatJSON))
+
+	assert.Equal(t, "a/b", imported.Partition("a/b").Cookie(urlObject, "1").Value)
+	assert.Equal(t, "a,b", imported.Partition("a").Partition("b").Cookie(urlObject, "1").Value)
+}
+
+func TestCookieJar_GivenEmptyKey_WhenPartition_ThenPanics(t *testing.T) {
+	assert.Panics(t, func() { NewCookieJar().Partition("") })
+}
+
+func TestCookieJar_GivenPartitionedCookie_WhenGetCookies_ThenOnlyTopLevelSiteMatches(t *testing.T) {
+	jar := NewCookieJar().(*cookieJar)
+	now := time.Now()
+
+	jar.setCookies(secureUrlObject, []*http.Cookie{{Name: "chips", Value: "a", Secure: true, Unparsed: []string{"Partitioned"}}}, now, cookieRequest{topLevelSite: "https://a.com"})
+	jar.setCookies(secureUrlObject, []*http.Cookie{{Name: "chips", Value: "b", Secure: true, Unparsed: []string{"Partitioned"}}}, now, cookieRequest{topLevelSite: "https://b.com"})
+
+	fromA := jar.cookies(secureUrlObject, now, cookieRequest{topLevelSite: "https://a.com"})
+	if assert.Len(t, fromA, 1) {
+		assert.Equal(t, "a", fromA[0].Value)
+		assert.Equal(t, []string{"Partitioned"}, fromA[0].cookie().Unparsed)
+	}
+
+	assert.Len(t, jar.cookies(secureUrlObject, now, cookieRequest{topLevelSite: "https://b.com"}), 1)
+	assert.Len(t, jar.cookies(secureUrlObject, now, cookieRequest{topLevelSite: "https://c.com"}), 0)
+}
+
+func TestCookieJar_GivenInsecurePartitionedCookie_WhenSetCookies_ThenCookieIsRejected(t *testing.T) {
+	jar := NewCookieJar()
+
+	jar.SetCookies(secureUrlObject, []*http.Cookie{{Name: "chips", Value: "a", Unparsed: []string{"Partitioned"}}})
+
+	assert.Nil(t, jar.Cookie(secureUrlObject, "chips"), "Expected partitioned cookie without secure attribute to be rejected")
+}
+
+func TestCookieJar_GivenPartitions_WhenExportAndImportJSON_ThenPartitionsAreKept(t *testing.T) {
+	jar := NewCookieJar()
+	jar.SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "root"}})
+	jar.Partition("alice").SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "alice"}})
+	jar.Partition("alice").Partition("tab").SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "tab"}})
+
+	var buf bytes.Buffer
+	assert.NoError(t, jar.Partition("alice").Export(&buf, CookieFormatJSON))
+
+	imported := NewCookieJar()
+	assert.NoError(t, imported.Partition("carol").Import(&buf, CookieFormatJSON))
+
+	assert.Nil(t, imported.Cookie(urlObject, "1"), "Expected cookies of the parent partition not to be exported")
+	assert.Equal(t, "alice", imported.Partition("carol").Cookie(urlObject, "1").Value)
+	assert.Equal(t, "tab", imported.Partition("carol").Partition("tab").Cookie(urlObject, "1").Value)
+}
+
+func TestCookieJar_GivenAutosave_WhenSetPartitionCookies_ThenAllPartitionsAreSaved(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.json")
+
+	jar := NewCookieJar(WithAutosave(path))
+	jar.SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "root"}})
+	jar.Partition("alice").SetCookies(urlObject, []*http.Cookie{{Name: "1", Value: "alice"}})
+	assert.NoError(t, jar.Partition("alice").Flush())
+
+	restored := NewCookieJar(WithAutosave(path))
+
+	if assert.NotNil(t, restored.Cookie(urlObject, "1")) {
+		assert.Equal(t, "root", restored.Cookie(urlObject, "1").Value)
+	}
+
+	if assert.NotNil(t, restored.Partition("alice").Cookie(urlObject, "1")) {
+		assert.Equal(t, "alice", restored.Partition("alice").Cookie(urlObject, "1").Value)
+	}
+}
@@ -0,0 +1,111 @@
+package httpkit
+
+import (
+	"net/url"
+	"time"
+
+	http "github.com/bogdanfinn/fhttp"
+)
+
+// laxAllowUnsafeMaxAge is how long Chrome sends cookies without a SameSite attribute on cross-site top-level POST
+// navigations after they were set ("Lax+POST").
+const laxAllowUnsafeMaxAge = 2 * time.Minute
+
+// cookieRequest is the context of a request deciding which cookies are sent with it and set from its response.
+type cookieRequest struct {
+	// topLevelSite is the site of the top-level document, the partition key of partitioned cookies.
+	topLevelSite string
+	method       string
+	// crossSite is whether the initiator or a hop of the redirect chain is cross-site with the request URL.
+	crossSite  bool
+	navigation bool
+}
+
+// CookiesFor returns the cookies to send in a request for the given url made in the given fetch context.
+// On cross-site requests, SameSite=Strict cookies are never sent and SameSite=Lax cookies are only sent on top-level
+// navigations with a safe method. Cookies without a SameSite attribute are treated as Lax, like Chrome does.
+func (jar *cookieJar) CookiesFor(u *url.URL, fetch FetchContext) []*http.Cookie {
+	jar.Lock()
+	cookies := toCookies(jar.cookies(u, time.Now(), jar.requestFor(u, fetch)))
+	changes := jar.takeChanges()
+	jar.Unlock()
+
+	jar.notify(changes)
+
+	return cookies
+}
+
+// SetCookiesFor sets the cookies received in the response to a request for the given url made in the given fetch context.
+// Responses to cross-site subresource requests can only set SameSite=None cookies.
+func (jar *cookieJar) SetCookiesFor(u *url.URL, cookies []*http.Cookie, fetch FetchContext) {
+	jar.Lock()
+	modified := jar.setCookies(u, cookies, time.Now(), jar.requestFor(u, fetch))
+	changes := jar.takeChanges()
+	jar.Unlock()
+
+	jar.notify(changes)
+
+	if modified {
+		jar.autosave()
+	}
+}
+
+// requestFor returns the context of a request to u made in fetch. Requests without an initiator are same-site.
+func (jar *cookieJar) requestFor(u *url.URL, fetch FetchContext) cookieRequest {
+	request := cookieRequest{
+		topLevelSite: jar.site(u),
+		method:       fetch.Method,
+		navigation:   fetch.Navigation,
+	}
+
+	if fetch.Initiator == "" {
+		return request
+	}
+
+	initiatorSite := ""
+	if initiator, err := url.Parse(fetch.Initiator); err == nil {
+		initiatorSite = jar.site(initiator)
+	}
+
+	request.crossSite = initiatorSite != request.topLevelSite
+
+	for _, redirect := range fetch.Redirects {
+		if jar.site(redirect) != initiatorSite {
+			request.crossSite = true
+		}
+	}
+
+	if !fetch.Navigation {
+		request.topLevelSite = initiatorSite
+	}
+
+	return request
+}
+
+// sameSiteAllows reports whether the SameSite attribute of the entry allows to send it in request, following
+// RFC 6265bis, section 5.8.3 and the Lax by default behavior of Chrome.
+func (e *cookieEntry) sameSiteAllows(request cookieRequest, now time.Time) bool {
+	if !request.crossSite {
+		return true
+	}
+
+	switch e.SameSite {
+	case http.SameSiteNoneMode:
+		return true
+	case http.SameSiteStrictMode:
+		return false
+	case http.SameSiteLaxMode:
+		return request.navigation && isSafeMethod(request.method)
+	default:
+		return request.navigation && (isSafeMethod(request.method) || now.Sub(e.Creation) < laxAllowUnsafeMaxAge)
+	}
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
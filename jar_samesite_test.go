@@ -0,0 +1,84 @@
+package httpkit
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func sameSiteCookies() []*http.Cookie {
+	return []*http.Cookie{
+		{Name: "strict", Value: "1", SameSite: http.SameSiteStrictMode},
+		{Name: "lax", Value: "1", SameSite: http.SameSiteLaxMode},
+		{Name: "none", Value: "1", SameSite: http.SameSiteNoneMode},
+		{Name: "unspecified", Value: "1"},
+	}
+}
+
+func cookieNames(cookies []*http.Cookie) []string {
+	var names []string
+	for _, cookie := range cookies {
+		names = append(names, cookie.Name)
+	}
+
+	return names
+}
+
+func TestCookieJar_GivenSameSiteCookies_WhenGetCookiesFor_ThenSameSiteRulesAreApplied(t *testing.T) {
+	jar := NewCookieJar()
+	jar.SetCookies(urlObject, sameSiteCookies())
+
+	tests := []struct {
+		name     string
+		fetch    FetchContext
+		expected []string
+	}{
+		{"same-site", FetchContext{Initiator: "http://www.test.com", Method: http.MethodPost}, []string{"strict", "lax", "none", "unspecified"}},
+		{"browser initiated", FetchContext{Navigation: true}, []string{"strict", "lax", "none", "unspecified"}},
+		{"cross-site navigation", FetchContext{Initiator: "https://other.com", Navigation: true, Method: http.MethodGet}, []string{"lax", "none", "unspecified"}},
+		{"cross-site post navigation", FetchContext{Initiator: "https://other.com", Navigation: true, Method: http.MethodPost}, []string{"none", "unspecified"}},
+		{"cross-site subresource", FetchContext{Initiator: "https://other.com", Method: http.MethodGet}, []string{"none"}},
+		{"cross-scheme", FetchContext{Initiator: "https://test.com", Navigation: true, Method: http.MethodGet}, []string{"lax", "none", "unspecified"}},
+		{"cross-site redirect", FetchContext{Initiator: "http://test.com", Navigation: true, Method: http.MethodGet, Redirects: []*url.URL{{Scheme: "https", Host: "other.com"}}}, []string{"lax", "none", "unspecified"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ElementsMatch(t, tt.expected, cookieNames(jar.CookiesFor(urlObject, tt.fetch)))
+		})
+	}
+}
+
+func TestCookieJar_GivenOldUnspecifiedCookie_WhenCrossSitePostNavigation_ThenCookieIsExcluded(t *testing.T) {
+	jar := NewCookieJar().(*cookieJar)
+	now := time.Now()
+
+	jar.setCookies(urlObject, []*http.Cookie{{Name: "unspecified", Value: "1"}}, now, jar.requestFor(urlObject, FetchContext{}))
+
+	request := jar.requestFor(urlObject, FetchContext{Initiator: "https://other.com", Navigation: true, Method: http.MethodPost})
+
+	assert.Len(t, jar.cookies(urlObject, now.Add(time.Minute), request), 1, "Expected Lax+POST to send a fresh cookie")
+	assert.Len(t, jar.cookies(urlObject, now.Add(laxAllowUnsafeMaxAge), request), 0)
+}
+
+func TestCookieJar_GivenCrossSiteSubresource_WhenSetCookiesFor_ThenOnlySameSiteNoneIsStored(t *testing.T) {
+	jar := NewCookieJar()
+
+	jar.SetCookiesFor(urlObject, sameSiteCookies(), FetchContext{Initiator: "https://other.com"})
+
+	assert.Equal(t, []string{"none"}, cookieNames(jar.Cookies(urlObject)))
+}
+
+func TestCookieJar_GivenCrossSiteSubresource_WhenSetPartitionedCookie_ThenCookieIsKeyedByInitiator(t *testing.T) {
+	jar := NewCookieJar()
+	chips := []*http.Cookie{{Name: "chips", Value: "1", Secure: true, SameSite: http.SameSiteNoneMode, Unparsed: []string{"Partitioned"}}}
+
+	jar.SetCookiesFor(secureUrlObject, chips, FetchContext{Initiator: "https://a.com"})
+
+	assert.Len(t, jar.CookiesFor(secureUrlObject, FetchContext{Initiator: "https://www.a.com"}), 1)
+	assert.Len(t, jar.CookiesFor(secureUrlObject, FetchContext{Initiator: "https://b.com"}), 0)
+	assert.Len(t, jar.Cookies(secureUrlObject), 0, "Expected partitioned cookie not to be sent from the top-level site of the cookie")
+}
@@ -68,3 +68,46 @@ func TestCookieJar_GivenAliveCookie_WhenSetExpiredCookies_ThenOldCookiesIsExclud
 
 	assert.Equal(t, 0, len(jar.Cookies(urlObject)), "Expected expired cookie to be excluded")
 }
+
+func TestCookieJar_GivenSiblingsOfPublicSuffix_WhenSetCookies_ThenCookiesAreIsolated(t *testing.T) {
+	jar := NewCookieJar()
+	first := &url.URL{Scheme: "https", Host: "a.co.uk", Path: "/"}
+	second := &url.URL{Scheme: "https", Host: "b.co.uk", Path: "/"}
+
+	jar.SetCookies(first, []*http.Cookie{{Name: "1", Value: "first"}})
+	jar.SetCookies(second, []*http.Cookie{{Name: "2", Value: "second", Domain: "co.uk"}})
+
+	assert.Equal(t, 1, len(jar.Cookies(first)), "Expected only the cookie of a.co.uk")
+	assert.Equal(t, 0, len(jar.Cookies(second)), "Expected cookie for public suffix to be rejected")
+}
+
+func TestCookieJar_GivenDomainCookie_WhenGetCookies_ThenSubdomainsAndPortsMatch(t *testing.T) {
+	jar := NewCookieJar()
+	origin := &url.URL{Scheme: "https", Host: "www.test.com:8443", Path: "/login"}
+
+	jar.SetCookies(origin, []*http.Cookie{
+		{Name: "domain", Value: "1", Domain: ".test.com"},
+		{Name: "host", Value: "2"},
+	})
+
+	assert.Equal(t, 2, len(jar.Cookies(&url.URL{Scheme: "https", Host: "www.test.com", Path: "/"})), "Expected port to be ignored")
+	assert.Equal(t, 1, len(jar.Cookies(&url.URL{Scheme: "https", Host: "api.test.com", Path: "/"})), "Expected host-only cookie not to be sent to subdomains")
+	assert.Equal(t, "test.com", jar.CookiesMap()["test.com"][0].Domain)
+}
+
+func TestCookieJar_GivenPathAndSecureAttributes_WhenGetCookies_ThenOnlyMatchingCookiesAreReturned(t *testing.T) {
+	jar := NewCookieJar()
+	origin := &url.URL{Scheme: "https", Host: "test.com", Path: "/"}
+
+	jar.SetCookies(origin, []*http.Cookie{
+		{Name: "root", Value: "1", Path: "/"},
+		{Name: "account", Value: "2", Path: "/account"},
+		{Name: "secure", Value: "3", Secure: true},
+	})
+
+	cookies := jar.Cookies(&url.URL{Scheme: "https", Host: "test.com", Path: "/account/settings"})
+	assert.Equal(t, 3, len(cookies))
+	assert.Equal(t, "account", cookies[0].Name, "Expected longest path to come first")
+
+	assert.Equal(t, 1, len(jar.Cookies(&url.URL{Scheme: "http", Host: "test.com", Path: "/accounts"})), "Expected path and secure attributes to be honored")
+}
@@ -0,0 +1,17 @@
+package httpkit
+
+import (
+	http "github.com/bogdanfinn/fhttp"
+)
+
+// RoundTripFunc sends a request and returns its response, like http.RoundTripper.
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper.
+func (f RoundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps the next RoundTripFunc of the chain, e.g. to sign requests, inject headers or inspect responses.
+// A middleware may also answer a request itself without calling next.
+type Middleware func(next RoundTripFunc) RoundTripFunc
@@ -0,0 +1,81 @@
+package httpkit
+
+import (
+	"strings"
+
+	"github.com/Mathious6/httpkit/profiles"
+	http "github.com/bogdanfinn/fhttp"
+)
+
+// requestKindFor returns the kind of request whose profile headers complete req.
+func requestKindFor(req *http.Request, reqConfig *requestConfig) profiles.RequestKind {
+	if reqConfig.requestKind != nil {
+		return *reqConfig.requestKind
+	}
+
+	if fetch := ContextFetchContext(req.Context()); fetch != nil && !fetch.Navigation {
+		return profiles.RequestKindFetch
+	}
+
+	return profiles.RequestKindNavigation
+}
+
+// applyProfileHeaders adds the headers of the profile which are not set on req, whatever their case, and the header
+// order of the profile if req has none.
+func applyProfileHeaders(req *http.Request, headers http.Header) {
+	if headers == nil {
+		return
+	}
+
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+
+	for key, values := range headers {
+		if key == http.PHeaderOrderKey || (key == http.HeaderOrderKey && len(req.Header[http.HeaderOrderKey]) > 0) {
+			continue
+		}
+
+		if key != http.HeaderOrderKey && hasHeader(req.Header, key) {
+			continue
+		}
+
+		req.Header[key] = values
+	}
+}
+
+// hasHeader reports whether the header key is set, its name being compared case-insensitively as headers are often
+// defined in lowercase to control their order.
+func hasHeader(header http.Header, key string) bool {
+	for name := range header {
+		if strings.EqualFold(name, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// canonicalizeProfileHeaders renames the headers of the profile and the fetch metadata headers, defined in lowercase
+// for HTTP/2, to the canonical case browsers send over HTTP/1.1. Client hints are sent in lowercase by browsers and are
+// left as is.
+func canonicalizeProfileHeaders(header http.Header, profileHeaders http.Header) {
+	for name, values := range header {
+		if name == http.HeaderOrderKey || name == http.PHeaderOrderKey {
+			continue
+		}
+
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "sec-ch-") || (!hasHeader(profileHeaders, lower) && !containsHint(fetchHeaderNames, lower)) {
+			continue
+		}
+
+		canonical := http.CanonicalHeaderKey(name)
+		if canonical == name {
+			continue
+		}
+
+		delete(header, name)
+		header[canonical] = append(header[canonical], values...)
+	}
+}
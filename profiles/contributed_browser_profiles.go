@@ -7,6 +7,7 @@ import (
 )
 
 var Firefox_135 = ClientProfile{
+	headers: firefoxHeaders("135"),
 	clientHelloId: tls.ClientHelloID{
 		Client:               "Firefox",
 		RandomExtensionOrder: false,
@@ -137,6 +138,7 @@ var Firefox_135 = ClientProfile{
 }
 
 var Firefox_133 = ClientProfile{
+	headers: firefoxHeaders("133"),
 	clientHelloId: tls.ClientHelloID{
 		Client:               "Firefox",
 		RandomExtensionOrder: false,
@@ -266,6 +268,7 @@ var Firefox_133 = ClientProfile{
 }
 
 var Chrome_130_PSK = ClientProfile{
+	headers: chromeHeaders("130"),
 	clientHelloId: tls.ClientHelloID{
 		Client:               "Chrome",
 		RandomExtensionOrder: false,
@@ -374,6 +377,7 @@ var Chrome_130_PSK = ClientProfile{
 }
 
 var Chrome_131_PSK = ClientProfile{
+	headers: chromeHeaders("131"),
 	clientHelloId: tls.ClientHelloID{
 		Client:               "Chrome",
 		RandomExtensionOrder: false,
@@ -484,6 +488,7 @@ var Chrome_131_PSK = ClientProfile{
 }
 
 var Chrome_131 = ClientProfile{
+	headers: chromeHeaders("131"),
 	clientHelloId: tls.ClientHelloID{
 		Client:               "Chrome",
 		RandomExtensionOrder: false,
@@ -593,6 +598,7 @@ var Chrome_131 = ClientProfile{
 }
 
 var Firefox_132 = ClientProfile{
+	headers: firefoxHeaders("132"),
 	clientHelloId: tls.ClientHelloID{
 		Client:               "Firefox",
 		RandomExtensionOrder: false,
@@ -724,6 +730,7 @@ var Firefox_132 = ClientProfile{
 }
 
 var Firefox_123 = ClientProfile{
+	headers: firefoxHeaders("123"),
 	clientHelloId: tls.ClientHelloID{
 		Client:               "Firefox",
 		RandomExtensionOrder: false,
@@ -866,6 +873,7 @@ var Firefox_123 = ClientProfile{
 }
 
 var Firefox_120 = ClientProfile{
+	headers: firefoxHeaders("120"),
 	clientHelloId: tls.ClientHelloID{
 		Client:               "Firefox",
 		RandomExtensionOrder: false,
@@ -1199,6 +1199,7 @@ var ConfirmedAndroid2 = ClientProfile{
 }
 
 var Okhttp4Android13 = ClientProfile{
+	headers: okhttpHeaders("4.10.0"),
 	clientHelloId: tls.ClientHelloID{
 		Client:  "OkHttp4Android13",
 		Version: "4.10.0",
@@ -1223,6 +1224,7 @@ var Okhttp4Android13 = ClientProfile{
 	connectionFlow: 16711681,
 }
 var Okhttp4Android12 = ClientProfile{
+	headers: okhttpHeaders("4.10.0"),
 	clientHelloId: tls.ClientHelloID{
 		Client:  "OkHttp4Android12",
 		Version: "4.10.0",
@@ -1248,6 +1250,7 @@ var Okhttp4Android12 = ClientProfile{
 }
 
 var Okhttp4Android11 = ClientProfile{
+	headers: okhttpHeaders("4.10.0"),
 	clientHelloId: tls.ClientHelloID{
 		Client:  "OkHttp4Android11",
 		Version: "4.10.0",
@@ -1273,6 +1276,7 @@ var Okhttp4Android11 = ClientProfile{
 }
 
 var Okhttp4Android10 = ClientProfile{
+	headers: okhttpHeaders("4.10.0"),
 	clientHelloId: tls.ClientHelloID{
 		Client:  "OkHttp4Android10",
 		Version: "4.10.0",
@@ -1357,6 +1361,7 @@ var Okhttp4Android10 = ClientProfile{
 }
 
 var Okhttp4Android9 = ClientProfile{
+	headers: okhttpHeaders("4.10.0"),
 	clientHelloId: tls.ClientHelloID{
 		Client:  "OkHttp4Android9",
 		Version: "4.10.0",
@@ -1428,6 +1433,7 @@ var Okhttp4Android9 = ClientProfile{
 }
 
 var Okhttp4Android8 = ClientProfile{
+	headers: okhttpHeaders("4.10.0"),
 	clientHelloId: tls.ClientHelloID{
 		Client:  "OkHttp4Android8",
 		Version: "4.10.0",
@@ -1497,6 +1503,7 @@ var Okhttp4Android8 = ClientProfile{
 }
 
 var Okhttp4Android7 = ClientProfile{
+	headers: okhttpHeaders("4.10.0"),
 	clientHelloId: tls.ClientHelloID{
 		Client:  "OkHttp4Android7",
 		Version: "4.10.0",
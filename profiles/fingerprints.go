@@ -0,0 +1,446 @@
+package profiles
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	http "github.com/bogdanfinn/fhttp"
+	tls "github.com/bogdanfinn/utls"
+)
+
+// The TLS extensions the fingerprints look into.
+const (
+	extensionServerName          uint16 = 0
+	extensionSupportedCurves     uint16 = 10
+	extensionSupportedPoints     uint16 = 11
+	extensionSignatureAlgorithms uint16 = 13
+	extensionALPN                uint16 = 16
+	extensionSupportedVersions   uint16 = 43
+)
+
+// TLSFingerprint are the fingerprints of a ClientHello.
+type TLSFingerprint struct {
+	// Ja3 is the JA3 string, see https://github.com/salesforce/ja3.
+	Ja3 string
+	// Ja3Hash is the MD5 hash of Ja3.
+	Ja3Hash string
+	// Ja4 is the JA4 fingerprint, see https://github.com/FoxIO-LLC/ja4/blob/main/technical_details/JA4.md.
+	Ja4 string
+	// Ja4R is the raw JA4 fingerprint, with the sorted cipher suites and extensions instead of their hashes.
+	Ja4R string
+}
+
+// GetTLSFingerprint builds the ClientHello the profile sends to example.com, without connecting, and returns its
+// fingerprints. The JA3 of profiles shuffling their extensions, like Chrome since version 106, changes with every
+// ClientHello, while JA4 sorts them.
+func (c ClientProfile) GetTLSFingerprint() (TLSFingerprint, error) {
+	conn := tls.UClient(nil, &tls.Config{ServerName: "example.com", OmitEmptyPsk: true}, c.clientHelloId, false, false)
+	if err := conn.BuildHandshakeState(); err != nil {
+		return TLSFingerprint{}, fmt.Errorf("failed to build the client hello: %w", err)
+	}
+
+	hello, err := parseClientHello(conn.HandshakeState.Hello.Raw)
+	if err != nil {
+		return TLSFingerprint{}, err
+	}
+
+	ja3 := hello.ja3()
+	ja3Hash := md5.Sum([]byte(ja3))
+	ja4, ja4R := hello.ja4()
+
+	return TLSFingerprint{Ja3: ja3, Ja3Hash: hex.EncodeToString(ja3Hash[:]), Ja4: ja4, Ja4R: ja4R}, nil
+}
+
+// GetAkamaiFingerprint returns the Akamai fingerprint of the HTTP/2 connections of the profile, as described in
+// https://www.blackhat.com/docs/eu-17/materials/eu-17-Shuster-Passive-Fingerprinting-Of-HTTP2-Clients-wp.pdf: its
+// settings, connection window update, priority frames and pseudo header order.
+func (c ClientProfile) GetAkamaiFingerprint() string {
+	settings := make([]string, 0, len(c.settingsOrder))
+	for _, id := range c.settingsOrder {
+		if value, ok := c.settings[id]; ok {
+			settings = append(settings, fmt.Sprintf("%d:%d", id, value))
+		}
+	}
+
+	priorities := make([]string, 0, len(c.priorities))
+	for _, priority := range c.priorities {
+		exclusive := 0
+		if priority.PriorityParam.Exclusive {
+			exclusive = 1
+		}
+
+		priorities = append(priorities, fmt.Sprintf("%d:%d:%d:%d", priority.StreamID, exclusive, priority.PriorityParam.StreamDep, int(priority.PriorityParam.Weight)+1))
+	}
+
+	if len(priorities) == 0 {
+		priorities = append(priorities, "0")
+	}
+
+	pseudoHeaders := make([]string, 0, len(c.pseudoHeaderOrder))
+	for _, pseudoHeader := range c.pseudoHeaderOrder {
+		pseudoHeaders = append(pseudoHeaders, pseudoHeader[1:2])
+	}
+
+	return strings.Join([]string{
+		strings.Join(settings, ";"),
+		strconv.FormatUint(uint64(c.connectionFlow), 10),
+		strings.Join(priorities, ","),
+		strings.Join(pseudoHeaders, ","),
+	}, "|")
+}
+
+// GetAkamaiFingerprintHash returns the MD5 hash of the Akamai fingerprint of the profile.
+func (c ClientProfile) GetAkamaiFingerprintHash() string {
+	hash := md5.Sum([]byte(c.GetAkamaiFingerprint()))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// GetJa4H returns the JA4H fingerprint of the HTTP/2 GET request of the given kind the profile sends without cookie nor
+// referer, see https://github.com/FoxIO-LLC/ja4/blob/main/technical_details/JA4H.md.
+// It returns an empty string if the profile does not define headers for this kind of request.
+func (c ClientProfile) GetJa4H(kind RequestKind) string {
+	headers := c.GetHeaders(kind)
+	if headers == nil {
+		return ""
+	}
+
+	positions := make(map[string]int)
+	for i, name := range headers[http.HeaderOrderKey] {
+		positions[strings.ToLower(name)] = i
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		if name != http.HeaderOrderKey && name != http.PHeaderOrderKey {
+			names = append(names, name)
+		}
+	}
+
+	sort.SliceStable(names, func(i, j int) bool {
+		pi, iok := positions[strings.ToLower(names[i])]
+		pj, jok := positions[strings.ToLower(names[j])]
+
+		if iok != jok {
+			return iok
+		}
+
+		if iok {
+			return pi < pj
+		}
+
+		return names[i] < names[j]
+	})
+
+	language := "0000"
+
+	for _, name := range names {
+		if strings.EqualFold(name, "accept-language") && len(headers[name]) > 0 {
+			language = strings.ToLower(strings.NewReplacer("-", "", ";", "", ",", "").Replace(headers[name][0]))
+			language = (language + "0000")[:4]
+		}
+	}
+
+	noHash := "000000000000"
+
+	return fmt.Sprintf("ge20nn%02d%s_%s_%s_%s", min(len(names), 99), language, truncatedHash(strings.Join(names, ",")), noHash, noHash)
+}
+
+// clientHello are the fields of a ClientHello the fingerprints are made of, without the GREASE values.
+type clientHello struct {
+	version             uint16
+	cipherSuites        []uint16
+	extensions          []uint16
+	curves              []uint16
+	points              []uint8
+	signatureAlgorithms []uint16
+	supportedVersions   []uint16
+	alpn                []string
+}
+
+// parseClientHello parses the ClientHello handshake message raw.
+func parseClientHello(raw []byte) (*clientHello, error) {
+	reader := helloReader(raw)
+	hello := &clientHello{}
+
+	var (
+		sessionId, cipherSuites, compressionMethods, extensions helloReader
+		handshakeType                                           uint8
+		ok                                                      = reader.readUint8(&handshakeType) && handshakeType == 1
+	)
+
+	ok = ok && reader.skip(3) && reader.readUint16(&hello.version) && reader.skip(32) &&
+		reader.readVector8(&sessionId) && reader.readVector16(&cipherSuites) && reader.readVector8(&compressionMethods) &&
+		reader.readVector16(&extensions)
+	if !ok {
+		return nil, errors.New("malformed client hello")
+	}
+
+	for len(cipherSuites) > 0 {
+		var cipherSuite uint16
+		if !cipherSuites.readUint16(&cipherSuite) {
+			return nil, errors.New("malformed client hello cipher suites")
+		}
+
+		if !isGREASE(cipherSuite) {
+			hello.cipherSuites = append(hello.cipherSuites, cipherSuite)
+		}
+	}
+
+	for len(extensions) > 0 {
+		var (
+			extension uint16
+			data      helloReader
+		)
+
+		if !extensions.readUint16(&extension) || !extensions.readVector16(&data) {
+			return nil, errors.New("malformed client hello extensions")
+		}
+
+		if isGREASE(extension) {
+			continue
+		}
+
+		hello.extensions = append(hello.extensions, extension)
+
+		if !hello.parseExtension(extension, data) {
+			return nil, fmt.Errorf("malformed client hello extension %d", extension)
+		}
+	}
+
+	return hello, nil
+}
+
+// parseExtension parses the data of the extensions the fingerprints look into.
+func (h *clientHello) parseExtension(extension uint16, data helloReader) bool {
+	var list helloReader
+
+	switch extension {
+	case extensionSupportedCurves:
+		return data.readVector16(&list) && list.readUint16List(&h.curves)
+	case extensionSupportedPoints:
+		if !data.readVector8(&list) {
+			return false
+		}
+
+		h.points = append(h.points, list...)
+	case extensionSignatureAlgorithms:
+		return data.readVector16(&list) && list.readUint16List(&h.signatureAlgorithms)
+	case extensionSupportedVersions:
+		return data.readVector8(&list) && list.readUint16List(&h.supportedVersions)
+	case extensionALPN:
+		if !data.readVector16(&list) {
+			return false
+		}
+
+		for len(list) > 0 {
+			var protocol helloReader
+			if !list.readVector8(&protocol) {
+				return false
+			}
+
+			h.alpn = append(h.alpn, string(protocol))
+		}
+	}
+
+	return true
+}
+
+// ja3 returns the JA3 string of the ClientHello.
+func (h *clientHello) ja3() string {
+	return strings.Join([]string{
+		strconv.Itoa(int(h.version)),
+		joinUint16(h.cipherSuites, "-", "%d"),
+		joinUint16(h.extensions, "-", "%d"),
+		joinUint16(h.curves, "-", "%d"),
+		joinUint16(func() []uint16 {
+			points := make([]uint16, 0, len(h.points))
+			for _, point := range h.points {
+				points = append(points, uint16(point))
+			}
+
+			return points
+		}(), "-", "%d"),
+	}, ",")
+}
+
+// ja4 returns the JA4 fingerprint of the ClientHello, hashed and raw.
+func (h *clientHello) ja4() (string, string) {
+	version := h.version
+	for _, supportedVersion := range h.supportedVersions {
+		version = max(version, supportedVersion)
+	}
+
+	sni := "i"
+	if containsUint16(h.extensions, extensionServerName) {
+		sni = "d"
+	}
+
+	alpn := "00"
+	if len(h.alpn) > 0 && h.alpn[0] != "" {
+		alpn = h.alpn[0][:1] + h.alpn[0][len(h.alpn[0])-1:]
+	}
+
+	a := fmt.Sprintf("t%s%s%02d%02d%s", ja4Version(version), sni, min(len(h.cipherSuites), 99), min(len(h.extensions), 99), alpn)
+
+	cipherSuites := sortedUint16(h.cipherSuites)
+
+	var extensions []uint16
+	for _, extension := range sortedUint16(h.extensions) {
+		if extension != extensionServerName && extension != extensionALPN {
+			extensions = append(extensions, extension)
+		}
+	}
+
+	b := joinUint16(cipherSuites, ",", "%04x")
+	c := joinUint16(extensions, ",", "%04x")
+
+	if len(h.signatureAlgorithms) > 0 {
+		c += "_" + joinUint16(h.signatureAlgorithms, ",", "%04x")
+	}
+
+	hashedB, hashedC := "000000000000", "000000000000"
+	if len(cipherSuites) > 0 {
+		hashedB = truncatedHash(b)
+	}
+
+	if len(extensions) > 0 {
+		hashedC = truncatedHash(c)
+	}
+
+	return a + "_" + hashedB + "_" + hashedC, a + "_" + b + "_" + c
+}
+
+// ja4Version returns the JA4 notation of a TLS version.
+func ja4Version(version uint16) string {
+	switch version {
+	case tls.VersionTLS13:
+		return "13"
+	case tls.VersionTLS12:
+		return "12"
+	case tls.VersionTLS11:
+		return "11"
+	case tls.VersionTLS10:
+		return "10"
+	case tls.VersionSSL30:
+		return "s3"
+	default:
+		return "00"
+	}
+}
+
+// truncatedHash returns the first 12 characters of the hex encoded SHA-256 hash of s, the hashes JA4 is made of.
+func truncatedHash(s string) string {
+	hash := sha256.Sum256([]byte(s))
+
+	return hex.EncodeToString(hash[:])[:12]
+}
+
+// isGREASE reports whether value is one of the GREASE values of RFC 8701, which fingerprints ignore.
+func isGREASE(value uint16) bool {
+	return value&0x0f0f == 0x0a0a && value>>8 == value&0xff
+}
+
+func joinUint16(values []uint16, separator string, format string) string {
+	formatted := make([]string, 0, len(values))
+	for _, value := range values {
+		formatted = append(formatted, fmt.Sprintf(format, value))
+	}
+
+	return strings.Join(formatted, separator)
+}
+
+func sortedUint16(values []uint16) []uint16 {
+	sorted := append([]uint16{}, values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return sorted
+}
+
+func containsUint16(values []uint16, value uint16) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// helloReader reads the big endian fields of a ClientHello.
+type helloReader []byte
+
+func (r *helloReader) skip(n int) bool {
+	if len(*r) < n {
+		return false
+	}
+
+	*r = (*r)[n:]
+
+	return true
+}
+
+func (r *helloReader) readUint8(v *uint8) bool {
+	if len(*r) < 1 {
+		return false
+	}
+
+	*v = (*r)[0]
+
+	return r.skip(1)
+}
+
+func (r *helloReader) readUint16(v *uint16) bool {
+	if len(*r) < 2 {
+		return false
+	}
+
+	*v = binary.BigEndian.Uint16(*r)
+
+	return r.skip(2)
+}
+
+func (r *helloReader) readVector8(v *helloReader) bool {
+	var n uint8
+
+	return r.readUint8(&n) && r.readBytes(int(n), v)
+}
+
+func (r *helloReader) readVector16(v *helloReader) bool {
+	var n uint16
+
+	return r.readUint16(&n) && r.readBytes(int(n), v)
+}
+
+func (r *helloReader) readBytes(n int, v *helloReader) bool {
+	if len(*r) < n {
+		return false
+	}
+
+	*v = (*r)[:n]
+
+	return r.skip(n)
+}
+
+// readUint16List reads the remaining values of r, without the GREASE values.
+func (r *helloReader) readUint16List(values *[]uint16) bool {
+	for len(*r) > 0 {
+		var value uint16
+		if !r.readUint16(&value) {
+			return false
+		}
+
+		if !isGREASE(value) {
+			*values = append(*values, value)
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,328 @@
+package profiles
+
+import (
+	"strconv"
+
+	http "github.com/bogdanfinn/fhttp"
+)
+
+// RequestKind is the kind of request a header set of a client is sent for.
+type RequestKind int
+
+const (
+	// RequestKindNavigation is a top-level navigation, like following a link or submitting a form.
+	RequestKindNavigation RequestKind = iota
+	// RequestKindFetch is a request made by a script through fetch or XMLHttpRequest.
+	RequestKindFetch
+	// RequestKindImage is an image subresource request.
+	RequestKindImage
+)
+
+func (k RequestKind) String() string {
+	switch k {
+	case RequestKindNavigation:
+		return "navigation"
+	case RequestKindFetch:
+		return "fetch"
+	case RequestKindImage:
+		return "image"
+	default:
+		return "unknown"
+	}
+}
+
+// GetHeaders returns a copy of the headers the client sends for the given kind of request, including their order under
+// http.HeaderOrderKey. Header names are lowercase, like browsers send them over HTTP/2 and HTTP/3; over HTTP/1.1
+// browsers send them in canonical case, client hints excepted. The order also lists the headers set per request, like
+// cookie, referer or origin.
+// The Chrome, Firefox, Safari and OkHttp profiles define headers. It returns nil for the other profiles, like the Opera
+// and mobile application ones, and if the profile does not define headers for this kind of request.
+func (c ClientProfile) GetHeaders(kind RequestKind) http.Header {
+	headers, ok := c.headers[kind]
+	if !ok {
+		return nil
+	}
+
+	return headers.Clone()
+}
+
+// WithHeaders returns a copy of the profile sending the given headers for each kind of request.
+func (c ClientProfile) WithHeaders(headers map[RequestKind]http.Header) ClientProfile {
+	c.headers = make(map[RequestKind]http.Header, len(headers))
+	for kind, header := range headers {
+		c.headers[kind] = header.Clone()
+	}
+
+	return c
+}
+
+const (
+	chromeNavigationAccept        = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"
+	chromeLegacyNavigationAccept  = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.9"
+	chromeImageAccept             = "image/avif,image/webp,image/apng,image/svg+xml,image/*,*/*;q=0.8"
+	firefoxNavigationAccept       = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+	firefoxLegacyNavigationAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"
+	firefoxImageAccept            = "image/avif,image/webp,image/png,image/svg+xml,image/*;q=0.8,*/*;q=0.5"
+	firefoxLegacyImageAccept      = "image/avif,image/webp,*/*"
+	safariNavigationAccept        = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+	safariImageAccept             = "image/webp,image/avif,image/jxl,image/heic,image/heic-sequence,video/*;q=0.8,image/png,image/svg+xml,image/*;q=0.8,*/*;q=0.5"
+	safariLegacyImageAccept       = "image/webp,image/avif,video/*;q=0.8,image/png,image/svg+xml,image/*;q=0.8,*/*;q=0.5"
+)
+
+// chromeHeaders returns the headers of the desktop Chrome of the given major version on Windows. Chrome accepts zstd
+// since version 123 and sends the priority header since version 124.
+func chromeHeaders(version string) map[RequestKind]http.Header {
+	ua, err := newChromeUserAgent(version, PlatformWindows, userAgentPlatforms[PlatformWindows])
+	if err != nil {
+		panic(err)
+	}
+
+	major, _ := strconv.Atoi(version)
+
+	userAgent := ua.UserAgent
+	secChUa := formatBrands(ua.Brands)
+
+	navigationAccept := chromeNavigationAccept
+	if major < 108 {
+		navigationAccept = chromeLegacyNavigationAccept
+	}
+
+	acceptEncoding := "gzip, deflate, br, zstd"
+	if major < 123 {
+		acceptEncoding = "gzip, deflate, br"
+	}
+
+	headers := map[RequestKind]http.Header{
+		RequestKindNavigation: {
+			"sec-ch-ua":                 {secChUa},
+			"sec-ch-ua-mobile":          {"?0"},
+			"sec-ch-ua-platform":        {`"Windows"`},
+			"upgrade-insecure-requests": {"1"},
+			"user-agent":                {userAgent},
+			"accept":                    {navigationAccept},
+			"sec-fetch-site":            {"none"},
+			"sec-fetch-mode":            {"navigate"},
+			"sec-fetch-user":            {"?1"},
+			"sec-fetch-dest":            {"document"},
+			"accept-encoding":           {acceptEncoding},
+			"accept-language":           {"en-US,en;q=0.9"},
+			"priority":                  {"u=0, i"},
+			http.HeaderOrderKey: {
+				"content-length", "cache-control", "sec-ch-ua", "sec-ch-ua-mobile", "sec-ch-ua-platform", "origin",
+				"content-type", "upgrade-insecure-requests", "user-agent", "accept", "sec-fetch-site", "sec-fetch-mode",
+				"sec-fetch-user", "sec-fetch-dest", "referer", "accept-encoding", "accept-language", "cookie", "priority",
+			},
+		},
+		RequestKindFetch: {
+			"sec-ch-ua-platform": {`"Windows"`},
+			"user-agent":         {userAgent},
+			"sec-ch-ua":          {secChUa},
+			"sec-ch-ua-mobile":   {"?0"},
+			"accept":             {"*/*"},
+			"sec-fetch-site":     {"same-origin"},
+			"sec-fetch-mode":     {"cors"},
+			"sec-fetch-dest":     {"empty"},
+			"accept-encoding":    {acceptEncoding},
+			"accept-language":    {"en-US,en;q=0.9"},
+			"priority":           {"u=1, i"},
+			http.HeaderOrderKey: {
+				"content-length", "sec-ch-ua-platform", "user-agent", "sec-ch-ua", "content-type", "sec-ch-ua-mobile",
+				"accept", "origin", "sec-fetch-site", "sec-fetch-mode", "sec-fetch-dest", "referer", "accept-encoding",
+				"accept-language", "cookie", "priority",
+			},
+		},
+		RequestKindImage: {
+			"sec-ch-ua-platform": {`"Windows"`},
+			"user-agent":         {userAgent},
+			"sec-ch-ua":          {secChUa},
+			"sec-ch-ua-mobile":   {"?0"},
+			"accept":             {chromeImageAccept},
+			"sec-fetch-site":     {"same-origin"},
+			"sec-fetch-mode":     {"no-cors"},
+			"sec-fetch-dest":     {"image"},
+			"accept-encoding":    {acceptEncoding},
+			"accept-language":    {"en-US,en;q=0.9"},
+			"priority":           {"i"},
+			http.HeaderOrderKey: {
+				"sec-ch-ua-platform", "user-agent", "sec-ch-ua", "sec-ch-ua-mobile", "accept", "sec-fetch-site",
+				"sec-fetch-mode", "sec-fetch-dest", "referer", "accept-encoding", "accept-language", "cookie", "priority",
+			},
+		},
+	}
+
+	if major < 124 {
+		removeHeader(headers, "priority")
+	}
+
+	return headers
+}
+
+// firefoxHeaders returns the headers of the desktop Firefox of the given major version on Windows. Firefox accepts zstd
+// since version 126 and sends the priority header and its current accept headers since version 128.
+func firefoxHeaders(version string) map[RequestKind]http.Header {
+	userAgent := newFirefoxUserAgent(version, PlatformWindows, userAgentPlatforms[PlatformWindows]).UserAgent
+
+	major, _ := strconv.Atoi(version)
+
+	navigationAccept, imageAccept := firefoxNavigationAccept, firefoxImageAccept
+	if major < 128 {
+		navigationAccept, imageAccept = firefoxLegacyNavigationAccept, firefoxLegacyImageAccept
+	}
+
+	acceptEncoding := "gzip, deflate, br, zstd"
+	if major < 126 {
+		acceptEncoding = "gzip, deflate, br"
+	}
+
+	headers := map[RequestKind]http.Header{
+		RequestKindNavigation: {
+			"user-agent":                {userAgent},
+			"accept":                    {navigationAccept},
+			"accept-language":           {"en-US,en;q=0.5"},
+			"accept-encoding":           {acceptEncoding},
+			"upgrade-insecure-requests": {"1"},
+			"sec-fetch-dest":            {"document"},
+			"sec-fetch-mode":            {"navigate"},
+			"sec-fetch-site":            {"none"},
+			"sec-fetch-user":            {"?1"},
+			"priority":                  {"u=0, i"},
+			"te":                        {"trailers"},
+			http.HeaderOrderKey: {
+				"user-agent", "accept", "accept-language", "accept-encoding", "content-type", "content-length",
+				"origin", "referer", "cookie", "upgrade-insecure-requests", "sec-fetch-dest", "sec-fetch-mode",
+				"sec-fetch-site", "sec-fetch-user", "priority", "te",
+			},
+		},
+		RequestKindFetch: {
+			"user-agent":      {userAgent},
+			"accept":          {"*/*"},
+			"accept-language": {"en-US,en;q=0.5"},
+			"accept-encoding": {acceptEncoding},
+			"sec-fetch-dest":  {"empty"},
+			"sec-fetch-mode":  {"cors"},
+			"sec-fetch-site":  {"same-origin"},
+			"priority":        {"u=4"},
+			"te":              {"trailers"},
+			http.HeaderOrderKey: {
+				"user-agent", "accept", "accept-language", "accept-encoding", "referer", "content-type",
+				"content-length", "origin", "cookie", "sec-fetch-dest", "sec-fetch-mode", "sec-fetch-site", "priority", "te",
+			},
+		},
+		RequestKindImage: {
+			"user-agent":      {userAgent},
+			"accept":          {imageAccept},
+			"accept-language": {"en-US,en;q=0.5"},
+			"accept-encoding": {acceptEncoding},
+			"sec-fetch-dest":  {"image"},
+			"sec-fetch-mode":  {"no-cors"},
+			"sec-fetch-site":  {"same-origin"},
+			"priority":        {"u=5, i"},
+			http.HeaderOrderKey: {
+				"user-agent", "accept", "accept-language", "accept-encoding", "referer", "cookie", "sec-fetch-dest",
+				"sec-fetch-mode", "sec-fetch-site", "priority",
+			},
+		},
+	}
+
+	if major < 128 {
+		removeHeader(headers, "priority")
+	}
+
+	return headers
+}
+
+// safariHeaders returns the headers of the Safari of the given version, e.g. "18.5", on iOS or macOS. Safari accepts
+// JPEG XL and HEIC images and sends the priority header since version 17.
+func safariHeaders(version string, platform Platform) map[RequestKind]http.Header {
+	ua, err := newSafariUserAgent(version, platform)
+	if err != nil {
+		panic(err)
+	}
+
+	major, err := majorVersion(version)
+	if err != nil {
+		panic(err)
+	}
+
+	userAgent := ua.UserAgent
+
+	imageAccept := safariImageAccept
+	if major < 17 {
+		imageAccept = safariLegacyImageAccept
+	}
+
+	headers := map[RequestKind]http.Header{
+		RequestKindNavigation: {
+			"sec-fetch-dest":  {"document"},
+			"user-agent":      {userAgent},
+			"accept":          {safariNavigationAccept},
+			"sec-fetch-site":  {"none"},
+			"sec-fetch-mode":  {"navigate"},
+			"accept-language": {"en-US,en;q=0.9"},
+			"priority":        {"u=0, i"},
+			"accept-encoding": {"gzip, deflate, br"},
+			http.HeaderOrderKey: {
+				"content-type", "origin", "sec-fetch-dest", "user-agent", "accept", "referer", "sec-fetch-site",
+				"sec-fetch-mode", "accept-language", "priority", "accept-encoding", "content-length", "cookie",
+			},
+		},
+		RequestKindFetch: {
+			"sec-fetch-dest":  {"empty"},
+			"user-agent":      {userAgent},
+			"accept":          {"*/*"},
+			"sec-fetch-site":  {"same-origin"},
+			"sec-fetch-mode":  {"cors"},
+			"accept-language": {"en-US,en;q=0.9"},
+			"priority":        {"u=3, i"},
+			"accept-encoding": {"gzip, deflate, br"},
+			http.HeaderOrderKey: {
+				"content-type", "sec-fetch-dest", "user-agent", "accept", "referer", "origin", "sec-fetch-site",
+				"sec-fetch-mode", "accept-language", "priority", "accept-encoding", "content-length", "cookie",
+			},
+		},
+		RequestKindImage: {
+			"sec-fetch-dest":  {"image"},
+			"user-agent":      {userAgent},
+			"accept":          {imageAccept},
+			"sec-fetch-site":  {"same-origin"},
+			"sec-fetch-mode":  {"no-cors"},
+			"accept-language": {"en-US,en;q=0.9"},
+			"priority":        {"u=5, i"},
+			"accept-encoding": {"gzip, deflate, br"},
+			http.HeaderOrderKey: {
+				"sec-fetch-dest", "user-agent", "accept", "referer", "sec-fetch-site", "sec-fetch-mode",
+				"accept-language", "priority", "accept-encoding", "cookie",
+			},
+		},
+	}
+
+	if major < 17 {
+		removeHeader(headers, "priority")
+	}
+
+	return headers
+}
+
+// okhttpHeaders returns the headers of the OkHttp client of the given version, which sends the same headers whatever the request is.
+func okhttpHeaders(version string) map[RequestKind]http.Header {
+	headers := http.Header{
+		"accept-encoding": {"gzip"},
+		"user-agent":      {"okhttp/" + version},
+		http.HeaderOrderKey: {
+			"content-type", "content-length", "accept-encoding", "cookie", "user-agent",
+		},
+	}
+
+	return map[RequestKind]http.Header{
+		RequestKindNavigation: headers,
+		RequestKindFetch:      headers,
+		RequestKindImage:      headers,
+	}
+}
+
+// removeHeader removes the header name from the headers of every kind of request.
+func removeHeader(headers map[RequestKind]http.Header, name string) {
+	for _, header := range headers {
+		delete(header, name)
+	}
+}
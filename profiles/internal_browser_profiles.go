@@ -6,6 +6,7 @@ import (
 )
 
 var Chrome_133_PSK = ClientProfile{
+	headers: chromeHeaders("133"),
 	clientHelloId: tls.ClientHelloID{
 		Client:               "Chrome",
 		RandomExtensionOrder: false,
@@ -49,7 +50,7 @@ var Chrome_133_PSK = ClientProfile{
 					}},
 					&tls.SessionTicketExtension{},
 					&tls.ApplicationSettingsExtensionNew{
-						SupportedProtocols: []string{"h3", "h2"},
+						SupportedProtocols: []string{"h2"},
 					},
 					&tls.KeyShareExtension{KeyShares: []tls.KeyShare{
 						{Group: tls.CurveID(tls.GREASE_PLACEHOLDER), Data: []byte{0}},
@@ -74,7 +75,6 @@ var Chrome_133_PSK = ClientProfile{
 						tls.CurveP384,
 					}},
 					&tls.ALPNExtension{AlpnProtocols: []string{
-						"h3",
 						"h2",
 						"http/1.1",
 					}},
@@ -114,9 +114,28 @@ var Chrome_133_PSK = ClientProfile{
 		":path",
 	},
 	connectionFlow: 15663105,
+	quicProfile: &QUICProfile{
+		TransportParameters: map[QUICTransportParameterID]uint64{
+			QUICTransportParameterMaxIdleTimeout:                 30000,
+			QUICTransportParameterInitialMaxData:                 15728640,
+			QUICTransportParameterInitialMaxStreamDataBidiLocal:  6291456,
+			QUICTransportParameterInitialMaxStreamDataBidiRemote: 6291456,
+			QUICTransportParameterInitialMaxStreamDataUni:        6291456,
+			QUICTransportParameterInitialMaxStreamsBidi:          100,
+			QUICTransportParameterInitialMaxStreamsUni:           103,
+		},
+		// Chrome also announces a QPACK dynamic table, which the HTTP/3 stack can not decode, so it is left out.
+		H3Settings: map[H3SettingID]uint64{
+			H3SettingMaxFieldSectionSize: 262144,
+		},
+		InitialPacketSize:  1250,
+		ConnectionIDLength: 8,
+		H3Datagram:         true,
+	},
 }
 
 var Chrome_133 = ClientProfile{
+	headers: chromeHeaders("133"),
 	clientHelloId: tls.ClientHelloID{
 		Client:               "Chrome",
 		RandomExtensionOrder: false,
@@ -159,7 +178,7 @@ var Chrome_133 = ClientProfile{
 						tls.PKCS1WithSHA512,
 					}},
 					&tls.ApplicationSettingsExtensionNew{
-						SupportedProtocols: []string{"h3", "h2"},
+						SupportedProtocols: []string{"h2"},
 					},
 					&tls.KeyShareExtension{KeyShares: []tls.KeyShare{
 						{Group: tls.CurveID(tls.GREASE_PLACEHOLDER), Data: []byte{0}},
@@ -177,7 +196,6 @@ var Chrome_133 = ClientProfile{
 					}},
 					&tls.StatusRequestExtension{},
 					&tls.ALPNExtension{AlpnProtocols: []string{
-						"h3",
 						"h2",
 						"http/1.1",
 					}},
@@ -224,9 +242,28 @@ var Chrome_133 = ClientProfile{
 		":path",
 	},
 	connectionFlow: 15663105,
+	quicProfile: &QUICProfile{
+		TransportParameters: map[QUICTransportParameterID]uint64{
+			QUICTransportParameterMaxIdleTimeout:                 30000,
+			QUICTransportParameterInitialMaxData:                 15728640,
+			QUICTransportParameterInitialMaxStreamDataBidiLocal:  6291456,
+			QUICTransportParameterInitialMaxStreamDataBidiRemote: 6291456,
+			QUICTransportParameterInitialMaxStreamDataUni:        6291456,
+			QUICTransportParameterInitialMaxStreamsBidi:          100,
+			QUICTransportParameterInitialMaxStreamsUni:           103,
+		},
+		// Chrome also announces a QPACK dynamic table, which the HTTP/3 stack can not decode, so it is left out.
+		H3Settings: map[H3SettingID]uint64{
+			H3SettingMaxFieldSectionSize: 262144,
+		},
+		InitialPacketSize:  1250,
+		ConnectionIDLength: 8,
+		H3Datagram:         true,
+	},
 }
 
 var Chrome_117 = ClientProfile{
+	headers: chromeHeaders("117"),
 	clientHelloId: tls.ClientHelloID{
 		Client:               "Chrome",
 		RandomExtensionOrder: false,
@@ -329,6 +366,7 @@ var Chrome_117 = ClientProfile{
 }
 
 var Chrome_124 = ClientProfile{
+	headers: chromeHeaders("124"),
 	clientHelloId: tls.ClientHelloID{
 		Client:               "Chrome",
 		RandomExtensionOrder: false,
@@ -433,6 +471,7 @@ var Chrome_124 = ClientProfile{
 }
 
 var Chrome_120 = ClientProfile{
+	headers: chromeHeaders("120"),
 	clientHelloId: tls.ClientHelloID{
 		Client:               "Chrome",
 		RandomExtensionOrder: false,
@@ -535,6 +574,7 @@ var Chrome_120 = ClientProfile{
 }
 
 var Chrome_112 = ClientProfile{
+	headers:       chromeHeaders("112"),
 	clientHelloId: tls.HelloChrome_112,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingHeaderTableSize:      65536,
@@ -560,6 +600,7 @@ var Chrome_112 = ClientProfile{
 }
 
 var Chrome_116_PSK = ClientProfile{
+	headers:       chromeHeaders("116"),
 	clientHelloId: tls.HelloChrome_112_PSK,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingHeaderTableSize:      65536,
@@ -585,6 +626,7 @@ var Chrome_116_PSK = ClientProfile{
 }
 
 var Chrome_116_PSK_PQ = ClientProfile{
+	headers:       chromeHeaders("116"),
 	clientHelloId: tls.HelloChrome_115_PQ_PSK,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingHeaderTableSize:      65536,
@@ -610,6 +652,7 @@ var Chrome_116_PSK_PQ = ClientProfile{
 }
 
 var Chrome_111 = ClientProfile{
+	headers:       chromeHeaders("111"),
 	clientHelloId: tls.HelloChrome_111,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingHeaderTableSize:      65536,
@@ -635,6 +678,7 @@ var Chrome_111 = ClientProfile{
 }
 
 var Chrome_110 = ClientProfile{
+	headers:       chromeHeaders("110"),
 	clientHelloId: tls.HelloChrome_110,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingHeaderTableSize:      65536,
@@ -660,6 +704,7 @@ var Chrome_110 = ClientProfile{
 }
 
 var Chrome_109 = ClientProfile{
+	headers:       chromeHeaders("109"),
 	clientHelloId: tls.HelloChrome_109,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingHeaderTableSize:      65536,
@@ -685,6 +730,7 @@ var Chrome_109 = ClientProfile{
 }
 
 var Chrome_108 = ClientProfile{
+	headers:       chromeHeaders("108"),
 	clientHelloId: tls.HelloChrome_108,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingHeaderTableSize:      65536,
@@ -710,6 +756,7 @@ var Chrome_108 = ClientProfile{
 }
 
 var Chrome_107 = ClientProfile{
+	headers:       chromeHeaders("107"),
 	clientHelloId: tls.HelloChrome_107,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingHeaderTableSize:      65536,
@@ -735,6 +782,7 @@ var Chrome_107 = ClientProfile{
 }
 
 var Chrome_106 = ClientProfile{
+	headers:       chromeHeaders("106"),
 	clientHelloId: tls.HelloChrome_106,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingHeaderTableSize:      65536,
@@ -760,6 +808,7 @@ var Chrome_106 = ClientProfile{
 }
 
 var Chrome_105 = ClientProfile{
+	headers:       chromeHeaders("105"),
 	clientHelloId: tls.HelloChrome_105,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingHeaderTableSize:      65536,
@@ -783,6 +832,7 @@ var Chrome_105 = ClientProfile{
 }
 
 var Chrome_104 = ClientProfile{
+	headers:       chromeHeaders("104"),
 	clientHelloId: tls.HelloChrome_104,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingHeaderTableSize:      65536,
@@ -806,6 +856,7 @@ var Chrome_104 = ClientProfile{
 }
 
 var Chrome_103 = ClientProfile{
+	headers:       chromeHeaders("103"),
 	clientHelloId: tls.HelloChrome_103,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingHeaderTableSize:      65536,
@@ -829,6 +880,7 @@ var Chrome_103 = ClientProfile{
 }
 
 var Safari_15_6_1 = ClientProfile{
+	headers:       safariHeaders("15.6.1", PlatformMacOS),
 	clientHelloId: tls.HelloSafari_15_6_1,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingInitialWindowSize:    4194304,
@@ -848,6 +900,7 @@ var Safari_15_6_1 = ClientProfile{
 }
 
 var Safari_16_0 = ClientProfile{
+	headers:       safariHeaders("16.0", PlatformMacOS),
 	clientHelloId: tls.HelloSafari_16_0,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingInitialWindowSize:    4194304,
@@ -867,6 +920,7 @@ var Safari_16_0 = ClientProfile{
 }
 
 var Safari_Ipad_15_6 = ClientProfile{
+	headers:       safariHeaders("15.6", PlatformMacOS),
 	clientHelloId: tls.HelloIPad_15_6,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingInitialWindowSize:    2097152,
@@ -886,6 +940,7 @@ var Safari_Ipad_15_6 = ClientProfile{
 }
 
 var Safari_IOS_17_0 = ClientProfile{
+	headers: safariHeaders("17.0", PlatformIOS),
 	clientHelloId: tls.ClientHelloID{
 		Client:               "iOS",
 		RandomExtensionOrder: false,
@@ -993,6 +1048,7 @@ var Safari_IOS_17_0 = ClientProfile{
 }
 
 var Safari_IOS_18_5 = ClientProfile{
+	headers: safariHeaders("18.5", PlatformIOS),
 	clientHelloId: tls.ClientHelloID{
 		Client:               "iOS",
 		RandomExtensionOrder: false,
@@ -1106,6 +1162,7 @@ var Safari_IOS_18_5 = ClientProfile{
 }
 
 var Safari_IOS_18_0 = ClientProfile{
+	headers: safariHeaders("18.0", PlatformIOS),
 	clientHelloId: tls.ClientHelloID{
 		Client:               "iOS",
 		RandomExtensionOrder: false,
@@ -1217,6 +1274,7 @@ var Safari_IOS_18_0 = ClientProfile{
 }
 
 var Safari_IOS_16_0 = ClientProfile{
+	headers:       safariHeaders("16.0", PlatformIOS),
 	clientHelloId: tls.HelloIOS_16_0,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingInitialWindowSize:    2097152,
@@ -1236,6 +1294,7 @@ var Safari_IOS_16_0 = ClientProfile{
 }
 
 var Safari_IOS_15_5 = ClientProfile{
+	headers:       safariHeaders("15.5", PlatformIOS),
 	clientHelloId: tls.HelloIOS_15_5,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingInitialWindowSize:    2097152,
@@ -1255,6 +1314,7 @@ var Safari_IOS_15_5 = ClientProfile{
 }
 
 var Safari_IOS_15_6 = ClientProfile{
+	headers:       safariHeaders("15.6", PlatformIOS),
 	clientHelloId: tls.HelloIOS_15_6,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingInitialWindowSize:    2097152,
@@ -1274,6 +1334,7 @@ var Safari_IOS_15_6 = ClientProfile{
 }
 
 var Firefox_117 = ClientProfile{
+	headers: firefoxHeaders("117"),
 	clientHelloId: tls.ClientHelloID{
 		Client:               "Firefox",
 		RandomExtensionOrder: false,
@@ -1417,6 +1478,7 @@ var Firefox_117 = ClientProfile{
 }
 
 var Firefox_110 = ClientProfile{
+	headers:       firefoxHeaders("110"),
 	clientHelloId: tls.HelloFirefox_110,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingHeaderTableSize:   65536,
@@ -1475,6 +1537,7 @@ var Firefox_110 = ClientProfile{
 }
 
 var Firefox_108 = ClientProfile{
+	headers:       firefoxHeaders("108"),
 	clientHelloId: tls.HelloFirefox_108,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingHeaderTableSize:   65536,
@@ -1533,6 +1596,7 @@ var Firefox_108 = ClientProfile{
 }
 
 var Firefox_106 = ClientProfile{
+	headers:       firefoxHeaders("106"),
 	clientHelloId: tls.HelloFirefox_106,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingHeaderTableSize:   65536,
@@ -1591,6 +1655,7 @@ var Firefox_106 = ClientProfile{
 }
 
 var Firefox_105 = ClientProfile{
+	headers:       firefoxHeaders("105"),
 	clientHelloId: tls.HelloFirefox_105,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingHeaderTableSize:   65536,
@@ -1649,6 +1714,7 @@ var Firefox_105 = ClientProfile{
 }
 
 var Firefox_104 = ClientProfile{
+	headers:       firefoxHeaders("104"),
 	clientHelloId: tls.HelloFirefox_104,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingHeaderTableSize:   65536,
@@ -1707,6 +1773,7 @@ var Firefox_104 = ClientProfile{
 }
 
 var Firefox_102 = ClientProfile{
+	headers:       firefoxHeaders("102"),
 	clientHelloId: tls.HelloFirefox_102,
 	settings: map[http2.SettingID]uint32{
 		http2.SettingHeaderTableSize:   65536,
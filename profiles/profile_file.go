@@ -0,0 +1,891 @@
+package profiles
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/bogdanfinn/fhttp/http2"
+	tls "github.com/bogdanfinn/utls"
+	"gopkg.in/yaml.v3"
+)
+
+// The extension types of a ProfileFile.
+const (
+	ExtensionTypeGREASE                  = "grease"
+	ExtensionTypeServerName              = "server_name"
+	ExtensionTypeStatusRequest           = "status_request"
+	ExtensionTypeSupportedCurves         = "supported_curves"
+	ExtensionTypeSupportedPoints         = "supported_points"
+	ExtensionTypeSignatureAlgorithms     = "signature_algorithms"
+	ExtensionTypeSignatureAlgorithmsCert = "signature_algorithms_cert"
+	ExtensionTypeALPN                    = "alpn"
+	ExtensionTypeApplicationSettings     = "application_settings"
+	ExtensionTypeApplicationSettingsNew  = "application_settings_new"
+	ExtensionTypeSCT                     = "sct"
+	ExtensionTypePadding                 = "padding"
+	ExtensionTypeExtendedMasterSecret    = "extended_master_secret"
+	ExtensionTypeSessionTicket           = "session_ticket"
+	ExtensionTypePreSharedKey            = "pre_shared_key"
+	ExtensionTypeSupportedVersions       = "supported_versions"
+	ExtensionTypePSKKeyExchangeModes     = "psk_key_exchange_modes"
+	ExtensionTypeKeyShare                = "key_share"
+	ExtensionTypeCompressCertificate     = "compress_certificate"
+	ExtensionTypeRecordSizeLimit         = "record_size_limit"
+	ExtensionTypeDelegatedCredentials    = "delegated_credentials"
+	ExtensionTypeRenegotiationInfo       = "renegotiation_info"
+	ExtensionTypeGREASEECH               = "grease_ech"
+	ExtensionTypeCookie                  = "cookie"
+	ExtensionTypeNPN                     = "npn"
+	ExtensionTypeChannelID               = "channel_id"
+	ExtensionTypeGeneric                 = "generic"
+)
+
+// The padding styles of the padding extension.
+const paddingStyleBoring = "boring"
+
+// The session id generators of a ClientHello spec.
+const sessionIdSha256 = "sha256"
+
+// The request kinds of the headers of a ProfileFile, by their name.
+var requestKinds = []RequestKind{RequestKindNavigation, RequestKindFetch, RequestKindImage}
+
+// ProfileFile is the declarative description of a client profile, read with ReadProfileFile or LoadProfile and written
+// by ClientProfile.Export. It is encoded in JSON or YAML with the field names of the tags, for example:
+//
+//	client_hello:
+//	  client: Chrome
+//	  version: "133"
+//	  spec:
+//	    cipher_suites: [2570, 4865, 4866, 4867]
+//	    compression_methods: [0]
+//	    extensions:
+//	      - type: grease
+//	      - type: server_name
+//	      - type: supported_curves
+//	        curves: [2570, 4588, 29, 23, 24]
+//	      - type: key_share
+//	        key_shares: [{group: 2570, data: "00"}, {group: 29}]
+//	      - type: generic
+//	        id: 22
+//	http2:
+//	  settings: [{id: 1, value: 65536}, {id: 2, value: 0}]
+//	  settings_order: [1, 2]
+//	  connection_flow: 15663105
+//	  pseudo_header_order: [":method", ":authority", ":scheme", ":path"]
+//	headers:
+//	  navigation:
+//	    order: [accept, user-agent, cookie]
+//	    values:
+//	      - {name: accept, value: "*/*"}
+//	      - {name: user-agent, value: "Mozilla/5.0"}
+//
+// The TLS and HTTP/2 values are the numbers of their registries; 2570 (0x0a0a) is the GREASE placeholder. Bytes are
+// hex encoded. A client hello without spec refers to a ClientHelloID built into the TLS library, like Chrome 103.
+type ProfileFile struct {
+	ClientHello ProfileClientHello        `json:"client_hello" yaml:"client_hello"`
+	HTTP2       ProfileHTTP2              `json:"http2" yaml:"http2"`
+	Headers     map[string]ProfileHeaders `json:"headers,omitempty" yaml:"headers,omitempty"`
+	QUIC        *ProfileQUIC              `json:"quic,omitempty" yaml:"quic,omitempty"`
+}
+
+// ProfileClientHello is the ClientHello of a profile: the name of its ClientHelloID and, for clients which are not built
+// into the TLS library, its spec.
+type ProfileClientHello struct {
+	Client               string                  `json:"client" yaml:"client"`
+	Version              string                  `json:"version" yaml:"version"`
+	RandomExtensionOrder bool                    `json:"random_extension_order,omitempty" yaml:"random_extension_order,omitempty"`
+	Spec                 *ProfileClientHelloSpec `json:"spec,omitempty" yaml:"spec,omitempty"`
+}
+
+// ProfileClientHelloSpec is the ClientHello of a profile, in the order it is sent.
+type ProfileClientHelloSpec struct {
+	CipherSuites       []uint16           `json:"cipher_suites" yaml:"cipher_suites"`
+	CompressionMethods []uint16           `json:"compression_methods,omitempty" yaml:"compression_methods,omitempty,flow"`
+	Extensions         []ProfileExtension `json:"extensions" yaml:"extensions"`
+	TLSVersMin         uint16             `json:"tls_version_min,omitempty" yaml:"tls_version_min,omitempty"`
+	TLSVersMax         uint16             `json:"tls_version_max,omitempty" yaml:"tls_version_max,omitempty"`
+	// SessionID is the generator of the session ids, "sha256" to hash the session ticket, empty for random ids.
+	SessionID string `json:"session_id,omitempty" yaml:"session_id,omitempty"`
+}
+
+// ProfileExtension is a ClientHello extension. Type tells the extension and which of the other fields it has:
+//
+//   - grease: Value and Data, its body, both optional
+//   - server_name: ServerName, optional
+//   - supported_curves: Curves
+//   - supported_points: Points
+//   - signature_algorithms, signature_algorithms_cert and delegated_credentials: SignatureAlgorithms
+//   - alpn, application_settings, application_settings_new and npn: Protocols
+//   - padding: Padding, the style computing the padding length, or PaddingLength
+//   - supported_versions: Versions
+//   - psk_key_exchange_modes: Modes
+//   - key_share: KeyShares
+//   - compress_certificate: Algorithms
+//   - record_size_limit: Limit
+//   - renegotiation_info: Renegotiation and Data, the renegotiated connection
+//   - grease_ech: CipherSuites, ConfigIDs, PayloadLengths and Data, the encapsulated key
+//   - cookie: Data
+//   - channel_id: OldID
+//   - generic: ID and Data, sent as is
+//
+// The status_request, sct, extended_master_secret, session_ticket and pre_shared_key extensions have no field.
+type ProfileExtension struct {
+	Type                string                   `json:"type" yaml:"type"`
+	ID                  uint16                   `json:"id,omitempty" yaml:"id,omitempty"`
+	Value               uint16                   `json:"value,omitempty" yaml:"value,omitempty"`
+	Data                HexBytes                 `json:"data,omitempty" yaml:"data,omitempty"`
+	ServerName          string                   `json:"server_name,omitempty" yaml:"server_name,omitempty"`
+	Curves              []uint16                 `json:"curves,omitempty" yaml:"curves,omitempty,flow"`
+	Points              []uint16                 `json:"points,omitempty" yaml:"points,omitempty,flow"`
+	SignatureAlgorithms []uint16                 `json:"signature_algorithms,omitempty" yaml:"signature_algorithms,omitempty,flow"`
+	Protocols           []string                 `json:"protocols,omitempty" yaml:"protocols,omitempty,flow"`
+	Padding             string                   `json:"padding,omitempty" yaml:"padding,omitempty"`
+	PaddingLength       int                      `json:"padding_length,omitempty" yaml:"padding_length,omitempty"`
+	Versions            []uint16                 `json:"versions,omitempty" yaml:"versions,omitempty,flow"`
+	Modes               []uint16                 `json:"modes,omitempty" yaml:"modes,omitempty,flow"`
+	KeyShares           []ProfileKeyShare        `json:"key_shares,omitempty" yaml:"key_shares,omitempty"`
+	Algorithms          []uint16                 `json:"algorithms,omitempty" yaml:"algorithms,omitempty,flow"`
+	Limit               uint16                   `json:"limit,omitempty" yaml:"limit,omitempty"`
+	Renegotiation       int                      `json:"renegotiation,omitempty" yaml:"renegotiation,omitempty"`
+	CipherSuites        []ProfileHPKECipherSuite `json:"cipher_suites,omitempty" yaml:"cipher_suites,omitempty"`
+	ConfigIDs           []uint16                 `json:"config_ids,omitempty" yaml:"config_ids,omitempty,flow"`
+	PayloadLengths      []uint16                 `json:"payload_lengths,omitempty" yaml:"payload_lengths,omitempty,flow"`
+	OldID               bool                     `json:"old_id,omitempty" yaml:"old_id,omitempty"`
+}
+
+// ProfileKeyShare is a key share of the key_share extension. Data is only set for the GREASE key share.
+type ProfileKeyShare struct {
+	Group uint16   `json:"group" yaml:"group"`
+	Data  HexBytes `json:"data,omitempty" yaml:"data,omitempty"`
+}
+
+// ProfileHPKECipherSuite is a candidate cipher suite of the grease_ech extension.
+type ProfileHPKECipherSuite struct {
+	KdfID  uint16 `json:"kdf_id" yaml:"kdf_id"`
+	AeadID uint16 `json:"aead_id" yaml:"aead_id"`
+}
+
+// ProfileHTTP2 is the HTTP/2 fingerprint of a profile.
+type ProfileHTTP2 struct {
+	Settings          []ProfileSetting      `json:"settings,omitempty" yaml:"settings,omitempty"`
+	SettingsOrder     []uint16              `json:"settings_order,omitempty" yaml:"settings_order,omitempty,flow"`
+	ConnectionFlow    uint32                `json:"connection_flow,omitempty" yaml:"connection_flow,omitempty"`
+	HeaderPriority    *ProfilePriorityParam `json:"header_priority,omitempty" yaml:"header_priority,omitempty"`
+	Priorities        []ProfilePriority     `json:"priorities,omitempty" yaml:"priorities,omitempty"`
+	PseudoHeaderOrder []string              `json:"pseudo_header_order,omitempty" yaml:"pseudo_header_order,omitempty,flow"`
+}
+
+// ProfileSetting is an HTTP/2 setting.
+type ProfileSetting struct {
+	ID    uint16 `json:"id" yaml:"id"`
+	Value uint32 `json:"value" yaml:"value"`
+}
+
+// ProfilePriorityParam is the priority of an HTTP/2 stream. Weight is the weight of the frame, one less than the
+// weight of the stream.
+type ProfilePriorityParam struct {
+	StreamDep uint32 `json:"stream_dep" yaml:"stream_dep"`
+	Exclusive bool   `json:"exclusive" yaml:"exclusive"`
+	Weight    uint8  `json:"weight" yaml:"weight"`
+}
+
+// ProfilePriority is an HTTP/2 PRIORITY frame sent after the connection preface.
+type ProfilePriority struct {
+	StreamID             uint32 `json:"stream_id" yaml:"stream_id"`
+	ProfilePriorityParam `yaml:",inline"`
+}
+
+// ProfileHeaders are the headers of a kind of request, as returned by ClientProfile.GetHeaders. Values lists the
+// headers in their order, a header with several values once per value.
+type ProfileHeaders struct {
+	Order  []string        `json:"order,omitempty" yaml:"order,omitempty,flow"`
+	Values []ProfileHeader `json:"values,omitempty" yaml:"values,omitempty"`
+}
+
+// ProfileHeader is a header value.
+type ProfileHeader struct {
+	Name  string `json:"name" yaml:"name"`
+	Value string `json:"value" yaml:"value"`
+}
+
+// ProfileQUIC is the QUIC and HTTP/3 description of a profile, see QUICProfile.
+type ProfileQUIC struct {
+	TransportParameters []ProfileQUICValue `json:"transport_parameters,omitempty" yaml:"transport_parameters,omitempty"`
+	H3Settings          []ProfileQUICValue `json:"h3_settings,omitempty" yaml:"h3_settings,omitempty"`
+	InitialPacketSize   uint16             `json:"initial_packet_size,omitempty" yaml:"initial_packet_size,omitempty"`
+	ConnectionIDLength  int                `json:"connection_id_length,omitempty" yaml:"connection_id_length,omitempty"`
+	H3Datagram          bool               `json:"h3_datagram,omitempty" yaml:"h3_datagram,omitempty"`
+}
+
+// ProfileQUICValue is the value of a QUIC transport parameter or HTTP/3 setting.
+type ProfileQUICValue struct {
+	ID    uint64 `json:"id" yaml:"id"`
+	Value uint64 `json:"value" yaml:"value"`
+}
+
+// HexBytes are bytes encoded as a hex string.
+type HexBytes []byte
+
+func (b HexBytes) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(b)), nil
+}
+
+func (b *HexBytes) UnmarshalText(text []byte) error {
+	decoded, err := hex.DecodeString(string(text))
+	if err != nil {
+		return err
+	}
+
+	*b = decoded
+
+	return nil
+}
+
+// LoadProfile reads a profile file in JSON or YAML from r, see ProfileFile, and returns its client profile.
+func LoadProfile(r io.Reader) (ClientProfile, error) {
+	file, err := ReadProfileFile(r)
+	if err != nil {
+		return ClientProfile{}, err
+	}
+
+	return file.Profile()
+}
+
+// ReadProfileFile reads a profile file in JSON or YAML from r. Unknown fields are rejected.
+func ReadProfileFile(r io.Reader) (*ProfileFile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &ProfileFile{}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.DisallowUnknownFields()
+
+		err = decoder.Decode(file)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+
+		err = decoder.Decode(file)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid profile file: %w", err)
+	}
+
+	return file, nil
+}
+
+// Profile returns the client profile of the file. The extensions of the ClientHello are created anew for every
+// connection.
+func (f *ProfileFile) Profile() (ClientProfile, error) {
+	clientHelloId := tls.ClientHelloID{
+		Client:               f.ClientHello.Client,
+		Version:              f.ClientHello.Version,
+		RandomExtensionOrder: f.ClientHello.RandomExtensionOrder,
+		SpecFactory:          tls.EmptyClientHelloSpecFactory,
+	}
+
+	if spec := f.ClientHello.Spec; spec != nil {
+		if _, err := spec.clientHelloSpec(); err != nil {
+			return ClientProfile{}, err
+		}
+
+		clientHelloId.SpecFactory = spec.clientHelloSpec
+	}
+
+	profile := ClientProfile{
+		clientHelloId:     clientHelloId,
+		pseudoHeaderOrder: f.HTTP2.PseudoHeaderOrder,
+		connectionFlow:    f.HTTP2.ConnectionFlow,
+		settings:          make(map[http2.SettingID]uint32, len(f.HTTP2.Settings)),
+	}
+
+	for _, setting := range f.HTTP2.Settings {
+		profile.settings[http2.SettingID(setting.ID)] = setting.Value
+	}
+
+	for _, id := range f.HTTP2.SettingsOrder {
+		profile.settingsOrder = append(profile.settingsOrder, http2.SettingID(id))
+	}
+
+	for _, priority := range f.HTTP2.Priorities {
+		profile.priorities = append(profile.priorities, http2.Priority{StreamID: priority.StreamID, PriorityParam: priority.param()})
+	}
+
+	if f.HTTP2.HeaderPriority != nil {
+		headerPriority := f.HTTP2.HeaderPriority.param()
+		profile.headerPriority = &headerPriority
+	}
+
+	if f.Headers != nil {
+		profile.headers = make(map[RequestKind]http.Header, len(f.Headers))
+	}
+
+	for name, headers := range f.Headers {
+		kind, ok := requestKindByName(name)
+		if !ok {
+			return ClientProfile{}, fmt.Errorf("unknown request kind %s", name)
+		}
+
+		header := make(http.Header, len(headers.Values)+1)
+		if headers.Order != nil {
+			header[http.HeaderOrderKey] = headers.Order
+		}
+
+		for _, value := range headers.Values {
+			header[value.Name] = append(header[value.Name], value.Value)
+		}
+
+		profile.headers[kind] = header
+	}
+
+	if f.QUIC != nil {
+		profile.quicProfile = f.QUIC.quicProfile()
+
+		if err := profile.quicProfile.Validate(); err != nil {
+			return ClientProfile{}, err
+		}
+	}
+
+	return profile, nil
+}
+
+// Export returns the profile file of the profile, which loads back to an identical profile. It fails for ClientHello
+// specs with extensions a ProfileFile does not describe, like session tickets or pre-shared keys set up with a session.
+func (c ClientProfile) Export() (*ProfileFile, error) {
+	clientHelloId := c.clientHelloId
+	if clientHelloId.Seed != nil || clientHelloId.Weights != nil {
+		return nil, errors.New("randomized client hellos can not be exported")
+	}
+
+	file := &ProfileFile{
+		ClientHello: ProfileClientHello{
+			Client:               clientHelloId.Client,
+			Version:              clientHelloId.Version,
+			RandomExtensionOrder: clientHelloId.RandomExtensionOrder,
+		},
+		HTTP2: ProfileHTTP2{
+			ConnectionFlow:    c.connectionFlow,
+			PseudoHeaderOrder: c.pseudoHeaderOrder,
+		},
+	}
+
+	if clientHelloId.SpecFactory != nil && !sameFunc(clientHelloId.SpecFactory, tls.EmptyClientHelloSpecFactory) {
+		spec, err := clientHelloId.SpecFactory()
+		if err != nil {
+			return nil, err
+		}
+
+		file.ClientHello.Spec, err = exportClientHelloSpec(spec)
+		if err != nil {
+			return nil, fmt.Errorf("client hello %s: %w", clientHelloId.Str(), err)
+		}
+	}
+
+	settingIds := make([]http2.SettingID, 0, len(c.settings))
+	for id := range c.settings {
+		settingIds = append(settingIds, id)
+	}
+
+	sort.Slice(settingIds, func(i, j int) bool { return settingIds[i] < settingIds[j] })
+
+	for _, id := range settingIds {
+		file.HTTP2.Settings = append(file.HTTP2.Settings, ProfileSetting{ID: uint16(id), Value: c.settings[id]})
+	}
+
+	for _, id := range c.settingsOrder {
+		file.HTTP2.SettingsOrder = append(file.HTTP2.SettingsOrder, uint16(id))
+	}
+
+	for _, priority := range c.priorities {
+		file.HTTP2.Priorities = append(file.HTTP2.Priorities, ProfilePriority{StreamID: priority.StreamID, ProfilePriorityParam: exportPriorityParam(priority.PriorityParam)})
+	}
+
+	if c.headerPriority != nil {
+		headerPriority := exportPriorityParam(*c.headerPriority)
+		file.HTTP2.HeaderPriority = &headerPriority
+	}
+
+	for _, kind := range requestKinds {
+		header, ok := c.headers[kind]
+		if !ok {
+			continue
+		}
+
+		if file.Headers == nil {
+			file.Headers = make(map[string]ProfileHeaders, len(c.headers))
+		}
+
+		file.Headers[kind.String()] = exportHeaders(header)
+	}
+
+	if c.quicProfile != nil {
+		file.QUIC = exportQUICProfile(c.quicProfile)
+	}
+
+	return file, nil
+}
+
+func (p ProfilePriorityParam) param() http2.PriorityParam {
+	return http2.PriorityParam{StreamDep: p.StreamDep, Exclusive: p.Exclusive, Weight: p.Weight}
+}
+
+func exportPriorityParam(param http2.PriorityParam) ProfilePriorityParam {
+	return ProfilePriorityParam{StreamDep: param.StreamDep, Exclusive: param.Exclusive, Weight: param.Weight}
+}
+
+func requestKindByName(name string) (RequestKind, bool) {
+	for _, kind := range requestKinds {
+		if kind.String() == name {
+			return kind, true
+		}
+	}
+
+	return 0, false
+}
+
+// exportHeaders returns the headers of header in their order, followed by the headers out of the order sorted by name.
+func exportHeaders(header http.Header) ProfileHeaders {
+	headers := ProfileHeaders{Order: header[http.HeaderOrderKey]}
+
+	positions := make(map[string]int, len(headers.Order))
+	for i, name := range headers.Order {
+		positions[strings.ToLower(name)] = i
+	}
+
+	names := make([]string, 0, len(header))
+	for name := range header {
+		if name != http.HeaderOrderKey {
+			names = append(names, name)
+		}
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		pi, iok := positions[strings.ToLower(names[i])]
+		pj, jok := positions[strings.ToLower(names[j])]
+
+		if iok != jok {
+			return iok
+		}
+
+		if iok && pi != pj {
+			return pi < pj
+		}
+
+		return names[i] < names[j]
+	})
+
+	for _, name := range names {
+		for _, value := range header[name] {
+			headers.Values = append(headers.Values, ProfileHeader{Name: name, Value: value})
+		}
+	}
+
+	return headers
+}
+
+func (q *ProfileQUIC) quicProfile() *QUICProfile {
+	profile := &QUICProfile{
+		InitialPacketSize:  q.InitialPacketSize,
+		ConnectionIDLength: q.ConnectionIDLength,
+		H3Datagram:         q.H3Datagram,
+	}
+
+	if q.TransportParameters != nil {
+		profile.TransportParameters = make(map[QUICTransportParameterID]uint64, len(q.TransportParameters))
+	}
+
+	for _, parameter := range q.TransportParameters {
+		profile.TransportParameters[QUICTransportParameterID(parameter.ID)] = parameter.Value
+	}
+
+	if q.H3Settings != nil {
+		profile.H3Settings = make(map[H3SettingID]uint64, len(q.H3Settings))
+	}
+
+	for _, setting := range q.H3Settings {
+		profile.H3Settings[H3SettingID(setting.ID)] = setting.Value
+	}
+
+	return profile
+}
+
+func exportQUICProfile(profile *QUICProfile) *ProfileQUIC {
+	quic := &ProfileQUIC{
+		InitialPacketSize:  profile.InitialPacketSize,
+		ConnectionIDLength: profile.ConnectionIDLength,
+		H3Datagram:         profile.H3Datagram,
+	}
+
+	for _, id := range sortedKeys(profile.TransportParameters) {
+		quic.TransportParameters = append(quic.TransportParameters, ProfileQUICValue{ID: uint64(id), Value: profile.TransportParameters[id]})
+	}
+
+	for _, id := range sortedKeys(profile.H3Settings) {
+		quic.H3Settings = append(quic.H3Settings, ProfileQUICValue{ID: uint64(id), Value: profile.H3Settings[id]})
+	}
+
+	return quic
+}
+
+func sortedKeys[K ~uint64, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	return keys
+}
+
+// clientHelloSpec returns a new ClientHello spec of the file.
+func (s *ProfileClientHelloSpec) clientHelloSpec() (tls.ClientHelloSpec, error) {
+	compressionMethods, err := uint8s("compression methods", s.CompressionMethods)
+	if err != nil {
+		return tls.ClientHelloSpec{}, err
+	}
+
+	spec := tls.ClientHelloSpec{
+		CipherSuites:       append([]uint16{}, s.CipherSuites...),
+		CompressionMethods: compressionMethods,
+		TLSVersMin:         s.TLSVersMin,
+		TLSVersMax:         s.TLSVersMax,
+	}
+
+	switch s.SessionID {
+	case "":
+	case sessionIdSha256:
+		spec.GetSessionID = sha256.Sum256
+	default:
+		return tls.ClientHelloSpec{}, fmt.Errorf("unknown session id generator %s", s.SessionID)
+	}
+
+	for i, e := range s.Extensions {
+		extension, err := e.extension()
+		if err != nil {
+			return tls.ClientHelloSpec{}, fmt.Errorf("extension %d: %w", i, err)
+		}
+
+		spec.Extensions = append(spec.Extensions, extension)
+	}
+
+	return spec, nil
+}
+
+func exportClientHelloSpec(spec tls.ClientHelloSpec) (*ProfileClientHelloSpec, error) {
+	file := &ProfileClientHelloSpec{
+		CipherSuites:       spec.CipherSuites,
+		CompressionMethods: uint16s(spec.CompressionMethods),
+		TLSVersMin:         spec.TLSVersMin,
+		TLSVersMax:         spec.TLSVersMax,
+	}
+
+	if spec.GetSessionID != nil {
+		if !sameFunc(spec.GetSessionID, sha256.Sum256) {
+			return nil, errors.New("unsupported session id generator")
+		}
+
+		file.SessionID = sessionIdSha256
+	}
+
+	for _, extension := range spec.Extensions {
+		e, err := exportExtension(extension)
+		if err != nil {
+			return nil, err
+		}
+
+		file.Extensions = append(file.Extensions, e)
+	}
+
+	return file, nil
+}
+
+// extension returns a new TLS extension of e.
+func (e ProfileExtension) extension() (tls.TLSExtension, error) {
+	switch e.Type {
+	case ExtensionTypeGREASE:
+		return &tls.UtlsGREASEExtension{Value: e.Value, Body: e.Data}, nil
+	case ExtensionTypeServerName:
+		return &tls.SNIExtension{ServerName: e.ServerName}, nil
+	case ExtensionTypeStatusRequest:
+		return &tls.StatusRequestExtension{}, nil
+	case ExtensionTypeSupportedCurves:
+		curves := make([]tls.CurveID, 0, len(e.Curves))
+		for _, curve := range e.Curves {
+			curves = append(curves, tls.CurveID(curve))
+		}
+
+		return &tls.SupportedCurvesExtension{Curves: curves}, nil
+	case ExtensionTypeSupportedPoints:
+		points, err := uint8s("points", e.Points)
+		if err != nil {
+			return nil, err
+		}
+
+		return &tls.SupportedPointsExtension{SupportedPoints: points}, nil
+	case ExtensionTypeSignatureAlgorithms:
+		return &tls.SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: signatureSchemes(e.SignatureAlgorithms)}, nil
+	case ExtensionTypeSignatureAlgorithmsCert:
+		return &tls.SignatureAlgorithmsCertExtension{SupportedSignatureAlgorithms: signatureSchemes(e.SignatureAlgorithms)}, nil
+	case ExtensionTypeDelegatedCredentials:
+		return &tls.DelegatedCredentialsExtension{SupportedSignatureAlgorithms: signatureSchemes(e.SignatureAlgorithms)}, nil
+	case ExtensionTypeALPN:
+		return &tls.ALPNExtension{AlpnProtocols: append([]string{}, e.Protocols...)}, nil
+	case ExtensionTypeApplicationSettings:
+		return &tls.ApplicationSettingsExtension{SupportedProtocols: append([]string{}, e.Protocols...)}, nil
+	case ExtensionTypeApplicationSettingsNew:
+		return &tls.ApplicationSettingsExtensionNew{SupportedProtocols: append([]string{}, e.Protocols...)}, nil
+	case ExtensionTypeNPN:
+		return &tls.NPNExtension{NextProtos: append([]string{}, e.Protocols...)}, nil
+	case ExtensionTypeSCT:
+		return &tls.SCTExtension{}, nil
+	case ExtensionTypePadding:
+		padding := &tls.UtlsPaddingExtension{PaddingLen: e.PaddingLength, WillPad: e.PaddingLength > 0}
+
+		switch e.Padding {
+		case "":
+		case paddingStyleBoring:
+			padding.GetPaddingLen = tls.BoringPaddingStyle
+		default:
+			return nil, fmt.Errorf("unknown padding style %s", e.Padding)
+		}
+
+		return padding, nil
+	case ExtensionTypeExtendedMasterSecret:
+		return &tls.ExtendedMasterSecretExtension{}, nil
+	case ExtensionTypeSessionTicket:
+		return &tls.SessionTicketExtension{}, nil
+	case ExtensionTypePreSharedKey:
+		return &tls.UtlsPreSharedKeyExtension{}, nil
+	case ExtensionTypeSupportedVersions:
+		return &tls.SupportedVersionsExtension{Versions: append([]uint16{}, e.Versions...)}, nil
+	case ExtensionTypePSKKeyExchangeModes:
+		modes, err := uint8s("modes", e.Modes)
+		if err != nil {
+			return nil, err
+		}
+
+		return &tls.PSKKeyExchangeModesExtension{Modes: modes}, nil
+	case ExtensionTypeKeyShare:
+		keyShares := make([]tls.KeyShare, 0, len(e.KeyShares))
+		for _, keyShare := range e.KeyShares {
+			keyShares = append(keyShares, tls.KeyShare{Group: tls.CurveID(keyShare.Group), Data: append([]byte(nil), keyShare.Data...)})
+		}
+
+		return &tls.KeyShareExtension{KeyShares: keyShares}, nil
+	case ExtensionTypeCompressCertificate:
+		algorithms := make([]tls.CertCompressionAlgo, 0, len(e.Algorithms))
+		for _, algorithm := range e.Algorithms {
+			algorithms = append(algorithms, tls.CertCompressionAlgo(algorithm))
+		}
+
+		return &tls.UtlsCompressCertExtension{Algorithms: algorithms}, nil
+	case ExtensionTypeRecordSizeLimit:
+		return &tls.FakeRecordSizeLimitExtension{Limit: e.Limit}, nil
+	case ExtensionTypeRenegotiationInfo:
+		return &tls.RenegotiationInfoExtension{Renegotiation: tls.RenegotiationSupport(e.Renegotiation), RenegotiatedConnection: e.Data}, nil
+	case ExtensionTypeGREASEECH:
+		configIds, err := uint8s("config ids", e.ConfigIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		ech := &tls.GREASEEncryptedClientHelloExtension{
+			CandidateConfigIds:   configIds,
+			EncapsulatedKey:      append([]byte(nil), e.Data...),
+			CandidatePayloadLens: append([]uint16(nil), e.PayloadLengths...),
+		}
+
+		for _, cipherSuite := range e.CipherSuites {
+			ech.CandidateCipherSuites = append(ech.CandidateCipherSuites, tls.HPKESymmetricCipherSuite{KdfId: cipherSuite.KdfID, AeadId: cipherSuite.AeadID})
+		}
+
+		return ech, nil
+	case ExtensionTypeCookie:
+		return &tls.CookieExtension{Cookie: e.Data}, nil
+	case ExtensionTypeChannelID:
+		return &tls.FakeChannelIDExtension{OldExtensionID: e.OldID}, nil
+	case ExtensionTypeGeneric:
+		return &tls.GenericExtension{Id: e.ID, Data: e.Data}, nil
+	default:
+		return nil, fmt.Errorf("unknown extension type %q", e.Type)
+	}
+}
+
+// exportExtension returns the profile file extension of extension.
+func exportExtension(extension tls.TLSExtension) (ProfileExtension, error) {
+	switch ext := extension.(type) {
+	case *tls.UtlsGREASEExtension:
+		return ProfileExtension{Type: ExtensionTypeGREASE, Value: ext.Value, Data: ext.Body}, nil
+	case *tls.SNIExtension:
+		return ProfileExtension{Type: ExtensionTypeServerName, ServerName: ext.ServerName}, nil
+	case *tls.StatusRequestExtension:
+		return ProfileExtension{Type: ExtensionTypeStatusRequest}, nil
+	case *tls.SupportedCurvesExtension:
+		curves := make([]uint16, 0, len(ext.Curves))
+		for _, curve := range ext.Curves {
+			curves = append(curves, uint16(curve))
+		}
+
+		return ProfileExtension{Type: ExtensionTypeSupportedCurves, Curves: curves}, nil
+	case *tls.SupportedPointsExtension:
+		return ProfileExtension{Type: ExtensionTypeSupportedPoints, Points: uint16s(ext.SupportedPoints)}, nil
+	case *tls.SignatureAlgorithmsExtension:
+		return ProfileExtension{Type: ExtensionTypeSignatureAlgorithms, SignatureAlgorithms: exportSignatureSchemes(ext.SupportedSignatureAlgorithms)}, nil
+	case *tls.SignatureAlgorithmsCertExtension:
+		return ProfileExtension{Type: ExtensionTypeSignatureAlgorithmsCert, SignatureAlgorithms: exportSignatureSchemes(ext.SupportedSignatureAlgorithms)}, nil
+	case *tls.DelegatedCredentialsExtension:
+		return ProfileExtension{Type: ExtensionTypeDelegatedCredentials, SignatureAlgorithms: exportSignatureSchemes(ext.SupportedSignatureAlgorithms)}, nil
+	case *tls.ALPNExtension:
+		return ProfileExtension{Type: ExtensionTypeALPN, Protocols: ext.AlpnProtocols}, nil
+	case *tls.ApplicationSettingsExtension:
+		return ProfileExtension{Type: ExtensionTypeApplicationSettings, Protocols: ext.SupportedProtocols}, nil
+	case *tls.ApplicationSettingsExtensionNew:
+		return ProfileExtension{Type: ExtensionTypeApplicationSettingsNew, Protocols: ext.SupportedProtocols}, nil
+	case *tls.NPNExtension:
+		return ProfileExtension{Type: ExtensionTypeNPN, Protocols: ext.NextProtos}, nil
+	case *tls.SCTExtension:
+		return ProfileExtension{Type: ExtensionTypeSCT}, nil
+	case *tls.UtlsPaddingExtension:
+		padding := ProfileExtension{Type: ExtensionTypePadding, PaddingLength: ext.PaddingLen}
+
+		if ext.WillPad != (ext.PaddingLen > 0) {
+			return ProfileExtension{}, errors.New("padding extension with inconsistent length")
+		}
+
+		if ext.GetPaddingLen != nil {
+			if !sameFunc(ext.GetPaddingLen, tls.BoringPaddingStyle) {
+				return ProfileExtension{}, errors.New("unsupported padding style")
+			}
+
+			padding.Padding = paddingStyleBoring
+		}
+
+		return padding, nil
+	case *tls.ExtendedMasterSecretExtension:
+		return ProfileExtension{Type: ExtensionTypeExtendedMasterSecret}, nil
+	case *tls.SessionTicketExtension:
+		if ext.Session != nil || len(ext.Ticket) > 0 || ext.Initialized {
+			return ProfileExtension{}, errors.New("session ticket extension with a session")
+		}
+
+		return ProfileExtension{Type: ExtensionTypeSessionTicket}, nil
+	case *tls.UtlsPreSharedKeyExtension:
+		if ext.Session != nil || len(ext.Identities) > 0 || ext.OmitEmptyPsk {
+			return ProfileExtension{}, errors.New("pre-shared key extension with a session")
+		}
+
+		return ProfileExtension{Type: ExtensionTypePreSharedKey}, nil
+	case *tls.SupportedVersionsExtension:
+		return ProfileExtension{Type: ExtensionTypeSupportedVersions, Versions: ext.Versions}, nil
+	case *tls.PSKKeyExchangeModesExtension:
+		return ProfileExtension{Type: ExtensionTypePSKKeyExchangeModes, Modes: uint16s(ext.Modes)}, nil
+	case *tls.KeyShareExtension:
+		keyShares := make([]ProfileKeyShare, 0, len(ext.KeyShares))
+		for _, keyShare := range ext.KeyShares {
+			keyShares = append(keyShares, ProfileKeyShare{Group: uint16(keyShare.Group), Data: keyShare.Data})
+		}
+
+		return ProfileExtension{Type: ExtensionTypeKeyShare, KeyShares: keyShares}, nil
+	case *tls.UtlsCompressCertExtension:
+		algorithms := make([]uint16, 0, len(ext.Algorithms))
+		for _, algorithm := range ext.Algorithms {
+			algorithms = append(algorithms, uint16(algorithm))
+		}
+
+		return ProfileExtension{Type: ExtensionTypeCompressCertificate, Algorithms: algorithms}, nil
+	case *tls.FakeRecordSizeLimitExtension:
+		return ProfileExtension{Type: ExtensionTypeRecordSizeLimit, Limit: ext.Limit}, nil
+	case *tls.RenegotiationInfoExtension:
+		return ProfileExtension{Type: ExtensionTypeRenegotiationInfo, Renegotiation: int(ext.Renegotiation), Data: ext.RenegotiatedConnection}, nil
+	case *tls.GREASEEncryptedClientHelloExtension:
+		ech := ProfileExtension{
+			Type:           ExtensionTypeGREASEECH,
+			ConfigIDs:      uint16s(ext.CandidateConfigIds),
+			Data:           ext.EncapsulatedKey,
+			PayloadLengths: ext.CandidatePayloadLens,
+		}
+
+		for _, cipherSuite := range ext.CandidateCipherSuites {
+			ech.CipherSuites = append(ech.CipherSuites, ProfileHPKECipherSuite{KdfID: cipherSuite.KdfId, AeadID: cipherSuite.AeadId})
+		}
+
+		return ech, nil
+	case *tls.CookieExtension:
+		return ProfileExtension{Type: ExtensionTypeCookie, Data: ext.Cookie}, nil
+	case *tls.FakeChannelIDExtension:
+		return ProfileExtension{Type: ExtensionTypeChannelID, OldID: ext.OldExtensionID}, nil
+	case *tls.GenericExtension:
+		return ProfileExtension{Type: ExtensionTypeGeneric, ID: ext.Id, Data: ext.Data}, nil
+	default:
+		return ProfileExtension{}, fmt.Errorf("unsupported extension %T", extension)
+	}
+}
+
+func signatureSchemes(values []uint16) []tls.SignatureScheme {
+	schemes := make([]tls.SignatureScheme, 0, len(values))
+	for _, value := range values {
+		schemes = append(schemes, tls.SignatureScheme(value))
+	}
+
+	return schemes
+}
+
+func exportSignatureSchemes(schemes []tls.SignatureScheme) []uint16 {
+	values := make([]uint16, 0, len(schemes))
+	for _, scheme := range schemes {
+		values = append(values, uint16(scheme))
+	}
+
+	return values
+}
+
+// uint8s returns the values of a field of single bytes, which the file lists as numbers.
+func uint8s(field string, values []uint16) ([]uint8, error) {
+	if values == nil {
+		return nil, nil
+	}
+
+	result := make([]uint8, 0, len(values))
+	for _, value := range values {
+		if value > 0xff {
+			return nil, fmt.Errorf("%d is not a valid value of the %s", value, field)
+		}
+
+		result = append(result, uint8(value))
+	}
+
+	return result, nil
+}
+
+func uint16s(values8 []uint8) []uint16 {
+	if values8 == nil {
+		return nil
+	}
+
+	values := make([]uint16, 0, len(values8))
+	for _, b := range values8 {
+		values = append(values, uint16(b))
+	}
+
+	return values
+}
+
+// sameFunc reports whether the functions a and b are the same function.
+func sameFunc(a any, b any) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
@@ -1,6 +1,7 @@
 package profiles
 
 import (
+	http "github.com/bogdanfinn/fhttp"
 	"github.com/bogdanfinn/fhttp/http2"
 	tls "github.com/bogdanfinn/utls"
 )
@@ -86,9 +87,12 @@ type ClientProfile struct {
 	pseudoHeaderOrder []string
 	settingsOrder     []http2.SettingID
 	connectionFlow    uint32
+	quicProfile       *QUICProfile
+	headers           map[RequestKind]http.Header
 }
 
-func NewClientProfile(clientHelloId tls.ClientHelloID, settings map[http2.SettingID]uint32, settingsOrder []http2.SettingID, pseudoHeaderOrder []string, connectionFlow uint32, priorities []http2.Priority, headerPriority *http2.PriorityParam) ClientProfile {
+// NewClientProfile creates a client profile. quicProfile may be nil for clients which do not speak HTTP/3.
+func NewClientProfile(clientHelloId tls.ClientHelloID, settings map[http2.SettingID]uint32, settingsOrder []http2.SettingID, pseudoHeaderOrder []string, connectionFlow uint32, priorities []http2.Priority, headerPriority *http2.PriorityParam, quicProfile *QUICProfile) ClientProfile {
 	return ClientProfile{
 		clientHelloId:     clientHelloId,
 		settings:          settings,
@@ -97,6 +101,7 @@ func NewClientProfile(clientHelloId tls.ClientHelloID, settings map[http2.Settin
 		connectionFlow:    connectionFlow,
 		priorities:        priorities,
 		headerPriority:    headerPriority,
+		quicProfile:       quicProfile,
 	}
 }
 
@@ -135,3 +140,8 @@ func (c ClientProfile) GetClientHelloId() tls.ClientHelloID {
 func (c ClientProfile) GetPriorities() []http2.Priority {
 	return c.priorities
 }
+
+// GetQUICProfile returns the QUIC and HTTP/3 description of the profile or nil if none is defined.
+func (c ClientProfile) GetQUICProfile() *QUICProfile {
+	return c.quicProfile
+}
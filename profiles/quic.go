@@ -0,0 +1,113 @@
+package profiles
+
+import (
+	"errors"
+	"fmt"
+)
+
+// QUICTransportParameterID identifies a QUIC transport parameter (RFC 9000, section 18.2).
+type QUICTransportParameterID uint64
+
+const (
+	QUICTransportParameterMaxIdleTimeout                 QUICTransportParameterID = 0x01
+	QUICTransportParameterInitialMaxData                 QUICTransportParameterID = 0x04
+	QUICTransportParameterInitialMaxStreamDataBidiLocal  QUICTransportParameterID = 0x05
+	QUICTransportParameterInitialMaxStreamDataBidiRemote QUICTransportParameterID = 0x06
+	QUICTransportParameterInitialMaxStreamDataUni        QUICTransportParameterID = 0x07
+	QUICTransportParameterInitialMaxStreamsBidi          QUICTransportParameterID = 0x08
+	QUICTransportParameterInitialMaxStreamsUni           QUICTransportParameterID = 0x09
+)
+
+// quicStreamWindowParameters are the transport parameters of the stream flow control windows, which the QUIC stack
+// sends with a single value.
+var quicStreamWindowParameters = []QUICTransportParameterID{
+	QUICTransportParameterInitialMaxStreamDataBidiLocal,
+	QUICTransportParameterInitialMaxStreamDataBidiRemote,
+	QUICTransportParameterInitialMaxStreamDataUni,
+}
+
+// H3SettingID identifies a setting of the HTTP/3 SETTINGS frame (RFC 9114, section 7.2.4.1).
+type H3SettingID uint64
+
+const (
+	H3SettingQpackMaxTableCapacity H3SettingID = 0x01
+	H3SettingMaxFieldSectionSize   H3SettingID = 0x06
+	H3SettingQpackBlockedStreams   H3SettingID = 0x07
+	H3SettingEnableConnectProtocol H3SettingID = 0x08
+	H3SettingH3Datagram            H3SettingID = 0x33
+)
+
+// QUICProfile describes the QUIC transport and HTTP/3 layer of a client profile.
+//
+// Only the values the QUIC stack applies can be described: the initial packet size, the connection ID length, the
+// transport parameters declared above, datagram support and the HTTP/3 settings values. The QUIC stack sends the
+// other transport parameters with values of its own, the transport parameters and the HTTP/3 settings in an order of
+// its own, and a random reserved transport parameter rather than grease_quic_bit. Validate reports the profiles
+// describing anything else.
+type QUICProfile struct {
+	// TransportParameters holds the values of the transport parameters sent in the ClientHello. The stream data
+	// parameters must be equal, as the QUIC stack uses a single stream window.
+	TransportParameters map[QUICTransportParameterID]uint64
+	// H3Settings holds the values sent in the HTTP/3 SETTINGS frame, H3_DATAGRAM excluded (see H3Datagram).
+	H3Settings map[H3SettingID]uint64
+	// InitialPacketSize is the size the Initial packets are padded to. Zero means the QUIC stack default.
+	InitialPacketSize uint16
+	// ConnectionIDLength is the length of the source connection IDs. Zero means the QUIC stack default.
+	ConnectionIDLength int
+	// H3Datagram enables HTTP/3 datagrams (RFC 9297), which announces the H3_DATAGRAM setting and the
+	// max_datagram_frame_size transport parameter of the QUIC stack.
+	H3Datagram bool
+}
+
+// GetTransportParameter returns the value of the given transport parameter and whether it is set.
+func (q *QUICProfile) GetTransportParameter(id QUICTransportParameterID) (uint64, bool) {
+	if q == nil || q.TransportParameters == nil {
+		return 0, false
+	}
+
+	value, ok := q.TransportParameters[id]
+
+	return value, ok
+}
+
+// StreamWindow returns the value of the stream data transport parameters and whether one of them is set.
+func (q *QUICProfile) StreamWindow() (uint64, bool) {
+	for _, id := range quicStreamWindowParameters {
+		if value, ok := q.GetTransportParameter(id); ok {
+			return value, true
+		}
+	}
+
+	return 0, false
+}
+
+// Validate returns an error if the profile describes transport parameters the QUIC stack does not send as described.
+func (q *QUICProfile) Validate() error {
+	if q == nil {
+		return nil
+	}
+
+	for id := range q.TransportParameters {
+		switch id {
+		case QUICTransportParameterMaxIdleTimeout, QUICTransportParameterInitialMaxData,
+			QUICTransportParameterInitialMaxStreamDataBidiLocal, QUICTransportParameterInitialMaxStreamDataBidiRemote,
+			QUICTransportParameterInitialMaxStreamDataUni, QUICTransportParameterInitialMaxStreamsBidi,
+			QUICTransportParameterInitialMaxStreamsUni:
+		default:
+			return fmt.Errorf("unsupported QUIC transport parameter %#x", uint64(id))
+		}
+	}
+
+	window, _ := q.StreamWindow()
+	for _, id := range quicStreamWindowParameters {
+		if value, ok := q.TransportParameters[id]; ok && value != window {
+			return fmt.Errorf("QUIC transport parameter %#x differs from the other stream data parameters", uint64(id))
+		}
+	}
+
+	if _, ok := q.H3Settings[H3SettingH3Datagram]; ok {
+		return errors.New("the H3_DATAGRAM setting is announced through H3Datagram")
+	}
+
+	return nil
+}
@@ -0,0 +1,365 @@
+package profiles
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	http "github.com/bogdanfinn/fhttp"
+)
+
+// Platform is an operating system a client runs on.
+type Platform string
+
+const (
+	PlatformWindows Platform = "Windows"
+	PlatformMacOS   Platform = "macOS"
+	PlatformLinux   Platform = "Linux"
+	PlatformAndroid Platform = "Android"
+	PlatformIOS     Platform = "iOS"
+)
+
+var (
+	ErrUnsupportedClient   = errors.New("no user agent is known for this client profile")
+	ErrUnsupportedPlatform = errors.New("the client of the profile does not run on this platform")
+)
+
+// The client hints sent by Chrome on every request, whether the server asked for them or not.
+var lowEntropyClientHints = []string{"sec-ch-ua", "sec-ch-ua-mobile", "sec-ch-ua-platform"}
+
+// Brand is a brand and version pair of a brand list, as sent in sec-ch-ua and sec-ch-ua-full-version-list.
+type Brand struct {
+	Name    string
+	Version string
+}
+
+// UserAgent is a coherent user agent string and set of client hints of the client of a profile running on a platform.
+// It is created with NewUserAgent and its fields may be adjusted afterward, e.g. to change the model of a phone.
+type UserAgent struct {
+	UserAgent string
+	Platform  Platform
+	// Brands is the brand list of sec-ch-ua with major versions, nil for clients which do not send client hints.
+	Brands []Brand
+	// FullVersionList is the brand list of sec-ch-ua-full-version-list with full versions.
+	FullVersionList []Brand
+	FullVersion     string
+	PlatformVersion string
+	Architecture    string
+	Bitness         string
+	Model           string
+	FormFactors     []string
+	Mobile          bool
+	Wow64           bool
+}
+
+type userAgentPlatform struct {
+	chromeUserAgent  string
+	firefoxUserAgent string
+	platformVersion  string
+	architecture     string
+	bitness          string
+	formFactor       string
+	mobile           bool
+}
+
+// userAgentPlatforms holds the platform dependent parts of the user agents, as sent by the reduced user agent of Chrome.
+var userAgentPlatforms = map[Platform]userAgentPlatform{
+	PlatformWindows: {
+		chromeUserAgent:  "Windows NT 10.0; Win64; x64",
+		firefoxUserAgent: "Windows NT 10.0; Win64; x64; rv:%s.0",
+		platformVersion:  "19.0.0",
+		architecture:     "x86",
+		bitness:          "64",
+		formFactor:       "Desktop",
+	},
+	PlatformMacOS: {
+		chromeUserAgent:  "Macintosh; Intel Mac OS X 10_15_7",
+		firefoxUserAgent: "Macintosh; Intel Mac OS X 10.15; rv:%s.0",
+		platformVersion:  "15.3.0",
+		architecture:     "arm",
+		bitness:          "64",
+		formFactor:       "Desktop",
+	},
+	PlatformLinux: {
+		chromeUserAgent:  "X11; Linux x86_64",
+		firefoxUserAgent: "X11; Linux x86_64; rv:%s.0",
+		platformVersion:  "6.8.0",
+		architecture:     "x86",
+		bitness:          "64",
+		formFactor:       "Desktop",
+	},
+	PlatformAndroid: {
+		chromeUserAgent:  "Linux; Android 10; K",
+		firefoxUserAgent: "Android 14; Mobile; rv:%s.0",
+		platformVersion:  "14.0.0",
+		formFactor:       "Mobile",
+		mobile:           true,
+	},
+	PlatformIOS: {
+		formFactor: "Mobile",
+		mobile:     true,
+	},
+}
+
+// chromeFullVersions are the last stable full versions of the Chrome versions of the profiles.
+var chromeFullVersions = map[int]string{
+	103: "103.0.5060.134",
+	104: "104.0.5112.102",
+	105: "105.0.5195.127",
+	106: "106.0.5249.119",
+	107: "107.0.5304.107",
+	108: "108.0.5359.125",
+	109: "109.0.5414.120",
+	110: "110.0.5481.178",
+	111: "111.0.5563.147",
+	112: "112.0.5615.138",
+	116: "116.0.5845.188",
+	117: "117.0.5938.150",
+	120: "120.0.6099.225",
+	124: "124.0.6367.208",
+	130: "130.0.6723.117",
+	131: "131.0.6778.265",
+	133: "133.0.6943.142",
+}
+
+// iOSVersion is the iOS version of the user agents of the clients which are not Safari on iOS.
+const iOSVersion = "18_5"
+
+// NewUserAgent returns the user agent and client hints of the client of profile running on platform.
+// Chrome, Firefox, Safari and OkHttp profiles are supported. Only Chrome sends client hints, except on iOS where it
+// is built on WebKit.
+func NewUserAgent(profile ClientProfile, platform Platform) (*UserAgent, error) {
+	platformInfo, ok := userAgentPlatforms[platform]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedPlatform, platform)
+	}
+
+	client := profile.clientHelloId.Client
+	version := profile.clientHelloId.Version
+
+	switch {
+	case client == "Chrome":
+		return newChromeUserAgent(version, platform, platformInfo)
+	case client == "Firefox":
+		return newFirefoxUserAgent(version, platform, platformInfo), nil
+	case client == "Safari" || client == "iOS" || client == "iPad":
+		return newSafariUserAgent(version, platform)
+	case strings.HasPrefix(client, "OkHttp"):
+		return &UserAgent{UserAgent: "okhttp/" + version, Platform: platform, Mobile: platformInfo.mobile}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedClient, profile.clientHelloId.Str())
+	}
+}
+
+func newChromeUserAgent(version string, platform Platform, platformInfo userAgentPlatform) (*UserAgent, error) {
+	major, err := majorVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	fullVersion, ok := chromeFullVersions[major]
+	if !ok {
+		fullVersion = fmt.Sprintf("%d.0.0.0", major)
+	}
+
+	if platform == PlatformIOS {
+		return &UserAgent{
+			UserAgent: fmt.Sprintf("Mozilla/5.0 (iPhone; CPU iPhone OS %s like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/%s Mobile/15E148 Safari/604.1", iOSVersion, fullVersion),
+			Platform:  platform,
+			Mobile:    true,
+		}, nil
+	}
+
+	mobile := ""
+	if platformInfo.mobile {
+		mobile = "Mobile "
+	}
+
+	return &UserAgent{
+		UserAgent:       fmt.Sprintf("Mozilla/5.0 (%s) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%d.0.0.0 %sSafari/537.36", platformInfo.chromeUserAgent, major, mobile),
+		Platform:        platform,
+		Brands:          greasedBrands(major, strconv.Itoa(major), false),
+		FullVersionList: greasedBrands(major, fullVersion, true),
+		FullVersion:     fullVersion,
+		PlatformVersion: platformInfo.platformVersion,
+		Architecture:    platformInfo.architecture,
+		Bitness:         platformInfo.bitness,
+		FormFactors:     []string{platformInfo.formFactor},
+		Mobile:          platformInfo.mobile,
+	}, nil
+}
+
+func newFirefoxUserAgent(version string, platform Platform, platformInfo userAgentPlatform) *UserAgent {
+	if platform == PlatformIOS {
+		return &UserAgent{
+			UserAgent: fmt.Sprintf("Mozilla/5.0 (iPhone; CPU iPhone OS %s like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) FxiOS/%s.0 Mobile/15E148 Safari/605.1.15", iOSVersion, version),
+			Platform:  platform,
+			Mobile:    true,
+		}
+	}
+
+	gecko := "20100101"
+	if platform == PlatformAndroid {
+		// Firefox for Android sends its version as Gecko version
+		gecko = version + ".0"
+	}
+
+	return &UserAgent{
+		UserAgent: fmt.Sprintf("Mozilla/5.0 (%s) Gecko/%s Firefox/%s.0", fmt.Sprintf(platformInfo.firefoxUserAgent, version), gecko, version),
+		Platform:  platform,
+		Mobile:    platformInfo.mobile,
+	}
+}
+
+func newSafariUserAgent(version string, platform Platform) (*UserAgent, error) {
+	if !strings.Contains(version, ".") {
+		version += ".0"
+	}
+
+	switch platform {
+	case PlatformIOS:
+		return &UserAgent{
+			UserAgent: fmt.Sprintf("Mozilla/5.0 (iPhone; CPU iPhone OS %s like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/%s Mobile/15E148 Safari/604.1", strings.ReplaceAll(version, ".", "_"), version),
+			Platform:  platform,
+			Mobile:    true,
+		}, nil
+	case PlatformMacOS:
+		return &UserAgent{
+			UserAgent: fmt.Sprintf("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/%s Safari/605.1.15", version),
+			Platform:  platform,
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: Safari on %s", ErrUnsupportedPlatform, platform)
+	}
+}
+
+// majorVersion parses the major version of a profile version such as "133" or "18.5".
+func majorVersion(version string) (int, error) {
+	major, _, _ := strings.Cut(version, ".")
+
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid version %q", ErrUnsupportedClient, version)
+	}
+
+	return n, nil
+}
+
+// greasedBrands returns the brand list of Chrome, which contains a GREASE brand whose name, version and position
+// are derived from the major version, like Chromium's GenerateBrandVersionList does.
+func greasedBrands(major int, version string, fullVersion bool) []Brand {
+	greaseyChars := []string{" ", "(", ":", "-", ".", "/", ")", ";", "=", "?", "_"}
+	greasedVersions := []string{"8", "99", "24"}
+	orders := [][3]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}}
+
+	grease := Brand{
+		Name:    "Not" + greaseyChars[major%len(greaseyChars)] + "A" + greaseyChars[(major+1)%len(greaseyChars)] + "Brand",
+		Version: greasedVersions[major%len(greasedVersions)],
+	}
+
+	if fullVersion {
+		grease.Version += ".0.0.0"
+	}
+
+	order := orders[major%len(orders)]
+
+	brands := make([]Brand, 3)
+	brands[order[0]] = grease
+	brands[order[1]] = Brand{Name: "Chromium", Version: version}
+	brands[order[2]] = Brand{Name: "Google Chrome", Version: version}
+
+	return brands
+}
+
+// SupportsClientHints reports whether the client sends client hints.
+func (u *UserAgent) SupportsClientHints() bool {
+	return len(u.Brands) > 0
+}
+
+// Hint returns the value of the given client hint, e.g. "sec-ch-ua-platform", formatted as a structured header.
+// It returns false for unknown hints and if the client does not send client hints.
+func (u *UserAgent) Hint(name string) (string, bool) {
+	if !u.SupportsClientHints() {
+		return "", false
+	}
+
+	switch strings.ToLower(name) {
+	case "sec-ch-ua":
+		return formatBrands(u.Brands), true
+	case "sec-ch-ua-full-version-list":
+		return formatBrands(u.FullVersionList), true
+	case "sec-ch-ua-full-version":
+		return strconv.Quote(u.FullVersion), true
+	case "sec-ch-ua-mobile":
+		return structuredBool(u.Mobile), true
+	case "sec-ch-ua-platform":
+		return strconv.Quote(string(u.Platform)), true
+	case "sec-ch-ua-platform-version":
+		return strconv.Quote(u.PlatformVersion), true
+	case "sec-ch-ua-arch":
+		return strconv.Quote(u.Architecture), true
+	case "sec-ch-ua-bitness":
+		return strconv.Quote(u.Bitness), true
+	case "sec-ch-ua-model":
+		return strconv.Quote(u.Model), true
+	case "sec-ch-ua-wow64":
+		return structuredBool(u.Wow64), true
+	case "sec-ch-ua-form-factors":
+		formFactors := make([]string, len(u.FormFactors))
+		for i, formFactor := range u.FormFactors {
+			formFactors[i] = strconv.Quote(formFactor)
+		}
+
+		return strings.Join(formFactors, ", "), true
+	default:
+		return "", false
+	}
+}
+
+// Headers returns the user-agent header and the client hints sent on every request, followed by the given high
+// entropy hints, typically the ones requested by the server through Accept-CH. Unknown hints are ignored.
+// Header names are lowercase.
+func (u *UserAgent) Headers(hints ...string) http.Header {
+	headers := http.Header{"user-agent": {u.UserAgent}}
+
+	for _, names := range [][]string{lowEntropyClientHints, hints} {
+		for _, name := range names {
+			if value, ok := u.Hint(name); ok {
+				headers[strings.ToLower(name)] = []string{value}
+			}
+		}
+	}
+
+	return headers
+}
+
+// ParseAcceptCH parses the list of client hints of an Accept-CH or Critical-CH response header. Hint names are lowercase.
+func ParseAcceptCH(value string) []string {
+	var hints []string
+
+	for _, hint := range strings.Split(value, ",") {
+		if hint = strings.ToLower(strings.TrimSpace(hint)); hint != "" {
+			hints = append(hints, hint)
+		}
+	}
+
+	return hints
+}
+
+func formatBrands(brands []Brand) string {
+	formatted := make([]string, len(brands))
+	for i, brand := range brands {
+		formatted[i] = fmt.Sprintf("%s;v=%s", strconv.Quote(brand.Name), strconv.Quote(brand.Version))
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
+func structuredBool(b bool) string {
+	if b {
+		return "?1"
+	}
+
+	return "?0"
+}
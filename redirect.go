@@ -0,0 +1,173 @@
+package httpkit
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	http "github.com/bogdanfinn/fhttp"
+)
+
+// ErrTooManyRedirects is returned, wrapped, when a request is redirected more times than allowed by its RedirectPolicy.
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// The headers describing the body of a request, removed when a redirect changes the method to GET.
+var requestBodyHeaders = []string{"Content-Type", "Content-Length", "Content-Encoding", "Content-Language", "Content-Location"}
+
+// RedirectPolicy configures how the redirects of a request are followed. It is installed with WithRedirectPolicy or
+// WithRequestRedirectPolicy, or used through its CheckRedirect method wherever a redirect func is accepted.
+//
+// Every hop keeps the header order of the original request. The intermediate responses of the redirect chain are
+// available from the final response through RedirectHistory.
+type RedirectPolicy struct {
+	// RewriteMethod returns the method of the hop following a 301, 302 or 303 redirect of a request with the given
+	// method. The body of the request is only sent again if the method is kept, which requires http.Request.GetBody:
+	// otherwise the redirect response is returned. BrowserRedirectMethod is used if nil.
+	RewriteMethod func(statusCode int, method string) string
+	// OnRedirect is called for every hop followed, after the checks of the policy, like http.Client.CheckRedirect.
+	OnRedirect func(req *http.Request, via []*http.Request) error
+	// CrossOriginHeaders are the headers of the original request which are not sent to another origin.
+	CrossOriginHeaders []string
+	// MaxRedirects is the maximum number of redirects followed, 10 if zero. Beyond, the request fails with
+	// ErrTooManyRedirects.
+	MaxRedirects int
+	// SameOriginOnly stops at the first redirect to another origin than the one of the original request, whose
+	// response is returned.
+	SameOriginOnly bool
+}
+
+// DefaultRedirectPolicy returns a redirect policy following up to 10 redirects like a browser, which does not send the
+// Authorization and Cookie headers set on the request to other origins.
+func DefaultRedirectPolicy() RedirectPolicy {
+	return RedirectPolicy{
+		MaxRedirects:       10,
+		CrossOriginHeaders: []string{"Authorization", "Cookie"},
+	}
+}
+
+// BrowserRedirectMethod rewrites the method of a redirected request like browsers do: POST becomes GET on 301 and 302
+// redirects and every method but GET and HEAD becomes GET on 303 redirects.
+func BrowserRedirectMethod(statusCode int, method string) string {
+	switch {
+	case statusCode == http.StatusSeeOther && method != http.MethodGet && method != http.MethodHead:
+		return http.MethodGet
+	case (statusCode == http.StatusMovedPermanently || statusCode == http.StatusFound) && method == http.MethodPost:
+		return http.MethodGet
+	default:
+		return method
+	}
+}
+
+// CheckRedirect applies the policy to the next hop req of the redirect chain via. It has the signature of
+// http.Client.CheckRedirect.
+func (p RedirectPolicy) CheckRedirect(req *http.Request, via []*http.Request) error {
+	maxRedirects := p.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = 10
+	}
+
+	if len(via) > maxRedirects {
+		return fmt.Errorf("%w: stopped after %d redirects", ErrTooManyRedirects, maxRedirects)
+	}
+
+	initial := via[0]
+	previous := via[len(via)-1]
+	crossOrigin := urlOrigin(req.URL) != urlOrigin(initial.URL)
+
+	if p.SameOriginOnly && crossOrigin {
+		return http.ErrUseLastResponse
+	}
+
+	if err := p.rewriteMethod(req, previous.Method, initial); err != nil {
+		return err
+	}
+
+	if crossOrigin {
+		for _, name := range p.CrossOriginHeaders {
+			deleteHeader(req.Header, name)
+		}
+	}
+
+	for _, key := range []string{http.HeaderOrderKey, http.PHeaderOrderKey} {
+		if order, ok := initial.Header[key]; ok {
+			req.Header[key] = append([]string(nil), order...)
+		}
+	}
+
+	if p.OnRedirect != nil {
+		return p.OnRedirect(req, via)
+	}
+
+	return nil
+}
+
+// rewriteMethod sets the method and body of the hop req following a redirect of a request with the given method,
+// replacing the ones chosen by the client. The body of the initial request is only sent while its method is kept.
+func (p RedirectPolicy) rewriteMethod(req *http.Request, method string, initial *http.Request) error {
+	rewriteMethod := p.RewriteMethod
+	if rewriteMethod == nil {
+		rewriteMethod = BrowserRedirectMethod
+	}
+
+	statusCode := 0
+	if req.Response != nil {
+		statusCode = req.Response.StatusCode
+	}
+
+	if statusCode == http.StatusMovedPermanently || statusCode == http.StatusFound || statusCode == http.StatusSeeOther {
+		method = rewriteMethod(statusCode, method)
+	}
+
+	req.Method = method
+
+	if method != initial.Method {
+		req.Body = nil
+		req.GetBody = nil
+		req.ContentLength = 0
+
+		for _, name := range requestBodyHeaders {
+			deleteHeader(req.Header, name)
+		}
+
+		return nil
+	}
+
+	if initial.Body == nil || initial.Body == http.NoBody || req.Body != nil {
+		return nil
+	}
+
+	if initial.GetBody == nil {
+		return http.ErrUseLastResponse
+	}
+
+	body, err := initial.GetBody()
+	if err != nil {
+		return err
+	}
+
+	req.Body = body
+	req.GetBody = initial.GetBody
+	req.ContentLength = initial.ContentLength
+
+	return nil
+}
+
+// RedirectHistory returns the responses of the redirects which led to resp, from the first one. Their body is closed.
+func RedirectHistory(resp *http.Response) []*http.Response {
+	var history []*http.Response
+
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		history = append([]*http.Response{req.Response}, history...)
+	}
+
+	return history
+}
+
+// deleteHeader removes the header key, its name being compared case-insensitively.
+func deleteHeader(header http.Header, key string) {
+	for name := range header {
+		if strings.EqualFold(name, key) {
+			delete(header, name)
+		}
+	}
+}
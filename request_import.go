@@ -0,0 +1,647 @@
+package httpkit
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	http "github.com/bogdanfinn/fhttp"
+)
+
+// The curl options taking a value which are ignored when importing a curl command, as they do not change the request.
+var ignoredCurlOptions = map[string]bool{
+	"-o": true, "--output": true, "--output-dir": true, "-D": true, "--dump-header": true, "-w": true, "--write-out": true,
+	"--stderr": true, "--trace": true, "--trace-ascii": true, "-c": true, "--cookie-jar": true,
+	"-x": true, "--proxy": true, "-U": true, "--proxy-user": true, "--noproxy": true, "--preproxy": true,
+	"--socks4": true, "--socks4a": true, "--socks5": true, "--socks5-hostname": true,
+	"--proxy-cacert": true, "--proxy-cert": true, "--proxy-key": true,
+	"-m": true, "--max-time": true, "--connect-timeout": true, "--expect100-timeout": true,
+	"--happy-eyeballs-timeout-ms": true, "--keepalive-time": true, "-y": true, "--speed-time": true, "-Y": true,
+	"--speed-limit": true, "--limit-rate": true, "--max-filesize": true, "--max-redirs": true,
+	"--retry": true, "--retry-delay": true, "--retry-max-time": true,
+	"--resolve": true, "--connect-to": true, "--dns-servers": true, "--interface": true, "--local-port": true,
+	"--unix-socket": true, "--abstract-unix-socket": true, "--alt-svc": true, "--hsts": true,
+	"-E": true, "--cert": true, "--cert-type": true, "--key": true, "--key-type": true, "--cacert": true, "--capath": true,
+	"--crlfile": true, "--pinnedpubkey": true, "--ciphers": true, "--tls13-ciphers": true, "--curves": true,
+	"--tls-max": true, "--proto": true, "--proto-redir": true, "--proto-default": true,
+}
+
+// The curl options without value which are ignored when importing a curl command, as they do not change the request.
+var ignoredCurlFlags = map[string]bool{
+	"--compressed": true, "-k": true, "--insecure": true, "--proxy-insecure": true, "-L": true, "--location": true,
+	"--location-trusted": true, "-s": true, "--silent": true, "-S": true, "--show-error": true, "-v": true,
+	"--verbose": true, "-i": true, "--include": true, "-f": true, "--fail": true, "--fail-with-body": true, "-g": true,
+	"--globoff": true, "-N": true, "--no-buffer": true, "-#": true, "--progress-bar": true, "--no-progress-meter": true,
+	"-O": true, "--remote-name": true, "-J": true, "--remote-header-name": true, "--create-dirs": true, "--raw": true,
+	"-4": true, "--ipv4": true, "-6": true, "--ipv6": true, "--tcp-nodelay": true, "--no-keepalive": true,
+	"--no-sessionid": true, "--no-alpn": true, "--no-npn": true, "--ssl-no-revoke": true, "--tlsv1": true,
+	"--tlsv1.2": true, "--tlsv1.3": true, "--http1.1": true, "--http2": true, "--http2-prior-knowledge": true,
+	"--http3": true, "--http3-only": true, "-p": true, "--proxytunnel": true, "--suppress-connect-headers": true,
+	"-q": true, "--disable": true,
+}
+
+// RequestFromHar returns the request of a HAR entry, such as one exported from the network panel of the browser
+// developer tools, with its headers in their recorded order under http.HeaderOrderKey. The pseudo headers recorded
+// for HTTP/2 and HTTP/3 exchanges set the pseudo header order under http.PHeaderOrderKey.
+func RequestFromHar(entry HarEntry) (*http.Request, error) {
+	var body io.Reader
+	if entry.Request.PostData != nil {
+		body = strings.NewReader(entry.Request.PostData.Text)
+	}
+
+	req, err := http.NewRequest(entry.Request.Method, entry.Request.URL, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, header := range entry.Request.Headers {
+		if strings.HasPrefix(header.Name, ":") {
+			req.Header[http.PHeaderOrderKey] = append(req.Header[http.PHeaderOrderKey], strings.ToLower(header.Name))
+			continue
+		}
+
+		addImportedHeader(req, header.Name, header.Value)
+	}
+
+	return req, nil
+}
+
+// RequestFromCurl returns the request of a curl command line, such as the ones copied with "Copy as cURL (bash)" from
+// the browser developer tools, with its headers in the order of the command under http.HeaderOrderKey. The options
+// setting the request are supported: the method, headers, cookies, user agent, referer, basic authentication and data.
+// The options which do not change the request, like --compressed or --proxy, are ignored; the other ones, such as
+// multipart forms and data read from files, are not supported and fail the import.
+func RequestFromCurl(command string) (*http.Request, error) {
+	args, err := splitShellWords(command)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 || args[0] != "curl" {
+		return nil, errors.New("not a curl command")
+	}
+
+	var (
+		rawUrl  string
+		method  string
+		data    []string
+		get     bool
+		headers [][2]string
+	)
+
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+
+		value := func() (string, error) {
+			if len(arg) > 2 && arg[1] != '-' {
+				return arg[2:], nil
+			}
+
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("curl option %s without value", arg)
+			}
+
+			i++
+
+			return args[i], nil
+		}
+
+		name := arg
+		if len(arg) > 2 && arg[0] == '-' && arg[1] != '-' {
+			name = arg[:2]
+		}
+
+		var v string
+
+		switch name {
+		case "-X", "--request":
+			if v, err = value(); err == nil {
+				method = v
+			}
+		case "-H", "--header":
+			if v, err = value(); err == nil {
+				headerName, headerValue, ok := strings.Cut(v, ":")
+				if !ok {
+					return nil, fmt.Errorf("invalid curl header %q", v)
+				}
+
+				headers = append(headers, [2]string{strings.TrimSpace(headerName), strings.TrimSpace(headerValue)})
+			}
+		case "-b", "--cookie":
+			if v, err = value(); err == nil {
+				if !strings.Contains(v, "=") {
+					return nil, errors.New("curl cookie files are not supported")
+				}
+
+				headers = append(headers, [2]string{"cookie", v})
+			}
+		case "-A", "--user-agent":
+			if v, err = value(); err == nil {
+				headers = append(headers, [2]string{"user-agent", v})
+			}
+		case "-e", "--referer":
+			if v, err = value(); err == nil {
+				headers = append(headers, [2]string{"referer", v})
+			}
+		case "-u", "--user":
+			if v, err = value(); err == nil {
+				headers = append(headers, [2]string{"authorization", "Basic " + base64.StdEncoding.EncodeToString([]byte(v))})
+			}
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii":
+			if v, err = value(); err == nil {
+				if strings.HasPrefix(v, "@") && name != "--data-raw" {
+					return nil, errors.New("curl data read from files is not supported")
+				}
+
+				data = append(data, v)
+			}
+		case "--data-urlencode":
+			if v, err = value(); err == nil {
+				if key, content, ok := strings.Cut(v, "="); ok {
+					data = append(data, key+"="+url.QueryEscape(content))
+				} else {
+					data = append(data, url.QueryEscape(v))
+				}
+			}
+		case "-F", "--form":
+			return nil, errors.New("curl multipart forms are not supported")
+		case "--url":
+			rawUrl, err = value()
+		case "-I", "--head":
+			method = http.MethodHead
+		case "-G", "--get":
+			get = true
+		default:
+			switch {
+			case ignoredCurlOptions[name]:
+				_, err = value()
+			case ignoredCurlFlags[name]:
+				err = checkCurlFlags(arg)
+			case strings.HasPrefix(arg, "-") && len(arg) > 1:
+				err = fmt.Errorf("unsupported curl option %s", arg)
+			case rawUrl == "":
+				rawUrl = arg
+			default:
+				return nil, fmt.Errorf("unexpected curl argument %q", arg)
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rawUrl == "" {
+		return nil, errors.New("curl command without url")
+	}
+
+	var body io.Reader
+	if len(data) > 0 {
+		if get {
+			separator := "?"
+			if strings.Contains(rawUrl, "?") {
+				separator = "&"
+			}
+
+			rawUrl += separator + strings.Join(data, "&")
+		} else {
+			body = strings.NewReader(strings.Join(data, "&"))
+
+			if method == "" {
+				method = http.MethodPost
+			}
+
+			if !hasImportedHeader(headers, "content-type") {
+				headers = append(headers, [2]string{"content-type", "application/x-www-form-urlencoded"})
+			}
+		}
+	}
+
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequest(method, rawUrl, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, header := range headers {
+		addImportedHeader(req, header[0], header[1])
+	}
+
+	return req, nil
+}
+
+// checkCurlFlags checks that the short flags grouped in arg, like -sSL, are all ignored.
+func checkCurlFlags(arg string) error {
+	if strings.HasPrefix(arg, "--") {
+		return nil
+	}
+
+	for _, flag := range arg[1:] {
+		if !ignoredCurlFlags["-"+string(flag)] {
+			return fmt.Errorf("unsupported curl option -%c in %s", flag, arg)
+		}
+	}
+
+	return nil
+}
+
+// RequestFromFetch returns the request of a fetch call, such as the ones copied with "Copy as fetch" from the browser
+// developer tools, with its headers in the order of the call under http.HeaderOrderKey. The options of the call must
+// be JSON, as copied. The referrer is sent in the referer header, after the other headers.
+func RequestFromFetch(snippet string) (*http.Request, error) {
+	snippet = strings.TrimSpace(snippet)
+	snippet = strings.TrimPrefix(snippet, "await ")
+
+	if !strings.HasPrefix(snippet, "fetch(") {
+		return nil, errors.New("not a fetch call")
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(strings.TrimPrefix(snippet, "fetch(")))
+
+	var rawUrl string
+	if err := decoder.Decode(&rawUrl); err != nil {
+		return nil, fmt.Errorf("invalid fetch url: %w", err)
+	}
+
+	options := fetchOptions{method: http.MethodGet}
+
+	rest := strings.TrimSpace(snippet[len("fetch(")+int(decoder.InputOffset()):])
+	if strings.HasPrefix(rest, ",") {
+		if err := options.parse(json.NewDecoder(strings.NewReader(strings.TrimPrefix(rest, ",")))); err != nil {
+			return nil, fmt.Errorf("invalid fetch options: %w", err)
+		}
+	}
+
+	var body io.Reader
+	if options.body != nil {
+		body = strings.NewReader(*options.body)
+	}
+
+	req, err := http.NewRequest(strings.ToUpper(options.method), rawUrl, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, header := range options.headers {
+		addImportedHeader(req, header[0], header[1])
+	}
+
+	if options.referrer != "" && !hasImportedHeader(options.headers, "referer") {
+		addImportedHeader(req, "referer", options.referrer)
+	}
+
+	return req, nil
+}
+
+// fetchOptions are the options of a fetch call which make up the request.
+type fetchOptions struct {
+	body     *string
+	method   string
+	referrer string
+	headers  [][2]string
+}
+
+// parse reads the options object of a fetch call from decoder, keeping the order of the headers.
+func (o *fetchOptions) parse(decoder *json.Decoder) error {
+	if err := expectDelim(decoder, '{'); err != nil {
+		return err
+	}
+
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		switch key {
+		case "headers":
+			if err := expectDelim(decoder, '{'); err != nil {
+				return err
+			}
+
+			for decoder.More() {
+				var name, value string
+				if err := decoder.Decode(&name); err != nil {
+					return err
+				}
+
+				if err := decoder.Decode(&value); err != nil {
+					return err
+				}
+
+				o.headers = append(o.headers, [2]string{name, value})
+			}
+
+			if err := expectDelim(decoder, '}'); err != nil {
+				return err
+			}
+		case "body":
+			err = decoder.Decode(&o.body)
+		case "method":
+			err = decoder.Decode(&o.method)
+		case "referrer":
+			err = decoder.Decode(&o.referrer)
+		default:
+			var ignored json.RawMessage
+			err = decoder.Decode(&ignored)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(decoder, '}')
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	if token != delim {
+		return fmt.Errorf("expected %s, got %v", delim, token)
+	}
+
+	return nil
+}
+
+// addImportedHeader adds the header name to req, appending it to the header order. The Host header sets the host of
+// the request and the Content-Length header, computed by the transport, is only kept in the header order.
+func addImportedHeader(req *http.Request, name string, value string) {
+	lower := strings.ToLower(name)
+
+	if !containsHint(req.Header[http.HeaderOrderKey], lower) {
+		req.Header[http.HeaderOrderKey] = append(req.Header[http.HeaderOrderKey], lower)
+	}
+
+	switch lower {
+	case "host":
+		req.Host = value
+	case "content-length":
+	default:
+		req.Header[name] = append(req.Header[name], value)
+	}
+}
+
+func hasImportedHeader(headers [][2]string, name string) bool {
+	for _, header := range headers {
+		if strings.EqualFold(header[0], name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ToCurl returns a curl command line sending req, with its headers in the order they are sent. The body is read
+// through http.Request.GetBody if set, otherwise it is read and replaced so that req can still be sent.
+func ToCurl(req *http.Request) (string, error) {
+	var command strings.Builder
+
+	command.WriteString("curl " + shellQuote(req.URL.String()))
+
+	body, err := requestBody(req)
+	if err != nil {
+		return "", err
+	}
+
+	if req.Method != http.MethodGet && !(req.Method == http.MethodPost && body != nil) {
+		command.WriteString(" \\\n  -X " + shellQuote(req.Method))
+	}
+
+	if req.Host != "" && req.Host != req.URL.Host {
+		command.WriteString(" \\\n  -H " + shellQuote("host: "+req.Host))
+	}
+
+	compressed := false
+
+	for _, header := range orderedHarHeaders(req.Header) {
+		if strings.EqualFold(header.Name, "accept-encoding") {
+			compressed = true
+		}
+
+		command.WriteString(" \\\n  -H " + shellQuote(header.Name+": "+header.Value))
+	}
+
+	if body != nil {
+		command.WriteString(" \\\n  --data-raw " + shellQuote(string(body)))
+	}
+
+	if compressed {
+		command.WriteString(" \\\n  --compressed")
+	}
+
+	return command.String(), nil
+}
+
+// requestBody returns the body of req without consuming it, nil if it has none.
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+
+		return io.ReadAll(body)
+	}
+
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = req.Body.Close()
+
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	return data, nil
+}
+
+// shellQuote quotes s for bash, with ANSI-C quoting if it contains control characters.
+func shellQuote(s string) string {
+	ansiC := !utf8.ValidString(s) || strings.IndexFunc(s, func(r rune) bool { return r < 0x20 || r == 0x7f }) >= 0
+	if !ansiC {
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}
+
+	var quoted strings.Builder
+
+	quoted.WriteString("$'")
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '\'':
+			quoted.WriteByte('\\')
+			quoted.WriteByte(c)
+		case '\n':
+			quoted.WriteString(`\n`)
+		case '\r':
+			quoted.WriteString(`\r`)
+		case '\t':
+			quoted.WriteString(`\t`)
+		default:
+			if c < 0x20 || c >= 0x7f {
+				quoted.WriteString(fmt.Sprintf(`\x%02x`, c))
+			} else {
+				quoted.WriteByte(c)
+			}
+		}
+	}
+
+	quoted.WriteString("'")
+
+	return quoted.String()
+}
+
+// splitShellWords splits a bash command line into its words, removing the quotes, escapes and line continuations.
+func splitShellWords(command string) ([]string, error) {
+	var (
+		words  []string
+		word   strings.Builder
+		inWord bool
+		input  = []byte(command)
+	)
+
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\\':
+			if i+1 < len(input) {
+				i++
+				if input[i] != '\n' {
+					word.WriteByte(input[i])
+					inWord = true
+				}
+			}
+		case c == '\'':
+			end := bytes.IndexByte(input[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+
+			word.Write(input[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '$' && i+1 < len(input) && input[i+1] == '\'':
+			n, err := readAnsiCQuote(input[i+2:], &word)
+			if err != nil {
+				return nil, err
+			}
+
+			i += n + 1
+			inWord = true
+		case c == '"':
+			closed := false
+
+			for i++; i < len(input); i++ {
+				if input[i] == '"' {
+					closed = true
+					break
+				}
+
+				if input[i] == '\\' && i+1 < len(input) && strings.IndexByte("\\\"$`\n", input[i+1]) >= 0 {
+					i++
+					if input[i] == '\n' {
+						continue
+					}
+				}
+
+				word.WriteByte(input[i])
+			}
+
+			if !closed {
+				return nil, errors.New("unterminated double quote")
+			}
+
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
+
+// readAnsiCQuote reads the content of a $'...' quote from s, which starts after the opening quote, into word. It returns
+// the number of bytes read, including the closing quote.
+func readAnsiCQuote(s []byte, word *strings.Builder) (int, error) {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if c == '\'' {
+			return i + 1, nil
+		}
+
+		if c != '\\' || i+1 >= len(s) {
+			word.WriteByte(c)
+			continue
+		}
+
+		i++
+
+		switch s[i] {
+		case 'n':
+			word.WriteByte('\n')
+		case 'r':
+			word.WriteByte('\r')
+		case 't':
+			word.WriteByte('\t')
+		case 'x', 'u', 'U':
+			digits := map[byte]int{'x': 2, 'u': 4, 'U': 8}[s[i]]
+
+			end := i + 1
+			for end < len(s) && end < i+1+digits && strings.IndexByte("0123456789abcdefABCDEF", s[end]) >= 0 {
+				end++
+			}
+
+			code, err := strconv.ParseUint(string(s[i+1:end]), 16, 32)
+			if err != nil {
+				return 0, fmt.Errorf("invalid escape in ANSI-C quote: %w", err)
+			}
+
+			if s[i] == 'x' {
+				word.WriteByte(byte(code))
+			} else {
+				word.WriteRune(rune(code))
+			}
+
+			i = end - 1
+		default:
+			word.WriteByte(s[i])
+		}
+	}
+
+	return 0, errors.New("unterminated ANSI-C quote")
+}
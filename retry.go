@@ -0,0 +1,211 @@
+package httpkit
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/bogdanfinn/fhttp/http2"
+)
+
+// RetryPolicy configures how failed requests are retried by the client.
+//
+// A request is retried when the connection failed or was reset, the server closed an idle connection, sent an HTTP/2
+// GOAWAY or refused the stream, the proxy CONNECT failed or the response status is one of RetryStatusCodes. Requests with a non-idempotent method are only retried
+// when they certainly never reached the server, unless RetryNonIdempotent is set. Requests with a body are only retried
+// when the body can be replayed through http.Request.GetBody.
+type RetryPolicy struct {
+	// ShouldRetry overrides the default classification of retryable responses and errors if set.
+	ShouldRetry func(req *http.Request, resp *http.Response, err error) bool
+	// ProxyRotator returns the proxy to use for the given attempt (starting at 2) if set.
+	ProxyRotator func(attempt int) string
+	// RetryStatusCodes are the response status codes which are retried.
+	RetryStatusCodes []int
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	MaxAttempts int
+	// InitialBackoff is the delay before the second attempt. It grows by Multiplier on every attempt up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// MaxRetryAfter is the longest Retry-After delay the client waits for. Responses asking for a longer delay are returned as is.
+	// Zero means no limit.
+	MaxRetryAfter time.Duration
+	Multiplier    float64
+	// Jitter is the fraction of the backoff which is randomized, between 0 and 1.
+	Jitter float64
+	// RetryNonIdempotent allows retrying requests with a non-idempotent method after they may have reached the server.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns a retry policy with three attempts and exponential backoff, retrying 429, 502, 503 and 504 responses.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:      3,
+		InitialBackoff:   500 * time.Millisecond,
+		MaxBackoff:       10 * time.Second,
+		MaxRetryAfter:    30 * time.Second,
+		Multiplier:       2,
+		Jitter:           0.2,
+		RetryStatusCodes: []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	}
+}
+
+// ProxyConnectError is returned when a proxy answers a CONNECT request with a non 200 status.
+type ProxyConnectError struct {
+	Status     string
+	StatusCode int
+}
+
+func (e *ProxyConnectError) Error() string {
+	return "Proxy responded with non 200 code: " + e.Status
+}
+
+func (p *RetryPolicy) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if p.ShouldRetry != nil {
+		return p.ShouldRetry(req, resp, err)
+	}
+
+	if err != nil {
+		if req.Context().Err() != nil {
+			return false
+		}
+
+		if isNotSentError(err) {
+			return true
+		}
+
+		return isRetryableError(err) && (p.RetryNonIdempotent || isIdempotent(req))
+	}
+
+	for _, statusCode := range p.RetryStatusCodes {
+		if resp.StatusCode == statusCode {
+			return p.RetryNonIdempotent || isIdempotent(req) || resp.StatusCode == http.StatusTooManyRequests
+		}
+	}
+
+	return false
+}
+
+// backoff returns the delay before the next attempt and false if the server asked for a delay longer than MaxRetryAfter.
+func (p *RetryPolicy) backoff(attempt int, resp *http.Response) (time.Duration, bool) {
+	if resp != nil {
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if p.MaxRetryAfter > 0 && retryAfter > p.MaxRetryAfter {
+				return 0, false
+			}
+
+			return retryAfter, true
+		}
+	}
+
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 {
+		delay = math.Min(delay, float64(p.MaxBackoff))
+	}
+
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(delay), true
+}
+
+// parseRetryAfter parses the Retry-After header, which is either a number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	return max(date.Sub(now), 0), true
+}
+
+// The messages of the errors of the transports for connections closed by the server, which are not exported.
+const (
+	// errGotGoAwayMessage fails the streams a graceful GOAWAY excluded, which the server did not process.
+	errGotGoAwayMessage = "http2: Transport received Server's graceful shutdown GOAWAY"
+	// errServerClosedIdleMessage fails the requests sent on a connection the server closed while it was idle.
+	errServerClosedIdleMessage = "http: server closed idle connection"
+)
+
+// isNotSentError reports whether err guarantees that the request never reached the origin server.
+func isNotSentError(err error) bool {
+	var proxyErr *ProxyConnectError
+	if errors.As(err, &proxyErr) {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	var streamErr http2.StreamError
+	if errors.As(err, &streamErr) && streamErr.Code == http2.ErrCodeRefusedStream {
+		return true
+	}
+
+	return hasErrorMessage(err, errGotGoAwayMessage)
+}
+
+// isRetryableError reports whether err is a failure of the connection which a new attempt may not run into. A bare EOF
+// may be a response cut short, it is only retried as part of an error of isNotSentError.
+func isRetryableError(err error) bool {
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var goAwayErr http2.GoAwayError
+	if errors.As(err, &goAwayErr) {
+		return true
+	}
+
+	return hasErrorMessage(err, errServerClosedIdleMessage)
+}
+
+// hasErrorMessage reports whether err or one of the errors it wraps has the given message.
+func hasErrorMessage(err error, message string) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if err.Error() == message {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isIdempotent reports whether the request can safely be sent twice, per RFC 9110, section 9.2.2.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	_, hasIdempotencyKey := req.Header["Idempotency-Key"]
+	_, hasXIdempotencyKey := req.Header["X-Idempotency-Key"]
+
+	return hasIdempotencyKey || hasXIdempotencyKey
+}
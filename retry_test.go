@@ -0,0 +1,40 @@
+package httpkit
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"syscall"
+	"testing"
+
+	"github.com/bogdanfinn/fhttp/http2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_GivenTransportErrors_ThenTheyAreClassified(t *testing.T) {
+	wrap := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://test.com", Err: err}
+	}
+
+	cases := []struct {
+		name      string
+		err       error
+		notSent   bool
+		retryable bool
+	}{
+		{name: "eof", err: wrap(io.EOF)},
+		{name: "unexpected eof", err: wrap(io.ErrUnexpectedEOF)},
+		{name: "eof while dialing", err: wrap(&net.OpError{Op: "dial", Net: "tcp", Err: io.EOF}), notSent: true},
+		{name: "graceful goaway", err: wrap(errors.New(errGotGoAwayMessage)), notSent: true},
+		{name: "goaway", err: wrap(http2.GoAwayError{LastStreamID: 1, ErrCode: http2.ErrCodeNo}), retryable: true},
+		{name: "server closed idle connection", err: wrap(errors.New(errServerClosedIdleMessage)), retryable: true},
+		{name: "connection reset", err: wrap(&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}), retryable: true},
+		{name: "goaway in message", err: wrap(errors.New("upstream sent GOAWAY"))},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.notSent, isNotSentError(c.err), "isNotSentError(%s)", c.name)
+		assert.Equal(t, c.retryable, isRetryableError(c.err), "isRetryableError(%s)", c.name)
+	}
+}
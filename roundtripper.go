@@ -5,23 +5,60 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/Dharmey747/quic-go-utls"
 	"github.com/Dharmey747/quic-go-utls/http3"
 	"github.com/Mathious6/httpkit/bandwidth"
 	"github.com/Mathious6/httpkit/profiles"
 	http "github.com/bogdanfinn/fhttp"
 	"github.com/bogdanfinn/fhttp/http2"
+	"github.com/bogdanfinn/fhttp/httptrace"
 	tls "github.com/bogdanfinn/utls"
 	"golang.org/x/net/proxy"
 )
 
 const defaultIdleConnectionTimeout = 90 * time.Second
 
+// quicRaceDelay is the head start of QUIC over TCP when the two race to connect to an origin, like the delay of the TCP
+// job of Chrome.
+const quicRaceDelay = 300 * time.Millisecond
+
+// quicDialTimeout bounds the QUIC connections dialed ahead of the requests.
+const quicDialTimeout = 30 * time.Second
+
+// Defaults of the QUIC stack for the upper bound of the flow control windows.
+const (
+	defaultQUICMaxStreamReceiveWindow     = 6 << 20
+	defaultQUICMaxConnectionReceiveWindow = 15 << 20
+)
+
 var errProtocolNegotiated = errors.New("protocol negotiated")
 
+// sendHook is called with every request sent by the round tripper, redirects and retries included, once the protocol
+// of the connection it is sent on is known. An error fails the request without sending it.
+type sendHook func(req *http.Request, http1 bool) error
+
+type sendHookContextKey struct{}
+
+// withSendHook returns a shallow copy of req whose requests are passed to hook before they are sent.
+func withSendHook(req *http.Request, hook sendHook) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), sendHookContextKey{}, hook))
+}
+
+// beforeSend calls the send hook of req, if any.
+func beforeSend(req *http.Request, http1 bool) error {
+	hook, _ := req.Context().Value(sendHookContextKey{}).(sendHook)
+	if hook == nil {
+		return nil
+	}
+
+	return hook(req, http1)
+}
+
 type roundTripper struct {
 	clientHelloId     tls.ClientHelloID
 	certificatePinner CertificatePinner
@@ -36,19 +73,28 @@ type roundTripper struct {
 	cachedConnections map[string]net.Conn
 	cachedTransports  map[string]http.RoundTripper
 
+	altSvcCache    *altSvcCache
+	http3Transport *http3.Transport
+	quicTransport  *quic.Transport
+	quicDials      map[string]*quicDial
+
 	headerPriority      *http2.PriorityParam
 	settings            map[http2.SettingID]uint32
 	transportOptions    *TransportOptions
 	serverNameOverwrite string
+	proxyUrl            string
 	priorities          []http2.Priority
 	pseudoHeaderOrder   []string
 	settingsOrder       []http2.SettingID
+	quicProfile         *profiles.QUICProfile
 	sync.Mutex
 
 	cachedTransportsLck sync.Mutex
+	quicDialsLck        sync.Mutex
 	connectionFlow      uint32
 
-	forceHttp1 bool
+	forceHttp1   bool
+	disableHttp3 bool
 
 	insecureSkipVerify          bool
 	withRandomTlsExtensionOrder bool
@@ -69,29 +115,538 @@ func (rt *roundTripper) CloseIdleConnections() {
 			tr.CloseIdleConnections()
 		}
 	}
+
+	if rt.http3Transport != nil {
+		rt.http3Transport.CloseIdleConnections()
+	}
+}
+
+// Close closes the idle connections of the round tripper, its HTTP/3 connections and the UDP socket they share. The
+// round tripper opens new ones if it is used again.
+func (rt *roundTripper) Close() error {
+	rt.CloseIdleConnections()
+
+	rt.cachedTransportsLck.Lock()
+	t3 := rt.http3Transport
+	rt.http3Transport = nil
+	rt.cachedTransportsLck.Unlock()
+
+	rt.Lock()
+	qt := rt.quicTransport
+	rt.quicTransport = nil
+	rt.Unlock()
+
+	// the connections dialed ahead of the requests are closed with the socket
+	rt.quicDialsLck.Lock()
+	rt.quicDials = make(map[string]*quicDial)
+	rt.quicDialsLck.Unlock()
+
+	var err error
+
+	if t3 != nil {
+		err = t3.Close()
+	}
+
+	// the transport does not close the socket it was given
+	if qt != nil {
+		err = errors.Join(err, qt.Close(), qt.Conn.Close())
+	}
+
+	return err
 }
 
 func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	var gotConn httptrace.GotConnInfo
+
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			gotConn = info
+		},
+	}))
+
+	resp, err := rt.roundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	withConnectionInfo(resp, req, newConnectionInfo(gotConn.Conn, resp, gotConn.Reused, rt.proxyUrl))
+
+	return resp, nil
+}
+
+// roundTrip sends the request over HTTP/3 when the origin advertised it, else over TCP. Like Chrome, QUIC and TCP race
+// to connect: TCP starts once QUIC did not connect within quicRaceDelay, and the request is sent over the first
+// connected. The request falls back to TCP once HTTP/3 failed, and only if it was certainly not sent or may be sent twice.
+func (rt *roundTripper) roundTrip(req *http.Request) (*http.Response, error) {
 	addr := rt.getDialTLSAddr(req)
+	withHttp3 := rt.supportsHttp3(req)
+
+	if withHttp3 {
+		if _, ok := rt.altSvcCache.lookup(addr); ok {
+			quicFirst, err := rt.raceQUIC(req, addr)
+			if err != nil {
+				return nil, err
+			}
+
+			if quicFirst {
+				if err := beforeSend(req, false); err != nil {
+					return nil, err
+				}
+
+				resp, err := rt.roundTripHttp3(req, addr)
+				if err == nil {
+					return resp, nil
+				}
+
+				// Like Chrome, an alternative service which fails is marked as broken.
+				if req.Context().Err() != nil {
+					return nil, err
+				}
+
+				rt.altSvcCache.markBroken(addr)
+
+				if !isNotSentError(err) && !isIdempotent(req) {
+					return nil, err
+				}
+
+				req, err = rewindRequest(req, err)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	t, err := rt.tcpTransport(req, addr)
+	if err != nil {
+		if errors.Is(err, ErrBadPinDetected) && rt.badPinHandlerFunc != nil {
+			rt.badPinHandlerFunc(req)
+		}
+
+		return nil, err
+	}
+
+	_, overHttp2 := t.(*http2.Transport)
+	if err := beforeSend(req, !overHttp2); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if withHttp3 {
+		rt.altSvcCache.update(addr, resp.Header)
+	}
+
+	return resp, nil
+}
+
+// raceQUIC connects to the alternative service of addr and, if QUIC did not connect within quicRaceDelay, to addr over
+// TCP. It reports whether QUIC connected first. A QUIC connection which loses the race is kept for the next requests,
+// a QUIC connection which fails marks the alternative service as broken.
+func (rt *roundTripper) raceQUIC(req *http.Request, addr string) (bool, error) {
+	dial := rt.quicDialFor(addr)
+
+	timer := time.NewTimer(quicRaceDelay)
+	defer timer.Stop()
+
+	var tcpConnected chan error
+
+	for {
+		select {
+		case <-dial.done:
+			return dial.err == nil, nil
+		case <-timer.C:
+			tcpConnected = make(chan error, 1)
+
+			go func() {
+				_, err := rt.tcpTransport(req, addr)
+				tcpConnected <- err
+			}()
+		case err := <-tcpConnected:
+			if err == nil {
+				return false, nil
+			}
+
+			// QUIC may still connect
+			tcpConnected = nil
+		case <-req.Context().Done():
+			return false, req.Context().Err()
+		}
+	}
+}
 
+// tcpTransport returns the transport of addr, connecting to addr to set it up if needed.
+func (rt *roundTripper) tcpTransport(req *http.Request, addr string) (http.RoundTripper, error) {
 	rt.cachedTransportsLck.Lock()
+	defer rt.cachedTransportsLck.Unlock()
 
 	if _, ok := rt.cachedTransports[addr]; !ok {
 		if err := rt.getTransport(req, addr); err != nil {
-			rt.cachedTransportsLck.Unlock()
+			return nil, err
+		}
+	}
+
+	return rt.cachedTransports[addr], nil
+}
+
+// supportsHttp3 reports whether the request may be upgraded to HTTP/3 once the origin advertised it through Alt-Svc.
+// QUIC can not be tunneled through the supported proxies, therefore HTTP/3 is only used with direct connections.
+func (rt *roundTripper) supportsHttp3(req *http.Request) bool {
+	if rt.disableHttp3 || rt.forceHttp1 || !strings.EqualFold(req.URL.Scheme, "https") {
+		return false
+	}
+
+	_, direct := rt.dialer.(*directDialer)
+
+	return direct
+}
+
+func (rt *roundTripper) roundTripHttp3(req *http.Request, addr string) (*http.Response, error) {
+	// the transport removes the header order from the headers, which a fallback to TCP still needs
+	sent := *req
+	sent.Header = req.Header.Clone()
+
+	resp, err := rt.getHttp3Transport().RoundTrip(&sent)
+	if err != nil {
+		return nil, err
+	}
+
+	rt.altSvcCache.confirm(addr)
+	rt.altSvcCache.update(addr, resp.Header)
+
+	return resp, nil
+}
+
+func (rt *roundTripper) getHttp3Transport() *http3.Transport {
+	rt.cachedTransportsLck.Lock()
+	defer rt.cachedTransportsLck.Unlock()
+
+	if rt.http3Transport == nil {
+		rt.http3Transport = rt.buildHttp3Transport()
+	}
+
+	return rt.http3Transport
+}
+
+func (rt *roundTripper) buildHttp3Transport() *http3.Transport {
+	utlsConfig := &tls.Config{ClientSessionCache: rt.clientSessionCache, InsecureSkipVerify: rt.insecureSkipVerify, OmitEmptyPsk: true}
+	if rt.transportOptions != nil {
+		utlsConfig.RootCAs = rt.transportOptions.RootCAs
+		utlsConfig.KeyLogWriter = rt.transportOptions.KeyLogWriter
+	}
+
+	if rt.serverNameOverwrite != "" {
+		utlsConfig.ServerName = rt.serverNameOverwrite
+	}
+
+	t3 := &http3.Transport{
+		TLSClientConfig: utlsConfig,
+		Dial:            rt.dialQUIC,
+	}
 
-			if errors.Is(err, ErrBadPinDetected) && rt.badPinHandlerFunc != nil {
-				rt.badPinHandlerFunc(req)
+	if rt.quicProfile != nil {
+		t3.QUICConfig = buildQUICConfig(rt.quicProfile)
+		t3.EnableDatagrams = rt.quicProfile.H3Datagram
+
+		if len(rt.quicProfile.H3Settings) > 0 {
+			t3.AdditionalSettings = make(map[uint64]uint64, len(rt.quicProfile.H3Settings))
+			for id, value := range rt.quicProfile.H3Settings {
+				t3.AdditionalSettings[uint64(id)] = value
 			}
+		}
+	}
 
-			return nil, err
+	// completed like the transport does, so that the connections dialed ahead of the requests use the same configuration
+	if t3.QUICConfig == nil {
+		t3.QUICConfig = &quic.Config{KeepAlivePeriod: 10 * time.Second}
+	}
+
+	t3.QUICConfig.Versions = []quic.Version{quic.Version1}
+
+	if t3.QUICConfig.MaxIncomingStreams == 0 {
+		t3.QUICConfig.MaxIncomingStreams = -1
+	}
+
+	if rt.transportOptions != nil {
+		t3.DisableCompression = rt.transportOptions.DisableCompression
+		t3.MaxResponseHeaderBytes = rt.transportOptions.MaxResponseHeaderBytes
+	}
+
+	return t3
+}
+
+// quicDial is a QUIC connection to the alternative service of an origin, dialed ahead of a request by raceQUIC or by
+// the HTTP/3 transport.
+type quicDial struct {
+	done chan struct{}
+	conn *quic.Conn
+	err  error
+	// taken tells whether the HTTP/3 transport uses the connection. It is guarded by quicDialsLck.
+	taken bool
+}
+
+// connected reports whether the dial completed with a connection which is still open.
+func (d *quicDial) connected() bool {
+	select {
+	case <-d.done:
+		return d.err == nil && d.conn.Context().Err() == nil
+	default:
+		return false
+	}
+}
+
+// failed reports whether the dial completed without a connection or with a connection which is closed since.
+func (d *quicDial) failed() bool {
+	select {
+	case <-d.done:
+		return !d.connected()
+	default:
+		return false
+	}
+}
+
+// quicDialFor returns the QUIC connection to the alternative service of addr, dialing it unless it is connected or
+// being dialed.
+func (rt *roundTripper) quicDialFor(addr string) *quicDial {
+	t3 := rt.getHttp3Transport()
+
+	rt.quicDialsLck.Lock()
+	defer rt.quicDialsLck.Unlock()
+
+	if dial := rt.quicDials[addr]; dial != nil && !dial.failed() {
+		return dial
+	}
+
+	// like the transport, which only offers h3 and names the server after the host of the request
+	tlsConfig := t3.TLSClientConfig.Clone()
+	if tlsConfig.ServerName == "" {
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			tlsConfig.ServerName = host
 		}
 	}
 
-	t := rt.cachedTransports[addr]
-	rt.cachedTransportsLck.Unlock()
+	tlsConfig.NextProtos = []string{http3.NextProtoH3}
+
+	dial := &quicDial{done: make(chan struct{})}
+	rt.quicDials[addr] = dial
 
-	return t.RoundTrip(req)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), quicDialTimeout)
+		defer cancel()
+
+		dial.conn, dial.err = rt.connectQUIC(ctx, addr, tlsConfig, t3.QUICConfig)
+
+		rt.quicDialsLck.Lock()
+		current := rt.quicDials[addr] == dial
+		rt.quicDialsLck.Unlock()
+
+		if dial.err != nil && current {
+			rt.altSvcCache.markBroken(addr)
+		}
+
+		close(dial.done)
+	}()
+
+	return dial
+}
+
+// dialQUIC is the dial function of the HTTP/3 transport. It hands over the connection dialed ahead of the request by
+// raceQUIC, else it dials the alternative service of addr.
+func (rt *roundTripper) dialQUIC(ctx context.Context, addr string, tlsConfig *tls.Config, quicConfig *quic.Config) (*quic.Conn, error) {
+	rt.quicDialsLck.Lock()
+	if dial := rt.quicDials[addr]; dial != nil && !dial.taken && dial.connected() {
+		dial.taken = true
+		rt.quicDialsLck.Unlock()
+
+		return dial.conn, nil
+	}
+	rt.quicDialsLck.Unlock()
+
+	conn, err := rt.connectQUIC(ctx, addr, tlsConfig, quicConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	close(done)
+
+	rt.quicDialsLck.Lock()
+	if dial := rt.quicDials[addr]; dial == nil || dial.failed() {
+		rt.quicDials[addr] = &quicDial{done: done, conn: conn, taken: true}
+	}
+	rt.quicDialsLck.Unlock()
+
+	return conn, nil
+}
+
+// connectQUIC dials the alternative service advertised for addr. All QUIC connections of the round tripper share a single UDP socket,
+// bound to the configured local address if any.
+func (rt *roundTripper) connectQUIC(ctx context.Context, addr string, tlsConfig *tls.Config, quicConfig *quic.Config) (*quic.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	service, ok := rt.altSvcCache.lookup(addr)
+	if !ok {
+		return nil, fmt.Errorf("no alternative service known for %s", addr)
+	}
+
+	network := "udp"
+	if rt.disableIPV6 {
+		network = "udp4"
+	}
+
+	if rt.disableIPV4 {
+		network = "udp6"
+	}
+
+	trace := ContextClientTrace(ctx)
+
+	// like the errors of net.Dialer, the errors of the dial are *net.OpError, telling that the request was not sent
+	udpAddr, err := resolveUDPAddr(ctx, network, service.address(host))
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+
+	rt.Lock()
+	if rt.quicTransport == nil {
+		var localAddr *net.UDPAddr
+		if d, ok := rt.dialer.(*directDialer); ok {
+			if tcpAddr, ok := d.dialer.LocalAddr.(*net.TCPAddr); ok && tcpAddr != nil {
+				localAddr = &net.UDPAddr{IP: tcpAddr.IP, Zone: tcpAddr.Zone}
+			}
+		}
+
+		udpConn, err := net.ListenUDP(network, localAddr)
+		if err != nil {
+			rt.Unlock()
+			return nil, &net.OpError{Op: "dial", Net: network, Addr: udpAddr, Err: err}
+		}
+
+		rt.quicTransport = &quic.Transport{Conn: udpConn}
+		if rt.quicProfile != nil {
+			rt.quicTransport.ConnectionIDLength = rt.quicProfile.ConnectionIDLength
+		}
+	}
+	qt := rt.quicTransport
+	rt.Unlock()
+
+	trace.connectStart(network, udpAddr.String())
+	trace.tlsHandshakeStart(tlsConfig.ServerName)
+
+	conn, err := qt.DialEarly(ctx, udpAddr, tlsConfig, quicConfig)
+
+	var state tls.ConnectionState
+	if conn != nil {
+		state = conn.ConnectionState().TLS
+	}
+
+	trace.tlsHandshakeDone(state, err)
+	trace.connectDone(network, udpAddr.String(), err)
+
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Addr: udpAddr, Err: err}
+	}
+
+	return conn, nil
+}
+
+// buildQUICConfig translates the transport parameters of a QUIC profile into the QUIC stack configuration.
+func buildQUICConfig(quicProfile *profiles.QUICProfile) *quic.Config {
+	config := &quic.Config{
+		InitialPacketSize: quicProfile.InitialPacketSize,
+		EnableDatagrams:   quicProfile.H3Datagram,
+	}
+
+	if idleTimeout, ok := quicProfile.GetTransportParameter(profiles.QUICTransportParameterMaxIdleTimeout); ok {
+		config.MaxIdleTimeout = time.Duration(idleTimeout) * time.Millisecond
+	}
+
+	if maxData, ok := quicProfile.GetTransportParameter(profiles.QUICTransportParameterInitialMaxData); ok {
+		config.InitialConnectionReceiveWindow = maxData
+		config.MaxConnectionReceiveWindow = max(maxData, defaultQUICMaxConnectionReceiveWindow)
+	}
+
+	// the QUIC stack uses a single stream window for all stream types
+	if maxStreamData, ok := quicProfile.StreamWindow(); ok {
+		config.InitialStreamReceiveWindow = maxStreamData
+		config.MaxStreamReceiveWindow = max(maxStreamData, defaultQUICMaxStreamReceiveWindow)
+	}
+
+	if maxStreams, ok := quicProfile.GetTransportParameter(profiles.QUICTransportParameterInitialMaxStreamsBidi); ok {
+		config.MaxIncomingStreams = int64(maxStreams)
+	}
+
+	if maxStreams, ok := quicProfile.GetTransportParameter(profiles.QUICTransportParameterInitialMaxStreamsUni); ok {
+		config.MaxIncomingUniStreams = int64(maxStreams)
+	}
+
+	return config
+}
+
+func resolveUDPAddr(ctx context.Context, network, addr string) (*net.UDPAddr, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := net.DefaultResolver.LookupPort(ctx, network, portStr)
+	if err != nil {
+		return nil, err
+	}
+
+	// like net.Dialer, no DNS hooks are fired for IP literals
+	trace := ContextClientTrace(ctx)
+	if net.ParseIP(host) != nil {
+		trace = nil
+	}
+
+	trace.dnsStart(host)
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	trace.dnsDone(ips, err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ip := range ips {
+		if network == "udp4" && ip.IP.To4() == nil {
+			continue
+		}
+
+		if network == "udp6" && ip.IP.To4() != nil {
+			continue
+		}
+
+		return &net.UDPAddr{IP: ip.IP, Port: port, Zone: ip.Zone}, nil
+	}
+
+	return nil, fmt.Errorf("no suitable address found for %s", addr)
+}
+
+// rewindRequest prepares a request which failed with err to be sent again, which is only possible if its body can be replayed.
+func rewindRequest(req *http.Request, err error) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+
+	if req.GetBody == nil {
+		return nil, err
+	}
+
+	body, bodyErr := req.GetBody()
+	if bodyErr != nil {
+		return nil, bodyErr
+	}
+
+	newReq := *req
+	newReq.Body = body
+
+	return &newReq, nil
 }
 
 func (rt *roundTripper) getTransport(req *http.Request, addr string) error {
@@ -159,13 +714,19 @@ func (rt *roundTripper) dialTLS(ctx context.Context, network, addr string) (net.
 
 	rawConn = rt.bandwidthTracker.TrackConnection(ctx, rawConn)
 
+	trace := ContextClientTrace(ctx)
+	trace.tlsHandshakeStart(host)
+
 	conn := tls.UClient(rawConn, tlsConfig, rt.clientHelloId, rt.withRandomTlsExtensionOrder, rt.forceHttp1)
 	if err = conn.HandshakeContext(ctx); err != nil {
 		_ = conn.Close()
+		trace.tlsHandshakeDone(tls.ConnectionState{}, err)
 
 		return nil, err
 	}
 
+	trace.tlsHandshakeDone(conn.ConnectionState(), nil)
+
 	err = rt.certificatePinner.Pin(conn, host)
 
 	if err != nil {
@@ -256,57 +817,6 @@ func (rt *roundTripper) dialTLS(ctx context.Context, network, addr string) (net.
 
 		t2.PushHandler = &http2.DefaultPushHandler{}
 		rt.cachedTransports[addr] = &t2
-	case http3.NextProtoH3:
-		utlsConfig := &tls.Config{
-			ClientSessionCache: rt.clientSessionCache,
-			InsecureSkipVerify: rt.insecureSkipVerify,
-			OmitEmptyPsk:       true,
-		}
-		if rt.transportOptions != nil {
-			utlsConfig.RootCAs = rt.transportOptions.RootCAs
-		}
-
-		if rt.serverNameOverwrite != "" {
-			utlsConfig.ServerName = rt.serverNameOverwrite
-		}
-
-		if rt.transportOptions != nil {
-			utlsConfig.RootCAs = rt.transportOptions.RootCAs
-		}
-
-		if rt.serverNameOverwrite != "" {
-			utlsConfig.ServerName = rt.serverNameOverwrite
-		}
-
-		t3 := http3.Transport{
-			TLSClientConfig: utlsConfig,
-		}
-
-		if rt.settings == nil {
-			// when we not provide a map of custom http2 settings
-			t3.AdditionalSettings = map[uint64]uint64{
-				uint64(http2.SettingMaxConcurrentStreams): 1000,
-				uint64(http2.SettingMaxFrameSize):         16384,
-				uint64(http2.SettingInitialWindowSize):    6291456,
-				uint64(http2.SettingHeaderTableSize):      65536,
-			}
-		} else {
-			// convert settings map from uint32 to uint64
-			convertedSettings := map[uint64]uint64{}
-			for key, value := range rt.settings {
-				convertedSettings[uint64(key)] = uint64(value)
-			}
-
-			// use custom settings
-			t3.AdditionalSettings = convertedSettings
-		}
-
-		if rt.transportOptions != nil {
-			t3.DisableCompression = rt.transportOptions.DisableCompression
-			t3.MaxResponseHeaderBytes = rt.transportOptions.MaxResponseHeaderBytes
-		}
-
-		rt.cachedTransports[addr] = &t3
 	default:
 		rt.cachedTransports[addr] = rt.buildHttp1Transport()
 	}
@@ -370,12 +880,16 @@ func (rt *roundTripper) getDialTLSAddr(req *http.Request) string {
 	return net.JoinHostPort(req.URL.Host, "443")
 }
 
-func newRoundTripper(clientProfile profiles.ClientProfile, transportOptions *TransportOptions, serverNameOverwrite string, insecureSkipVerify bool, withRandomTlsExtensionOrder bool, forceHttp1 bool, certificatePins map[string][]string, badPinHandlerFunc BadPinHandlerFunc, disableIPV6 bool, disableIPV4 bool, bandwidthTracker bandwidth.BandwidthTracker, dialer ...proxy.ContextDialer) (http.RoundTripper, error) {
+func newRoundTripper(clientProfile profiles.ClientProfile, transportOptions *TransportOptions, serverNameOverwrite string, insecureSkipVerify bool, withRandomTlsExtensionOrder bool, forceHttp1 bool, disableHttp3 bool, certificatePins map[string][]string, badPinHandlerFunc BadPinHandlerFunc, disableIPV6 bool, disableIPV4 bool, proxyUrl string, bandwidthTracker bandwidth.BandwidthTracker, dialer ...proxy.ContextDialer) (http.RoundTripper, error) {
 	pinner, err := NewCertificatePinner(certificatePins)
 	if err != nil {
 		return nil, fmt.Errorf("can not instantiate certificate pinner: %w", err)
 	}
 
+	if err := clientProfile.GetQUICProfile().Validate(); err != nil {
+		return nil, fmt.Errorf("invalid QUIC profile: %w", err)
+	}
+
 	var clientSessionCache tls.ClientSessionCache
 
 	withSessionResumption := supportsSessionResumption(clientProfile.GetClientHelloId())
@@ -391,18 +905,23 @@ func newRoundTripper(clientProfile profiles.ClientProfile, transportOptions *Tra
 		transportOptions:            transportOptions,
 		clientSessionCache:          clientSessionCache,
 		serverNameOverwrite:         serverNameOverwrite,
+		proxyUrl:                    redactProxyUrl(proxyUrl),
 		settings:                    clientProfile.GetSettings(),
 		settingsOrder:               clientProfile.GetSettingsOrder(),
 		priorities:                  clientProfile.GetPriorities(),
 		headerPriority:              clientProfile.GetHeaderPriority(),
 		pseudoHeaderOrder:           clientProfile.GetPseudoHeaderOrder(),
+		quicProfile:                 clientProfile.GetQUICProfile(),
 		insecureSkipVerify:          insecureSkipVerify,
 		forceHttp1:                  forceHttp1,
+		disableHttp3:                disableHttp3,
 		withRandomTlsExtensionOrder: withRandomTlsExtensionOrder,
 		connectionFlow:              clientProfile.GetConnectionFlow(),
 		clientHelloId:               clientProfile.GetClientHelloId(),
 		cachedTransports:            make(map[string]http.RoundTripper),
 		cachedConnections:           make(map[string]net.Conn),
+		quicDials:                   make(map[string]*quicDial),
+		altSvcCache:                 newAltSvcCache(),
 		disableIPV6:                 disableIPV6,
 		disableIPV4:                 disableIPV4,
 		bandwidthTracker:            bandwidthTracker,
@@ -417,6 +936,18 @@ func newRoundTripper(clientProfile profiles.ClientProfile, transportOptions *Tra
 	return rt, nil
 }
 
+// redactProxyUrl removes the credentials from a proxy URL.
+func redactProxyUrl(proxyUrl string) string {
+	u, err := url.Parse(proxyUrl)
+	if err != nil || proxyUrl == "" {
+		return ""
+	}
+
+	u.User = nil
+
+	return u.String()
+}
+
 func supportsSessionResumption(id tls.ClientHelloID) bool {
 	spec, err := tls.UTLSIdToSpec(id)
 	if err != nil {
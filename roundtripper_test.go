@@ -0,0 +1,214 @@
+package httpkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dharmey747/quic-go-utls/http3"
+	"github.com/Mathious6/httpkit/bandwidth"
+	"github.com/Mathious6/httpkit/profiles"
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/bogdanfinn/fhttp/httptest"
+	tls "github.com/bogdanfinn/utls"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRoundTripper(t *testing.T) *roundTripper {
+	t.Helper()
+
+	transport, err := newRoundTripper(profiles.Chrome_133, nil, "", true, false, false, false, nil, nil, false, false, "", bandwidth.NewNopeTracker(), newDirectDialer(5*time.Second, nil, net.Dialer{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt := transport.(*roundTripper)
+	// UDP port 0 can not be sent to, so that the QUIC dial fails right away
+	rt.altSvcCache.update("127.0.0.1:443", http.Header{"Alt-Svc": {`h3=":0"; ma=3600`}})
+
+	return rt
+}
+
+func TestRoundTripper_GivenUnreachableAlternativeService_WhenDialQUIC_ThenTheRequestIsNotSent(t *testing.T) {
+	rt := newTestRoundTripper(t)
+	defer rt.Close()
+
+	_, err := rt.dialQUIC(context.Background(), "127.0.0.1:443", &tls.Config{ServerName: "localhost"}, nil)
+
+	assert.Error(t, err)
+	assert.True(t, isNotSentError(err), "Expected a failed QUIC dial to tell that the request was not sent")
+}
+
+func TestRoundTripper_GivenQUICSocket_WhenClose_ThenTheSocketIsClosed(t *testing.T) {
+	rt := newTestRoundTripper(t)
+
+	_, _ = rt.dialQUIC(context.Background(), "127.0.0.1:443", &tls.Config{ServerName: "localhost"}, nil)
+
+	if rt.quicTransport == nil {
+		t.Fatal("Expected the QUIC dial to open a UDP socket")
+	}
+
+	socket := rt.quicTransport.Conn
+
+	assert.NoError(t, rt.Close())
+	assert.Nil(t, rt.quicTransport)
+
+	_, err := socket.WriteTo([]byte{0}, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9})
+	assert.True(t, errors.Is(err, net.ErrClosed), "Expected the UDP socket to be closed, got %v", err)
+}
+
+// newRaceTestRoundTripper returns a round tripper to which the origin of testServer advertises the alternative service
+// on the UDP port of service.
+func newRaceTestRoundTripper(t *testing.T, testServer *httptest.Server, service net.PacketConn) (*roundTripper, string) {
+	t.Helper()
+
+	transport, err := newRoundTripper(profiles.Chrome_133, nil, "", true, false, false, false, nil, nil, false, false, "", bandwidth.NewNopeTracker(), newDirectDialer(5*time.Second, nil, net.Dialer{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt := transport.(*roundTripper)
+	addr := strings.TrimPrefix(testServer.URL, "https://")
+	rt.altSvcCache.update(addr, http.Header{"Alt-Svc": {fmt.Sprintf(`h3=":%d"; ma=3600`, service.LocalAddr().(*net.UDPAddr).Port)}})
+
+	return rt, addr
+}
+
+func TestRoundTripper_GivenSlowQUICHandshake_WhenRoundTrip_ThenTCPWinsTheRace(t *testing.T) {
+	testServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer testServer.Close()
+
+	// a service which never answers, so that the QUIC handshake is still running when TCP connects
+	service, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+
+	rt, addr := newRaceTestRoundTripper(t, testServer, service)
+	defer rt.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, testServer.URL, nil)
+
+	start := time.Now()
+
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	assert.GreaterOrEqual(t, time.Since(start), quicRaceDelay, "Expected QUIC to get a head start")
+	assert.Less(t, time.Since(start), 2*time.Second, "Expected TCP not to wait for the QUIC handshake")
+	assert.Equal(t, 1, resp.ProtoMajor)
+
+	rt.quicDialsLck.Lock()
+	dial := rt.quicDials[addr]
+	rt.quicDialsLck.Unlock()
+
+	if assert.NotNil(t, dial) {
+		assert.False(t, dial.failed(), "Expected the QUIC dial which lost the race to go on")
+	}
+
+	_, ok := rt.altSvcCache.lookup(addr)
+	assert.True(t, ok, "Expected the alternative service not to be marked as broken")
+}
+
+func TestRoundTripper_GivenHTTP3Service_WhenRoundTrip_ThenQUICWinsTheRace(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(req.Proto))
+	})
+
+	testServer := httptest.NewTLSServer(handler)
+	defer testServer.Close()
+
+	service, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &http3.Server{Handler: handler, TLSConfig: http3.ConfigureTLSConfig(testServer.TLS.Clone())}
+	go func() { _ = server.Serve(service) }()
+	defer server.Close()
+
+	rt, _ := newRaceTestRoundTripper(t, testServer, service)
+	defer rt.Close()
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest(http.MethodGet, testServer.URL, nil)
+
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		assert.Equal(t, 3, resp.ProtoMajor, "Expected request %d to be sent over the QUIC connection", i)
+	}
+
+	rt.cachedTransportsLck.Lock()
+	assert.Empty(t, rt.cachedTransports, "Expected no TCP connection while QUIC connects within the head start")
+	rt.cachedTransportsLck.Unlock()
+}
+
+func TestBuildQUICConfig_GivenQUICProfile_ThenItsTransportParametersAreApplied(t *testing.T) {
+	config := buildQUICConfig(&profiles.QUICProfile{
+		TransportParameters: map[profiles.QUICTransportParameterID]uint64{
+			profiles.QUICTransportParameterMaxIdleTimeout:                30000,
+			profiles.QUICTransportParameterInitialMaxData:                15728640,
+			profiles.QUICTransportParameterInitialMaxStreamDataBidiLocal: 6291456,
+			profiles.QUICTransportParameterInitialMaxStreamsBidi:         100,
+			profiles.QUICTransportParameterInitialMaxStreamsUni:          103,
+		},
+		InitialPacketSize: 1250,
+		H3Datagram:        true,
+	})
+
+	assert.Equal(t, 30*time.Second, config.MaxIdleTimeout)
+	assert.Equal(t, uint64(15728640), config.InitialConnectionReceiveWindow)
+	assert.Equal(t, uint64(6291456), config.InitialStreamReceiveWindow)
+	assert.Equal(t, int64(100), config.MaxIncomingStreams)
+	assert.Equal(t, int64(103), config.MaxIncomingUniStreams)
+	assert.Equal(t, uint16(1250), config.InitialPacketSize)
+	assert.True(t, config.EnableDatagrams)
+}
+
+func TestBuildQUICConfig_GivenSmallWindows_ThenTheWindowsCanGrowToTheDefaults(t *testing.T) {
+	config := buildQUICConfig(&profiles.QUICProfile{
+		TransportParameters: map[profiles.QUICTransportParameterID]uint64{
+			profiles.QUICTransportParameterInitialMaxData:                1 << 20,
+			profiles.QUICTransportParameterInitialMaxStreamDataBidiLocal: 1 << 19,
+		},
+	})
+
+	assert.Equal(t, uint64(1<<20), config.InitialConnectionReceiveWindow)
+	assert.Equal(t, uint64(defaultQUICMaxConnectionReceiveWindow), config.MaxConnectionReceiveWindow)
+	assert.Equal(t, uint64(1<<19), config.InitialStreamReceiveWindow)
+	assert.Equal(t, uint64(defaultQUICMaxStreamReceiveWindow), config.MaxStreamReceiveWindow)
+
+	config = buildQUICConfig(&profiles.QUICProfile{
+		TransportParameters: map[profiles.QUICTransportParameterID]uint64{
+			profiles.QUICTransportParameterInitialMaxData: 32 << 20,
+		},
+	})
+
+	assert.Equal(t, uint64(32<<20), config.MaxConnectionReceiveWindow, "Expected a window above the default to be kept")
+}
+
+func TestBuildQUICConfig_GivenEmptyQUICProfile_ThenTheQUICStackDefaultsAreKept(t *testing.T) {
+	config := buildQUICConfig(&profiles.QUICProfile{})
+
+	assert.Zero(t, config.MaxIdleTimeout)
+	assert.Zero(t, config.InitialConnectionReceiveWindow)
+	assert.Zero(t, config.MaxConnectionReceiveWindow)
+	assert.Zero(t, config.InitialStreamReceiveWindow)
+	assert.Zero(t, config.MaxStreamReceiveWindow)
+	assert.Zero(t, config.MaxIncomingStreams)
+	assert.Zero(t, config.MaxIncomingUniStreams)
+	assert.Zero(t, config.InitialPacketSize)
+	assert.False(t, config.EnableDatagrams)
+}
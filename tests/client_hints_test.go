@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/Mathious6/httpkit"
+	"github.com/Mathious6/httpkit/profiles"
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/bogdanfinn/fhttp/httptest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserAgent_ChromeGreasedBrands(t *testing.T) {
+	expected := map[string]string{
+		"124": `"Chromium";v="124", "Google Chrome";v="124", "Not-A.Brand";v="99"`,
+		"131": `"Google Chrome";v="131", "Chromium";v="131", "Not_A Brand";v="24"`,
+		"133": `"Not(A:Brand";v="99", "Google Chrome";v="133", "Chromium";v="133"`,
+	}
+
+	for version, profile := range map[string]profiles.ClientProfile{"124": profiles.Chrome_124, "131": profiles.Chrome_131, "133": profiles.Chrome_133} {
+		ua, err := profiles.NewUserAgent(profile, profiles.PlatformWindows)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		secChUa, _ := ua.Hint("sec-ch-ua")
+		assert.Equal(t, expected[version], secChUa)
+	}
+}
+
+func TestUserAgent_Platforms(t *testing.T) {
+	ua, err := profiles.NewUserAgent(profiles.Chrome_133, profiles.PlatformAndroid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers := ua.Headers("sec-ch-ua-full-version-list", "sec-ch-ua-unknown")
+
+	assert.Equal(t, "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/133.0.0.0 Mobile Safari/537.36", headers["user-agent"][0])
+	assert.Equal(t, []string{"?1"}, headers["sec-ch-ua-mobile"])
+	assert.Equal(t, []string{`"Android"`}, headers["sec-ch-ua-platform"])
+	assert.Equal(t, []string{`"Not(A:Brand";v="99.0.0.0", "Google Chrome";v="133.0.6943.142", "Chromium";v="133.0.6943.142"`}, headers["sec-ch-ua-full-version-list"])
+	assert.NotContains(t, headers, "sec-ch-ua-unknown")
+
+	ua, err = profiles.NewUserAgent(profiles.Firefox_135, profiles.PlatformMacOS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:135.0) Gecko/20100101 Firefox/135.0", ua.UserAgent)
+	assert.False(t, ua.SupportsClientHints())
+	assert.Len(t, ua.Headers(), 1, "Expected Firefox to send no client hints")
+
+	_, err = profiles.NewUserAgent(profiles.Safari_IOS_18_5, profiles.PlatformWindows)
+	assert.ErrorIs(t, err, profiles.ErrUnsupportedPlatform)
+
+	_, err = profiles.NewUserAgent(profiles.NikeIosMobile, profiles.PlatformIOS)
+	assert.ErrorIs(t, err, profiles.ErrUnsupportedClient)
+}
+
+func TestClient_ClientHints_AcceptAndCriticalCH(t *testing.T) {
+	var requests atomic.Int32
+
+	testServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+
+		w.Header().Set("Accept-CH", "Sec-CH-UA-Platform-Version, Sec-CH-UA-Arch")
+		w.Header().Set("Critical-CH", "Sec-CH-UA-Platform-Version")
+		w.Header().Set("X-Platform-Version", req.Header.Get("Sec-CH-UA-Platform-Version"))
+		w.Header().Set("X-User-Agent", req.Header.Get("User-Agent"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	ua, err := profiles.NewUserAgent(profiles.Chrome_133, profiles.PlatformMacOS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(), httpkit.WithInsecureSkipVerify(), httpkit.WithMiddleware(httpkit.ClientHints(ua)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, testServer.URL, nil)
+	req.Header = http.Header{"user-agent": {"overridden"}}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, int32(2), requests.Load(), "Expected the request to be retried with the critical hints")
+	assert.Equal(t, `"15.3.0"`, resp.Header.Get("X-Platform-Version"))
+	assert.Equal(t, ua.UserAgent, resp.Header.Get("X-User-Agent"))
+
+	resp, err = client.Get(testServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, int32(3), requests.Load(), "Expected accepted hints to be sent on the following requests")
+	assert.Equal(t, `"15.3.0"`, resp.Header.Get("X-Platform-Version"))
+}
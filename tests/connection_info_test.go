@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/Mathious6/httpkit"
+	"github.com/Mathious6/httpkit/profiles"
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/bogdanfinn/fhttp/httptest"
+	tls "github.com/bogdanfinn/utls"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_ConnInfo_DescribesTheConnection(t *testing.T) {
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	testServer.EnableHTTP2 = true
+	testServer.StartTLS()
+	defer testServer.Close()
+
+	var connects atomic.Int32
+	proxyServer := getConnectProxy(&connects)
+	defer proxyServer.Close()
+
+	proxyUrl := "http://user:pass@" + proxyServer.Listener.Addr().String()
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(), httpkit.WithClientProfile(profiles.Chrome_133), httpkit.WithInsecureSkipVerify(), httpkit.WithProxyUrl(proxyUrl))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var infos []*httpkit.ConnectionInfo
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(testServer.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+
+		infos = append(infos, httpkit.ConnInfo(resp))
+	}
+
+	info := infos[0]
+	if info == nil {
+		t.Fatal("Expected connection info on the response")
+	}
+
+	assert.Equal(t, "h2", info.Protocol)
+	assert.Equal(t, uint16(tls.VersionTLS13), info.TLSVersion)
+	assert.NotZero(t, info.CipherSuite)
+	assert.Equal(t, "http://"+proxyServer.Listener.Addr().String(), info.Proxy, "Expected proxy credentials to be removed")
+	assert.Equal(t, proxyServer.Listener.Addr().String(), info.RemoteAddr.String())
+	assert.NotEmpty(t, info.PeerCertificates)
+	assert.False(t, info.Reused)
+
+	if assert.NotNil(t, info.ServerHello) {
+		assert.Equal(t, info.CipherSuite, info.ServerHello.CipherSuite)
+		assert.Equal(t, uint16(tls.VersionTLS13), info.ServerHello.SupportedVersion)
+		assert.Contains(t, info.ServerHello.Extensions, uint16(43), "Expected supported_versions extension")
+		assert.Regexp(t, `^t13\d{2}00_1301_[0-9a-f]{12}$`, info.ServerHello.JA4S())
+	}
+
+	assert.True(t, infos[1].Reused)
+}
+
+func TestClient_ConnInfo_PlainHttp(t *testing.T) {
+	testServer := getWebServer()
+	testServer.Start()
+	defer testServer.Close()
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Get(testServer.URL + "/index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	info := httpkit.ConnInfo(resp)
+	if info == nil {
+		t.Fatal("Expected connection info on the response")
+	}
+
+	assert.Equal(t, "http/1.1", info.Protocol)
+	assert.Zero(t, info.TLSVersion)
+	assert.Nil(t, info.ServerHello)
+	assert.Empty(t, info.Proxy)
+	assert.Equal(t, testServer.Listener.Addr().String(), info.RemoteAddr.String())
+}
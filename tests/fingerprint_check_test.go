@@ -0,0 +1,251 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Mathious6/httpkit"
+	"github.com/Mathious6/httpkit/profiles"
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/bogdanfinn/fhttp/httptest"
+	"github.com/stretchr/testify/assert"
+)
+
+const firefoxUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:135.0) Gecko/20100101 Firefox/135.0"
+
+func TestClient_FingerprintCheck_RejectsInconsistentRequests(t *testing.T) {
+	requests := 0
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(),
+		httpkit.WithClientProfile(profiles.Chrome_133),
+		httpkit.WithFingerprintConsistencyCheck(httpkit.FingerprintCheckOptions{Reject: true}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, testServer.URL, nil)
+	req.Header = http.Header{
+		"User-Agent":      {firefoxUserAgent},
+		"Accept-Encoding": {"gzip, compress"},
+		"sec-ch-ua":       {`"Chromium";v="133", "Not(A:Brand";v="99", "Google Chrome";v="133"`},
+	}
+
+	_, err = client.Do(req)
+
+	var fingerprintErr *httpkit.FingerprintError
+	if !errors.As(err, &fingerprintErr) {
+		t.Fatalf("expected a fingerprint error, got %v", err)
+	}
+
+	assert.Equal(t, []string{httpkit.FingerprintCheckUserAgent, httpkit.FingerprintCheckClientHints, httpkit.FingerprintCheckAcceptEncoding}, issueChecks(fingerprintErr.Issues))
+	assert.Equal(t, 0, requests, "Expected the rejected request not to be sent")
+
+	req, _ = http.NewRequest(http.MethodGet, testServer.URL, nil)
+	req.Header = http.Header{
+		"User-Agent":      {profiles.Chrome_133.GetHeaders(profiles.RequestKindNavigation)["user-agent"][0]},
+		"Accept-Encoding": {"gzip, deflate, br, zstd"},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, 1, requests, "Expected the consistent request to be sent")
+}
+
+func TestClient_FingerprintCheck_ReportsIssues(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	var issues []httpkit.FingerprintIssue
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(),
+		httpkit.WithClientProfile(profiles.Chrome_124),
+		httpkit.WithFingerprintConsistencyCheck(httpkit.FingerprintCheckOptions{
+			OnIssues: func(req *http.Request, reported []httpkit.FingerprintIssue) {
+				issues = append(issues, reported...)
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, testServer.URL, nil)
+	req.Header = http.Header{
+		"User-Agent":         {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/133.0.0.0 Safari/537.36"},
+		"accept":             {"*/*"},
+		"sec-ch-ua-mobile":   {"?1"},
+		"sec-ch-ua-platform": {`"macOS"`},
+		"sec-ch-ua":          {`"Chromium";v="124", "Google Chrome";v="124", "Not-A.Brand";v="99"`},
+		http.HeaderOrderKey:  {"accept", "user-agent"},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, []string{
+		httpkit.FingerprintCheckUserAgent,
+		httpkit.FingerprintCheckClientHints,
+		httpkit.FingerprintCheckClientHints,
+		httpkit.FingerprintCheckClientHints,
+		httpkit.FingerprintCheckHeaderCase,
+		httpkit.FingerprintCheckHeaderOrder,
+	}, issueChecks(issues))
+	assert.Equal(t, "the user-agent is Chrome 133 but the client profile is Chrome-124", issues[0].Message)
+	assert.Equal(t, "accept is sent over HTTP/1.1, where browsers send Accept", issues[4].Message)
+	assert.Equal(t, "user-agent is ordered after accept while the client profile sends it before", issues[5].Message)
+}
+
+func TestClient_FingerprintCheck_PseudoHeaderOrder(t *testing.T) {
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	testServer.EnableHTTP2 = true
+	testServer.StartTLS()
+	defer testServer.Close()
+
+	var issues []httpkit.FingerprintIssue
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(),
+		httpkit.WithClientProfile(profiles.Chrome_133),
+		httpkit.WithInsecureSkipVerify(),
+		httpkit.WithProfileHeaders(),
+		httpkit.WithFingerprintConsistencyCheck(httpkit.FingerprintCheckOptions{
+			OnIssues: func(req *http.Request, reported []httpkit.FingerprintIssue) {
+				issues = append(issues, reported...)
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, testServer.URL, nil)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	assert.Empty(t, issues, "Expected the profile headers to be consistent with the profile")
+
+	req, _ = http.NewRequest(http.MethodGet, testServer.URL, nil)
+	req.Header = http.Header{http.PHeaderOrderKey: {":method", ":path", ":authority", ":scheme"}}
+
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, []string{httpkit.FingerprintCheckHeaderOrder}, issueChecks(issues))
+}
+
+func TestClient_FingerprintCheck_ChecksEveryHop(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/redirect" {
+			http.Redirect(w, req, "/target", http.StatusFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	issues := make(map[string][]string)
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(),
+		httpkit.WithClientProfile(profiles.Chrome_133),
+		httpkit.WithNavigationHeaders(),
+		httpkit.WithFingerprintConsistencyCheck(httpkit.FingerprintCheckOptions{
+			OnIssues: func(req *http.Request, reported []httpkit.FingerprintIssue) {
+				for _, issue := range reported {
+					issues[req.URL.Path] = append(issues[req.URL.Path], issue.Message)
+				}
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, testServer.URL+"/redirect", nil)
+	req.Header = http.Header{"accept": {"*/*"}}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	assert.Contains(t, issues["/redirect"], "accept is sent over HTTP/1.1, where browsers send Accept")
+	assert.Contains(t, issues["/redirect"], "sec-fetch-mode is sent over HTTP/1.1, where browsers send Sec-Fetch-Mode", "Expected the fetch headers to be checked")
+	assert.Contains(t, issues["/target"], "accept is sent over HTTP/1.1, where browsers send Accept", "Expected the redirect to be checked")
+}
+
+func TestClient_FingerprintCheck_NegotiatedHttp1(t *testing.T) {
+	testServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	var sent http.Header
+	var issues []httpkit.FingerprintIssue
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(),
+		httpkit.WithClientProfile(profiles.Chrome_133),
+		httpkit.WithInsecureSkipVerify(),
+		httpkit.WithProfileHeaders(),
+		httpkit.WithNavigationHeaders(),
+		httpkit.WithFingerprintConsistencyCheck(httpkit.FingerprintCheckOptions{
+			OnIssues: func(req *http.Request, reported []httpkit.FingerprintIssue) {
+				sent = req.Header.Clone()
+				issues = append(issues, reported...)
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, testServer.URL, nil)
+	req.Header = http.Header{"x-requested-with": {"XMLHttpRequest"}}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, "HTTP/1.1", resp.Proto)
+	assert.Equal(t, []string{httpkit.FingerprintCheckHeaderCase}, issueChecks(issues), "Expected the request header only to be reported")
+	assert.Equal(t, "x-requested-with is sent over HTTP/1.1, where browsers send X-Requested-With", issues[0].Message)
+	assert.Contains(t, sent, "User-Agent", "Expected the profile headers in canonical case over HTTP/1.1")
+	assert.Contains(t, sent, "Sec-Fetch-Mode", "Expected the fetch headers in canonical case over HTTP/1.1")
+	assert.Contains(t, sent, "sec-ch-ua", "Expected the client hints in lowercase")
+}
+
+func issueChecks(issues []httpkit.FingerprintIssue) []string {
+	checks := make([]string, len(issues))
+	for i, issue := range issues {
+		checks[i] = issue.Check
+	}
+
+	return checks
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/Mathious6/httpkit"
+	"github.com/bogdanfinn/fhttp/http2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAkamaiFingerprint(t *testing.T) {
+	fingerprint, err := httpkit.ParseAkamaiFingerprint("1:65536,3:1000,4:6291456|15663105|3:0:0:201,5:1:3:101|m,p,a,s")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []http2.SettingID{http2.SettingHeaderTableSize, http2.SettingMaxConcurrentStreams, http2.SettingInitialWindowSize}, fingerprint.SettingsOrder)
+	assert.Equal(t, uint32(1000), fingerprint.Settings[http2.SettingMaxConcurrentStreams])
+	assert.Equal(t, uint32(15663105), fingerprint.ConnectionFlow)
+	assert.Equal(t, []http2.Priority{
+		{StreamID: 3, PriorityParam: http2.PriorityParam{Weight: 200}},
+		{StreamID: 5, PriorityParam: http2.PriorityParam{StreamDep: 3, Exclusive: true, Weight: 100}},
+	}, fingerprint.Priorities)
+	assert.Equal(t, []string{":method", ":path", ":authority", ":scheme"}, fingerprint.PseudoHeaderOrder)
+
+	for _, invalid := range []string{"1:65536|15663105|0", "1=65536|15663105|0|m,a,s,p", "1:65536|15663105|3:0:0:0|m,a,s,p", "1:65536|15663105|0|m,x"} {
+		_, err := httpkit.ParseAkamaiFingerprint(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestProfileFromFingerprints(t *testing.T) {
+	ja3 := "771,4865-4867-4866-49195-49199-52393-52392-49196-49200-49162-49161-49171-49172-156-157-47-53,0-23-65281-10-11-16-5-34-18-51-43-13-28-27-65037,4588-29-23-24-25-256-257,0"
+	akamai := "1:65536;2:0;4:131072;5:16384|12517377|0|m,p,a,s"
+
+	extras := httpkit.FingerprintExtras{
+		Client: "Firefox",
+		Ja3SpecOptions: httpkit.Ja3SpecOptions{
+			SignatureAlgorithms:            []string{"ECDSAWithP256AndSHA256", "ECDSAWithP384AndSHA384", "ECDSAWithP521AndSHA512", "PSSWithSHA256", "PSSWithSHA384", "PSSWithSHA512", "PKCS1WithSHA256", "PKCS1WithSHA384", "PKCS1WithSHA512", "ECDSAWithSHA1", "PKCS1WithSHA1"},
+			DelegatedCredentialsAlgorithms: []string{"ECDSAWithP256AndSHA256", "ECDSAWithP384AndSHA384", "ECDSAWithP521AndSHA512", "ECDSAWithSHA1"},
+			SupportedVersions:              []string{"1.3", "1.2"},
+			KeyShareCurves:                 []string{"X25519MLKEM768", "X25519", "P256"},
+			ALPNProtocols:                  []string{"h2", "http/1.1"},
+			ECHCandidateCipherSuites:       []httpkit.CandidateCipherSuites{{KdfId: "HKDF_SHA256", AeadId: "AEAD_AES_128_GCM"}},
+			ECHCandidatePayloads:           []uint16{128, 223},
+			CertCompressionAlgorithms:      []string{"zlib", "brotli", "zstd"},
+			RecordSizeLimit:                0x4001,
+		},
+	}
+
+	profile, warnings, err := httpkit.ProfileFromFingerprints(ja3, akamai, extras)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Empty(t, warnings)
+
+	assert.Equal(t, akamai, profile.GetAkamaiFingerprint())
+
+	fingerprint, err := profile.GetTLSFingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, ja3, fingerprint.Ja3)
+	assert.Equal(t, "Firefox", profile.GetClientHelloId().Client)
+
+	other, _, err := httpkit.ProfileFromFingerprints(ja3, "1:65536;2:0;4:131072;5:16384|12517377|0|m,a,s,p", extras)
+	if assert.NoError(t, err) {
+		assert.NotEqual(t, profile.GetClientHelloStr(), other.GetClientHelloStr(), "Expected profiles of different fingerprints not to share a ClientHelloID")
+	}
+
+	unknownCurve := extras
+	unknownCurve.KeyShareCurves = []string{"X25519", "unknown"}
+
+	_, warnings, err = httpkit.ProfileFromFingerprints(ja3, akamai, unknownCurve)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"unknown key share curve unknown is ignored"}, warnings)
+	}
+
+	_, _, err = httpkit.ProfileFromFingerprints("771,4865", akamai, httpkit.FingerprintExtras{})
+	assert.ErrorIs(t, err, httpkit.ErrInvalidJa3)
+}
@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Mathious6/httpkit"
+	"github.com/Mathious6/httpkit/profiles"
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/bogdanfinn/fhttp/httptest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_HarRecorder(t *testing.T) {
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/", HttpOnly: true})
+			http.Redirect(w, req, "/home", http.StatusSeeOther)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("welcome home"))
+	}))
+	testServer.EnableHTTP2 = true
+	testServer.StartTLS()
+	defer testServer.Close()
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(),
+		httpkit.WithClientProfile(profiles.Chrome_133),
+		httpkit.WithInsecureSkipVerify(),
+		httpkit.WithCookieJar(httpkit.NewCookieJar()),
+		httpkit.WithHarRecorder(httpkit.HarOptions{MaxBodySize: 7}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, testServer.URL+"/login?next=home", strings.NewReader("user=me"))
+	req.Header = http.Header{
+		"content-type":      {"application/x-www-form-urlencoded"},
+		"accept":            {"*/*"},
+		http.HeaderOrderKey: {"accept", "content-type"},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, client.GetHarRecorder().Har().Log.Entries, 1, "Expected the last exchange to complete once its body is read")
+
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assert.Equal(t, "welcome home", string(body), "Expected the body to be read whole by the caller")
+
+	har := client.GetHarRecorder().Har()
+	assert.Equal(t, "1.2", har.Log.Version)
+
+	if !assert.Len(t, har.Log.Entries, 2) {
+		return
+	}
+
+	login, home := har.Log.Entries[0], har.Log.Entries[1]
+
+	assert.Equal(t, http.MethodPost, login.Request.Method)
+	assert.Equal(t, []httpkit.HarNameValue{{Name: "next", Value: "home"}}, login.Request.QueryString)
+	assert.Equal(t, []httpkit.HarNameValue{{Name: "accept", Value: "*/*"}, {Name: "content-type", Value: "application/x-www-form-urlencoded"}}, login.Request.Headers[:2])
+	assert.Equal(t, "user=me", login.Request.PostData.Text)
+	assert.Equal(t, int64(7), login.Request.BodySize)
+	assert.Equal(t, http.StatusSeeOther, login.Response.Status)
+	assert.Equal(t, "/home", login.Response.RedirectURL)
+	assert.Equal(t, "session", login.Response.Cookies[0].Name)
+	assert.True(t, login.Response.Cookies[0].HTTPOnly)
+	assert.Equal(t, "h2", login.Protocol)
+	assert.Equal(t, "HTTP/2.0", login.Response.HTTPVersion)
+	assert.GreaterOrEqual(t, login.Timings.SSL, 0.0, "Expected the TLS handshake of the first exchange to be timed")
+	assert.NotEmpty(t, login.ServerIPAddress)
+
+	assert.Equal(t, http.MethodGet, home.Request.Method)
+	assert.Equal(t, []httpkit.HarCookie{{Name: "session", Value: "abc"}}, home.Request.Cookies)
+	assert.Equal(t, -1.0, home.Timings.Connect, "Expected the connection to be reused")
+	assert.Equal(t, "welcome", home.Response.Content.Text)
+	assert.Equal(t, int64(12), home.Response.Content.Size)
+	assert.Equal(t, "body truncated to 7 of 12 bytes", home.Response.Content.Comment)
+
+	var buf bytes.Buffer
+	if _, err := client.GetHarRecorder().WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded httpkit.Har
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Len(t, decoded.Log.Entries, 2)
+}
+
+func TestClient_HarRecorder_EntryWriter(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer testServer.Close()
+
+	var buf bytes.Buffer
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(), httpkit.WithHarRecorder(httpkit.HarOptions{EntryWriter: &buf}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(testServer.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	assert.Empty(t, client.GetHarRecorder().Har().Log.Entries, "Expected the entries to be written rather than kept")
+
+	lines := 0
+	scanner := bufio.NewScanner(&buf)
+
+	for scanner.Scan() {
+		var entry httpkit.HarEntry
+		if assert.NoError(t, json.Unmarshal(scanner.Bytes(), &entry)) {
+			assert.Equal(t, http.StatusNoContent, entry.Response.Status)
+			assert.Equal(t, "HTTP/1.1", entry.Request.HTTPVersion)
+			assert.Equal(t, "Host", entry.Request.Headers[0].Name)
+		}
+
+		lines++
+	}
+
+	assert.Equal(t, 2, lines)
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/Mathious6/httpkit"
 	"github.com/Mathious6/httpkit/profiles"
 	http "github.com/bogdanfinn/fhttp"
+	"github.com/bogdanfinn/fhttp/httptest"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestHTTP3(t *testing.T) {
@@ -29,10 +31,21 @@ func TestHTTP3(t *testing.T) {
 
 	req.Header = defaultHeader
 
+	// The first request is sent over TCP and discovers HTTP/3 through the Alt-Svc header.
 	resp, err := client.Do(req)
 	if err != nil {
 		t.Fatal(err)
 	}
+	_ = resp.Body.Close()
+
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.ProtoMajor != 3 {
+		t.Fatalf("expected HTTP/3 on the second request, got %s", resp.Proto)
+	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -43,3 +56,61 @@ func TestHTTP3(t *testing.T) {
 		t.Fatal("Response did not contain HTTP3 result")
 	}
 }
+
+func TestHTTP3_GivenUnreachableAlternativeService_WhenPost_ThenTheRequestFallsBackToTCP(t *testing.T) {
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// UDP port 0 can not be sent to, so that the QUIC dial fails right away
+		w.Header().Set("Alt-Svc", `h3=":0"; ma=3600`)
+		_, _ = io.Copy(w, req.Body)
+	}))
+	testServer.EnableHTTP2 = true
+	testServer.StartTLS()
+	defer testServer.Close()
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(), httpkit.WithClientProfile(profiles.Chrome_133), httpkit.WithInsecureSkipVerify())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Post(testServer.URL, "text/plain", strings.NewReader("body"))
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+
+		assert.Equal(t, 2, resp.ProtoMajor)
+		assert.Equal(t, "body", string(body), "Expected the body to be sent again over TCP")
+	}
+
+	assert.NoError(t, client.Close())
+
+	resp, err := client.Get(testServer.URL)
+	if err != nil {
+		t.Fatal("Expected the client to be usable once closed: ", err)
+	}
+	_ = resp.Body.Close()
+}
+
+func TestQUICProfile_Validate(t *testing.T) {
+	assert.NoError(t, profiles.Chrome_133.GetQUICProfile().Validate())
+
+	for name, quicProfile := range map[string]*profiles.QUICProfile{
+		"unsupported parameter": {TransportParameters: map[profiles.QUICTransportParameterID]uint64{0x03: 1472}},
+		"grease quic bit":       {TransportParameters: map[profiles.QUICTransportParameterID]uint64{0x2ab2: 0}},
+		"stream windows": {TransportParameters: map[profiles.QUICTransportParameterID]uint64{
+			profiles.QUICTransportParameterInitialMaxStreamDataBidiLocal: 6291456,
+			profiles.QUICTransportParameterInitialMaxStreamDataUni:       1048576,
+		}},
+		"h3 datagram setting": {H3Settings: map[profiles.H3SettingID]uint64{profiles.H3SettingH3Datagram: 1}},
+	} {
+		assert.Error(t, quicProfile.Validate(), "Expected %s to be rejected", name)
+
+		profile := profiles.NewClientProfile(profiles.Chrome_133.GetClientHelloId(), nil, nil, nil, 0, nil, nil, quicProfile)
+
+		_, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(), httpkit.WithClientProfile(profile))
+		assert.Error(t, err, "Expected a client with %s not to be created", name)
+	}
+}
@@ -191,3 +191,73 @@ func ja3_opera_91(t *testing.T) {
 	assert.Equal(t, len(spec.CipherSuites), 15, "Client should have 15 CipherSuites")
 	assert.Equal(t, len(spec.Extensions), 16, "Client should have 16 extensions")
 }
+
+func TestJA3Options(t *testing.T) {
+	ja3 := "771,4865-4866-4867,0-23-65281-10-11-16-43-13-45-51-28-44-21,29-23-24,0"
+
+	options := httpkit.Ja3SpecOptions{
+		SignatureAlgorithms: []string{"ECDSAWithP256AndSHA256", "PSSWithSHA256", "PKCS1WithSHA256"},
+		SupportedVersions:   []string{"1.3", "1.2", "0.9"},
+		KeyShareCurves:      []string{"X25519"},
+		ALPNProtocols:       []string{"h2", "http/1.1"},
+		ExtensionPayloads:   map[uint16][]byte{44: {0x00, 0x02, 0xab, 0xcd}},
+	}
+
+	specFunc, warnings, err := httpkit.GetSpecFactoryFromJa3Options(ja3, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{
+		"unknown supported version 0.9 is ignored",
+		"ja3 defines extension 28 but RecordSizeLimit is not set",
+	}, warnings)
+
+	spec, err := specFunc()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, &utls.GenericExtension{Id: 44, Data: []byte{0x00, 0x02, 0xab, 0xcd}}, spec.Extensions[11], "Expected the payload to be sent for the cookie extension")
+
+	options.ExtensionPayloads[44][2] = 0xff
+	options.ExtensionPayloads[65000] = []byte{0x01}
+
+	spec, err = specFunc()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, &utls.GenericExtension{Id: 44, Data: []byte{0x00, 0x02, 0xab, 0xcd}}, spec.Extensions[11], "Expected the factory not to see later changes of the options")
+
+	delete(options.ExtensionPayloads, 65000)
+
+	_, _, err = httpkit.GetSpecFactoryFromJa3Options("771,4865-4866,0-23-65000,29,0", options)
+
+	var unknownExtension *httpkit.UnknownExtensionError
+	if assert.ErrorAs(t, err, &unknownExtension) {
+		assert.Equal(t, uint16(65000), unknownExtension.Id)
+	}
+
+	options.ExtensionPayloads[65000] = []byte{0x01}
+
+	_, _, err = httpkit.GetSpecFactoryFromJa3Options("771,4865-4866,0-23-65000,29,0", options)
+	assert.NoError(t, err, "Expected extensions without builtin support to be sent from their payload")
+
+	for field, invalid := range map[string]string{
+		"format":       "771,4865-4866,0-23",
+		"version":      "tls,4865,0,29,0",
+		"cipher suite": "771,4865-x,0,29,0",
+		"extension":    "771,4865,0-70000,29,0",
+		"curve":        "771,4865,0,29-,0",
+		"point format": "771,4865,0,29,256",
+	} {
+		_, _, err := httpkit.GetSpecFactoryFromJa3Options(invalid, options)
+
+		var fieldError *httpkit.Ja3FieldError
+		if assert.ErrorAs(t, err, &fieldError, invalid) {
+			assert.Equal(t, field, fieldError.Field)
+			assert.ErrorIs(t, err, httpkit.ErrInvalidJa3)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/Mathious6/httpkit"
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/bogdanfinn/fhttp/httptest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Middleware_OrderAndHeaderInjection(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/redirect" {
+			http.Redirect(w, req, "/final", http.StatusFound)
+			return
+		}
+
+		w.Header().Set("X-Signature", req.Header.Get("X-Signature"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	var calls []string
+	tracer := func(name string) httpkit.Middleware {
+		return func(next httpkit.RoundTripFunc) httpkit.RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				calls = append(calls, name+" before")
+				resp, err := next(req)
+				calls = append(calls, name+" after")
+
+				return resp, err
+			}
+		}
+	}
+
+	signer := func(next httpkit.RoundTripFunc) httpkit.RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("X-Signature", "signed")
+			return next(req)
+		}
+	}
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(), httpkit.WithMiddleware(tracer("outer"), tracer("inner")), httpkit.WithMiddleware(signer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Get(testServer.URL + "/redirect")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "/final", resp.Request.URL.Path, "Expected middlewares to receive the response after redirects")
+	assert.Equal(t, "signed", resp.Header.Get("X-Signature"))
+	assert.Equal(t, []string{"outer before", "inner before", "inner after", "outer after"}, calls)
+}
+
+func TestClient_Middleware_ShortCircuit(t *testing.T) {
+	cached := func(next httpkit.RoundTripFunc) httpkit.RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusNoContent, Header: http.Header{}, Request: req, Body: http.NoBody}, nil
+		}
+	}
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(), httpkit.WithMiddleware(cached))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Get("http://127.0.0.1:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Mathious6/httpkit"
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/bogdanfinn/fhttp/httptest"
+	"github.com/stretchr/testify/assert"
+)
+
+// navigationServer records the navigation headers received for each path. /bounce redirects to the URL of its "to"
+// query parameter, with the referrer policy of its "policy" query parameter.
+func navigationServer(t *testing.T) (*httptest.Server, func(path string) map[string]string) {
+	var lck sync.Mutex
+	received := make(map[string]map[string]string)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		headers := make(map[string]string)
+		for _, name := range []string{"Sec-Fetch-Site", "Sec-Fetch-Mode", "Sec-Fetch-Dest", "Sec-Fetch-User", "Referer", "Origin"} {
+			if value := req.Header.Get(name); value != "" {
+				headers[name] = value
+			}
+		}
+
+		lck.Lock()
+		received[req.URL.Path] = headers
+		lck.Unlock()
+
+		if req.URL.Path == "/bounce" {
+			if policy := req.URL.Query().Get("policy"); policy != "" {
+				w.Header().Set("Referrer-Policy", policy)
+			}
+
+			http.Redirect(w, req, req.URL.Query().Get("to"), http.StatusFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(testServer.Close)
+
+	return testServer, func(path string) map[string]string {
+		lck.Lock()
+		defer lck.Unlock()
+
+		return received[path]
+	}
+}
+
+func TestClient_NavigationHeaders_BrowserInitiated(t *testing.T) {
+	testServer, received := navigationServer(t)
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(), httpkit.WithNavigationHeaders())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bounceUrl := strings.Replace(testServer.URL, "127.0.0.1", "localhost", 1) + "/bounce?to=" + url.QueryEscape(testServer.URL+"/page")
+
+	req, _ := http.NewRequest(http.MethodGet, bounceUrl, nil)
+	req.Header = http.Header{"Referer": {"https://caller.com/"}}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	expected := map[string]string{"Sec-Fetch-Site": "none", "Sec-Fetch-Mode": "navigate", "Sec-Fetch-Dest": "document", "Sec-Fetch-User": "?1"}
+
+	assert.Equal(t, expected, received("/bounce"))
+	assert.Equal(t, expected, received("/page"), "Expected browser initiated navigations to stay so through redirects")
+}
+
+func TestClient_NavigationHeaders_FollowPage(t *testing.T) {
+	testServer, received := navigationServer(t)
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(), httpkit.WithNavigationHeaders())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(method string, rawUrl string, fetch httpkit.FetchContext) *http.Response {
+		req, err := http.NewRequestWithContext(httpkit.WithFetchContext(context.Background(), fetch), method, rawUrl, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		return resp
+	}
+
+	page := send(http.MethodGet, testServer.URL+"/page?q=1#top", httpkit.FetchContext{Navigation: true})
+	click := httpkit.FetchContextFrom(page, true)
+
+	otherSite := strings.Replace(testServer.URL, "127.0.0.1", "localhost", 1)
+	send(http.MethodGet, otherSite+"/bounce?policy=no-referrer&to="+url.QueryEscape(otherSite+"/next"), click)
+
+	assert.Equal(t, map[string]string{
+		"Sec-Fetch-Site": "cross-site",
+		"Sec-Fetch-Mode": "navigate",
+		"Sec-Fetch-Dest": "document",
+		"Sec-Fetch-User": "?1",
+		"Referer":        testServer.URL + "/",
+	}, received("/bounce"), "Expected the referrer to be cut to its origin on cross-origin requests")
+	assert.Equal(t, map[string]string{
+		"Sec-Fetch-Site": "cross-site",
+		"Sec-Fetch-Mode": "navigate",
+		"Sec-Fetch-Dest": "document",
+		"Sec-Fetch-User": "?1",
+	}, received("/next"), "Expected the referrer policy of the redirect to apply to the next hop")
+
+	send(http.MethodPost, testServer.URL+"/api", httpkit.FetchContextFrom(page, false))
+
+	assert.Equal(t, map[string]string{
+		"Sec-Fetch-Site": "same-origin",
+		"Sec-Fetch-Mode": "cors",
+		"Sec-Fetch-Dest": "empty",
+		"Referer":        testServer.URL + "/page?q=1",
+		"Origin":         testServer.URL,
+	}, received("/api"))
+}
@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Mathious6/httpkit/profiles"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestProfileFile_RoundTrip(t *testing.T) {
+	for name, profile := range profiles.MappedTLSClients {
+		exported, err := profile.Export()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		jsonFile, err := json.Marshal(exported)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		yamlFile, err := yaml.Marshal(exported)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		for format, file := range map[string][]byte{"json": jsonFile, "yaml": yamlFile} {
+			loaded, err := profiles.LoadProfile(bytes.NewReader(file))
+			if err != nil {
+				t.Fatalf("%s (%s): %v", name, format, err)
+			}
+
+			reexported, err := loaded.Export()
+			if err != nil {
+				t.Fatalf("%s (%s): %v", name, format, err)
+			}
+
+			again, _ := json.Marshal(reexported)
+			assert.JSONEq(t, string(jsonFile), string(again), "Expected %s (%s) to round trip", name, format)
+
+			assert.Equal(t, profile.GetClientHelloStr(), loaded.GetClientHelloStr())
+			assert.Equal(t, profile.GetAkamaiFingerprint(), loaded.GetAkamaiFingerprint())
+			assert.Equal(t, profile.GetJa4H(profiles.RequestKindNavigation), loaded.GetJa4H(profiles.RequestKindNavigation))
+			assert.Equal(t, profile.GetQUICProfile(), loaded.GetQUICProfile())
+
+			if exported.ClientHello.Spec == nil {
+				continue
+			}
+
+			expected, err := profile.GetTLSFingerprint()
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+
+			actual, err := loaded.GetTLSFingerprint()
+			if err != nil {
+				t.Fatalf("%s (%s): %v", name, format, err)
+			}
+
+			assert.Equal(t, expected.Ja4R, actual.Ja4R, "Expected %s (%s) to send the same ClientHello", name, format)
+		}
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	profile, err := profiles.LoadProfile(strings.NewReader(`
+client_hello:
+  client: Custom
+  version: "1"
+  spec:
+    cipher_suites: [4865, 4866]
+    compression_methods: [0]
+    extensions:
+      - type: server_name
+      - type: supported_versions
+        versions: [772, 771]
+      - type: key_share
+        key_shares: [{group: 29}]
+http2:
+  settings: [{id: 1, value: 65536}, {id: 4, value: 6291456}]
+  settings_order: [1, 4]
+  connection_flow: 15663105
+  pseudo_header_order: [":method", ":authority", ":scheme", ":path"]
+headers:
+  navigation:
+    order: [accept, user-agent]
+    values:
+      - {name: accept, value: "*/*"}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Custom-1", profile.GetClientHelloStr())
+	assert.Equal(t, "1:65536;4:6291456|15663105|0|m,a,s,p", profile.GetAkamaiFingerprint())
+	assert.Equal(t, []string{"*/*"}, profile.GetHeaders(profiles.RequestKindNavigation)["accept"])
+
+	_, err = profiles.LoadProfile(strings.NewReader(`{"client_hello": {"client": "Custom", "version": "1", "spec": {"cipher_suites": [4865], "extensions": [{"type": "unknown"}]}}, "http2": {}}`))
+	assert.Error(t, err, "Expected unknown extensions to be rejected")
+
+	_, err = profiles.LoadProfile(strings.NewReader(`{"client_hello": {}, "http2": {}, "unknown": true}`))
+	assert.Error(t, err, "Expected unknown fields to be rejected")
+
+	_, err = profiles.LoadProfile(strings.NewReader(`{"client_hello": {"client": "Custom", "version": "1"}, "http2": {}, "quic": {"transport_parameters": [{"id": 3, "value": 1472}]}}`))
+	assert.Error(t, err, "Expected QUIC transport parameters the QUIC stack does not send to be rejected")
+}
@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Mathious6/httpkit/profiles"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientProfile_TLSFingerprint(t *testing.T) {
+	chrome, err := profiles.Chrome_133.GetTLSFingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "t13d1516h2_8daaf6152771_d8a2da3f94cd", chrome.Ja4)
+	assert.True(t, strings.HasPrefix(chrome.Ja4R, "t13d1516h2_002f,0035,009c,009d,1301,1302,1303,"))
+
+	again, err := profiles.Chrome_133.GetTLSFingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, chrome.Ja4, again.Ja4, "Expected JA4 not to depend on the extension order")
+
+	firefox, err := profiles.Firefox_135.GetTLSFingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "771,4865-4867-4866-49195-49199-52393-52392-49196-49200-49162-49161-49171-49172-156-157-47-53,0-23-65281-10-11-16-5-34-18-51-43-13-28-27-65037,4588-29-23-24-25-256-257,0", firefox.Ja3)
+	assert.Equal(t, "7704a11cf87dfcf33080b90ce11d5527", firefox.Ja3Hash)
+	assert.Equal(t, "t13d1715h2_5b57614c22b0_a54fffd0eb61", firefox.Ja4)
+
+	_, err = profiles.Chrome_133_PSK.GetTLSFingerprint()
+	assert.NoError(t, err, "Expected the PSK extension to be omitted without session")
+}
+
+func TestClientProfile_AkamaiFingerprint(t *testing.T) {
+	assert.Equal(t, "1:65536;2:0;4:6291456;6:262144|15663105|0|m,a,s,p", profiles.Chrome_133.GetAkamaiFingerprint())
+	assert.Equal(t, "52d84b11737d980aef856699f885ca86", profiles.Chrome_133.GetAkamaiFingerprintHash())
+	assert.Equal(t, "1:65536;2:0;4:131072;5:16384|12517377|0|m,p,a,s", profiles.Firefox_135.GetAkamaiFingerprint())
+}
+
+func TestClientProfile_Ja4H(t *testing.T) {
+	assert.Equal(t, "ge20nn13enus_0c2c1d640f3e_000000000000_000000000000", profiles.Chrome_133.GetJa4H(profiles.RequestKindNavigation))
+	assert.Empty(t, profiles.Opera_91.GetJa4H(profiles.RequestKindNavigation), "Expected no fingerprint without headers")
+}
@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Mathious6/httpkit"
+	"github.com/Mathious6/httpkit/profiles"
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/bogdanfinn/fhttp/httptest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_ProfileHeaders_FillUnsetHeaders(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-User-Agent", req.Header.Get("User-Agent"))
+		w.Header().Set("X-Accept", req.Header.Get("Accept"))
+		w.Header().Set("X-Sec-Fetch-Dest", req.Header.Get("Sec-Fetch-Dest"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(), httpkit.WithClientProfile(profiles.Chrome_133), httpkit.WithProfileHeaders())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	navigation := profiles.Chrome_133.GetHeaders(profiles.RequestKindNavigation)
+
+	req, _ := http.NewRequest(http.MethodGet, testServer.URL, nil)
+	req.Header = http.Header{"accept": {"application/json"}}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, navigation["user-agent"][0], resp.Header.Get("X-User-Agent"))
+	assert.Equal(t, "application/json", resp.Header.Get("X-Accept"), "Expected headers set on the request to be kept")
+	assert.Equal(t, "document", resp.Header.Get("X-Sec-Fetch-Dest"))
+	assert.Equal(t, navigation[http.HeaderOrderKey], req.Header[http.HeaderOrderKey])
+
+	req, _ = http.NewRequest(http.MethodGet, testServer.URL, nil)
+
+	resp, err = client.DoWithOptions(req, httpkit.WithRequestKind(profiles.RequestKindImage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, profiles.Chrome_133.GetHeaders(profiles.RequestKindImage)["accept"][0], resp.Header.Get("X-Accept"))
+	assert.Equal(t, "image", resp.Header.Get("X-Sec-Fetch-Dest"))
+}
+
+func TestClientProfile_Headers(t *testing.T) {
+	assert.Nil(t, profiles.Opera_91.GetHeaders(profiles.RequestKindNavigation), "Expected profiles without headers to return nil")
+
+	headers := profiles.Firefox_135.GetHeaders(profiles.RequestKindFetch)
+	headers["user-agent"][0] = "changed"
+	headers["accept"] = append(headers["accept"], "changed")
+	delete(headers, "te")
+
+	copied := profiles.Firefox_135.GetHeaders(profiles.RequestKindFetch)
+
+	assert.Contains(t, copied["user-agent"][0], "Firefox/135.0", "Expected a copy of the header values")
+	assert.Len(t, copied["accept"], 1, "Expected a copy of the header values")
+	assert.Contains(t, copied, "te", "Expected a copy of the headers")
+
+	custom := profiles.Opera_91.WithHeaders(map[profiles.RequestKind]http.Header{profiles.RequestKindNavigation: {"user-agent": {"custom"}}})
+
+	assert.Equal(t, []string{"custom"}, custom.GetHeaders(profiles.RequestKindNavigation)["user-agent"])
+	assert.Nil(t, profiles.Opera_91.GetHeaders(profiles.RequestKindNavigation))
+}
+
+func TestClientProfile_Headers_BrowserProfiles(t *testing.T) {
+	for name, profile := range profiles.MappedTLSClients {
+		if !strings.HasPrefix(name, "chrome_") && !strings.HasPrefix(name, "firefox_") && !strings.HasPrefix(name, "safari_") {
+			continue
+		}
+
+		for _, kind := range []profiles.RequestKind{profiles.RequestKindNavigation, profiles.RequestKindFetch, profiles.RequestKindImage} {
+			headers := profile.GetHeaders(kind)
+			if assert.NotNil(t, headers, "Expected %s to define %s headers", name, kind) {
+				assert.NotEmpty(t, headers["user-agent"], "Expected %s to define a user-agent", name)
+				assert.NotEmpty(t, headers[http.HeaderOrderKey], "Expected %s to define a header order", name)
+			}
+		}
+	}
+
+	chrome103 := profiles.Chrome_103.GetHeaders(profiles.RequestKindNavigation)
+
+	assert.Contains(t, chrome103["user-agent"][0], "Chrome/103.0.0.0")
+	assert.Equal(t, []string{"gzip, deflate, br"}, chrome103["accept-encoding"], "Expected Chrome 103 not to accept zstd")
+	assert.NotContains(t, chrome103, "priority", "Expected Chrome 103 not to send the priority header")
+
+	safari := profiles.Safari_16_0.GetHeaders(profiles.RequestKindNavigation)
+
+	assert.Contains(t, safari["user-agent"][0], "Macintosh")
+	assert.Contains(t, safari["user-agent"][0], "Version/16.0")
+}
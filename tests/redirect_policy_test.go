@@ -0,0 +1,191 @@
+package tests
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Mathious6/httpkit"
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/bogdanfinn/fhttp/httptest"
+	"github.com/stretchr/testify/assert"
+)
+
+// redirectServer redirects /redirect with the status of its "status" query parameter to the URL of its "to" query
+// parameter, setting a cookie named after the status, and echoes the other requests.
+func redirectServer(t *testing.T) *httptest.Server {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/redirect" {
+			status, _ := strconv.Atoi(req.URL.Query().Get("status"))
+			http.SetCookie(w, &http.Cookie{Name: "hop" + strconv.Itoa(status), Value: "1"})
+			http.Redirect(w, req, req.URL.Query().Get("to"), status)
+			return
+		}
+
+		body, _ := io.ReadAll(req.Body)
+
+		w.Header().Set("X-Method", req.Method)
+		w.Header().Set("X-Body", string(body))
+		w.Header().Set("X-Content-Type", req.Header.Get("Content-Type"))
+		w.Header().Set("X-Secret", req.Header.Get("X-Secret"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(testServer.Close)
+
+	return testServer
+}
+
+func redirectUrl(base string, status int, to string) string {
+	return base + "/redirect?status=" + strconv.Itoa(status) + "&to=" + url.QueryEscape(to)
+}
+
+func TestClient_RedirectPolicy_RewritesMethods(t *testing.T) {
+	testServer := redirectServer(t)
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(), httpkit.WithRedirectPolicy(httpkit.DefaultRedirectPolicy()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(method string, status int) *http.Response {
+		req, _ := http.NewRequest(method, redirectUrl(testServer.URL, status, "/echo"), strings.NewReader("payload"))
+		req.Header.Set("Content-Type", "text/plain")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		return resp
+	}
+
+	resp := send(http.MethodPost, http.StatusFound)
+	assert.Equal(t, http.MethodGet, resp.Header.Get("X-Method"))
+	assert.Equal(t, "", resp.Header.Get("X-Body"))
+	assert.Equal(t, "", resp.Header.Get("X-Content-Type"), "Expected the body headers to be removed with the body")
+
+	resp = send(http.MethodPut, http.StatusMovedPermanently)
+	assert.Equal(t, http.MethodPut, resp.Header.Get("X-Method"), "Expected browsers to keep other methods than POST on 301")
+	assert.Equal(t, "payload", resp.Header.Get("X-Body"))
+	assert.Equal(t, "text/plain", resp.Header.Get("X-Content-Type"))
+
+	resp = send(http.MethodPut, http.StatusSeeOther)
+	assert.Equal(t, http.MethodGet, resp.Header.Get("X-Method"))
+
+	keepMethod := httpkit.DefaultRedirectPolicy()
+	keepMethod.RewriteMethod = func(statusCode int, method string) string {
+		return method
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, redirectUrl(testServer.URL, http.StatusFound, "/echo"), strings.NewReader("payload"))
+
+	resp, err = client.DoWithOptions(req, httpkit.WithRequestRedirectPolicy(keepMethod))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, http.MethodPost, resp.Header.Get("X-Method"))
+	assert.Equal(t, "payload", resp.Header.Get("X-Body"))
+}
+
+func TestClient_RedirectPolicy_CrossOrigin(t *testing.T) {
+	testServer := redirectServer(t)
+	otherOrigin := strings.Replace(testServer.URL, "127.0.0.1", "localhost", 1)
+
+	policy := httpkit.DefaultRedirectPolicy()
+	policy.CrossOriginHeaders = append(policy.CrossOriginHeaders, "X-Secret")
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(), httpkit.WithRedirectPolicy(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(rawUrl string, options ...httpkit.RequestOption) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, rawUrl, nil)
+		req.Header.Set("X-Secret", "secret")
+
+		resp, err := client.DoWithOptions(req, options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		return resp
+	}
+
+	assert.Equal(t, "secret", send(redirectUrl(testServer.URL, http.StatusFound, "/echo")).Header.Get("X-Secret"))
+	assert.Equal(t, "", send(redirectUrl(testServer.URL, http.StatusFound, otherOrigin+"/echo")).Header.Get("X-Secret"))
+
+	policy.SameOriginOnly = true
+
+	resp := send(redirectUrl(testServer.URL, http.StatusFound, otherOrigin+"/echo"), httpkit.WithRequestRedirectPolicy(policy))
+	assert.Equal(t, http.StatusFound, resp.StatusCode, "Expected the cross-origin redirect response to be returned")
+}
+
+func TestClient_RedirectPolicy_History(t *testing.T) {
+	testServer := redirectServer(t)
+
+	policy := httpkit.DefaultRedirectPolicy()
+	policy.MaxRedirects = 2
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(), httpkit.WithRedirectPolicy(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headerOrder := []string{"x-secret", "user-agent"}
+	chain := redirectUrl(testServer.URL, http.StatusMovedPermanently, redirectUrl(testServer.URL, http.StatusTemporaryRedirect, "/echo"))
+
+	req, _ := http.NewRequest(http.MethodGet, chain, nil)
+	req.Header = http.Header{"x-secret": {"secret"}, http.HeaderOrderKey: headerOrder}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	history := httpkit.RedirectHistory(resp)
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, http.StatusMovedPermanently, history[0].StatusCode)
+		assert.Equal(t, "hop301", history[0].Cookies()[0].Name)
+		assert.Equal(t, http.StatusTemporaryRedirect, history[1].StatusCode)
+		assert.Equal(t, "hop307", history[1].Cookies()[0].Name)
+	}
+
+	assert.Equal(t, headerOrder, resp.Request.Header[http.HeaderOrderKey], "Expected the header order to be kept on every hop")
+	assert.Empty(t, httpkit.RedirectHistory(history[0]))
+
+	req, _ = http.NewRequest(http.MethodGet, redirectUrl(testServer.URL, http.StatusFound, chain), nil)
+
+	_, err = client.Do(req)
+	assert.True(t, errors.Is(err, httpkit.ErrTooManyRedirects), "Expected the third redirect to fail, got %v", err)
+}
+
+func TestRedirectPolicy_MaxRedirects(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/next", nil)
+
+	via := func(n int) []*http.Request {
+		requests := make([]*http.Request, n)
+		for i := range requests {
+			requests[i], _ = http.NewRequest(http.MethodGet, "https://example.com/"+strconv.Itoa(i), nil)
+		}
+
+		return requests
+	}
+
+	policy := httpkit.RedirectPolicy{MaxRedirects: 3}
+
+	assert.NoError(t, policy.CheckRedirect(req, via(3)), "Expected the third redirect to be followed")
+	assert.True(t, errors.Is(policy.CheckRedirect(req, via(4)), httpkit.ErrTooManyRedirects), "Expected the fourth redirect not to be followed")
+
+	policy = httpkit.RedirectPolicy{}
+
+	assert.NoError(t, policy.CheckRedirect(req, via(10)), "Expected the tenth redirect to be followed")
+	assert.True(t, errors.Is(policy.CheckRedirect(req, via(11)), httpkit.ErrTooManyRedirects), "Expected 10 redirects by default")
+}
@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/Mathious6/httpkit"
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestFromHar(t *testing.T) {
+	var entry httpkit.HarEntry
+
+	err := json.Unmarshal([]byte(`{
+		"request": {
+			"method": "POST",
+			"url": "https://example.com/api?q=1",
+			"httpVersion": "http/2.0",
+			"headers": [
+				{"name": ":authority", "value": "example.com"},
+				{"name": ":method", "value": "POST"},
+				{"name": ":path", "value": "/api?q=1"},
+				{"name": ":scheme", "value": "https"},
+				{"name": "content-length", "value": "7"},
+				{"name": "sec-ch-ua-platform", "value": "\"Windows\""},
+				{"name": "content-type", "value": "text/plain"},
+				{"name": "accept", "value": "*/*"}
+			],
+			"postData": {"mimeType": "text/plain", "text": "payload"}
+		}
+	}`), &entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := httpkit.RequestFromHar(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "https://example.com/api?q=1", req.URL.String())
+	assert.Equal(t, []string{":authority", ":method", ":path", ":scheme"}, req.Header[http.PHeaderOrderKey])
+	assert.Equal(t, []string{"content-length", "sec-ch-ua-platform", "content-type", "accept"}, req.Header[http.HeaderOrderKey])
+	assert.Equal(t, []string{`"Windows"`}, req.Header["sec-ch-ua-platform"])
+	assert.NotContains(t, req.Header, "content-length", "Expected the content length to be left to the transport")
+
+	body, _ := io.ReadAll(req.Body)
+	assert.Equal(t, "payload", string(body))
+}
+
+func TestRequestFromCurl(t *testing.T) {
+	req, err := httpkit.RequestFromCurl(`curl 'https://example.com/api' \
+  -H 'accept: application/json' \
+  -H 'accept-language: en-US,en;q=0.9' \
+  -b 'session=abc; theme=dark' \
+  -H 'user-agent: Mozilla/5.0' \
+  -H $'x-note: it\'s\tfine' \
+  --data-raw '{"name":"it'\''s"}' \
+  --compressed`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, http.MethodPost, req.Method, "Expected data to make a POST request")
+	assert.Equal(t, "https://example.com/api", req.URL.String())
+	assert.Equal(t, []string{"accept", "accept-language", "cookie", "user-agent", "x-note", "content-type"}, req.Header[http.HeaderOrderKey])
+	assert.Equal(t, "session=abc; theme=dark", req.Header["cookie"][0])
+	assert.Equal(t, "it's\tfine", req.Header["x-note"][0])
+
+	body, _ := io.ReadAll(req.Body)
+	assert.Equal(t, `{"name":"it's"}`, string(body))
+
+	req, err = httpkit.RequestFromCurl(`curl -G -XDELETE "https://example.com/items" -d id=1 -u "user:pass"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, http.MethodDelete, req.Method)
+	assert.Equal(t, "https://example.com/items?id=1", req.URL.String())
+	assert.Equal(t, "Basic dXNlcjpwYXNz", req.Header["authorization"][0])
+
+	_, err = httpkit.RequestFromCurl(`curl 'https://example.com' -F 'file=@a.txt'`)
+	assert.Error(t, err)
+
+	_, err = httpkit.RequestFromCurl(`wget 'https://example.com'`)
+	assert.Error(t, err)
+
+	req, err = httpkit.RequestFromCurl(`curl -sSL --connect-to a:443:b:443 -x http://proxy:8080 'https://example.com' --http2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "https://example.com", req.URL.String(), "Expected the values of the ignored options to be skipped")
+
+	_, err = httpkit.RequestFromCurl(`curl --oauth2-bearer token 'https://example.com'`)
+	assert.ErrorContains(t, err, "unsupported curl option --oauth2-bearer")
+
+	_, err = httpkit.RequestFromCurl(`curl -sz yesterday 'https://example.com'`)
+	assert.ErrorContains(t, err, "unsupported curl option -z")
+}
+
+func TestRequestFromFetch(t *testing.T) {
+	req, err := httpkit.RequestFromFetch(`fetch("https://example.com/api", {
+  "headers": {
+    "accept": "*/*",
+    "content-type": "application/json",
+    "sec-fetch-mode": "cors"
+  },
+  "referrer": "https://example.com/page",
+  "referrerPolicy": "strict-origin-when-cross-origin",
+  "body": "{\"a\":1}",
+  "method": "PUT",
+  "mode": "cors",
+  "credentials": "include"
+});`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, http.MethodPut, req.Method)
+	assert.Equal(t, []string{"accept", "content-type", "sec-fetch-mode", "referer"}, req.Header[http.HeaderOrderKey])
+	assert.Equal(t, "https://example.com/page", req.Header["referer"][0])
+
+	body, _ := io.ReadAll(req.Body)
+	assert.Equal(t, `{"a":1}`, string(body))
+
+	req, err = httpkit.RequestFromFetch(`fetch("https://example.com/", {"headers": {"accept": "text/html"}, "body": null, "method": "GET"});`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, http.MethodGet, req.Method)
+	assert.Nil(t, req.Body)
+}
+
+func TestToCurl(t *testing.T) {
+	req, err := httpkit.RequestFromCurl(`curl 'https://example.com/api?q=1' -X PATCH -H 'accept: */*' -H 'accept-encoding: gzip' -H $'x-note: line\nbreak' --data-raw 'it'\''s'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	command, err := httpkit.ToCurl(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, `curl 'https://example.com/api?q=1' \
+  -X 'PATCH' \
+  -H 'accept: */*' \
+  -H 'accept-encoding: gzip' \
+  -H $'x-note: line\nbreak' \
+  -H 'content-type: application/x-www-form-urlencoded' \
+  --data-raw 'it'\''s' \
+  --compressed`, command)
+
+	roundTrip, err := httpkit.RequestFromCurl(command)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, req.Header, roundTrip.Header, "Expected the command to reproduce the request")
+
+	body, _ := io.ReadAll(req.Body)
+	assert.Equal(t, "it's", string(body), "Expected the body to be left to send")
+}
@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/httpkit"
+	"github.com/Mathious6/httpkit/profiles"
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/bogdanfinn/fhttp/httptest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_DoWithOptions_ProxiesAreUsedInParallel(t *testing.T) {
+	testServer := getWebServer()
+	testServer.Start()
+	defer testServer.Close()
+
+	var firstConnects, secondConnects atomic.Int32
+	firstProxy := getConnectProxy(&firstConnects)
+	defer firstProxy.Close()
+	secondProxy := getConnectProxy(&secondConnects)
+	defer secondProxy.Close()
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(), httpkit.WithClientProfile(profiles.Chrome_133))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		proxyUrl := firstProxy.URL
+		if i%2 == 1 {
+			proxyUrl = secondProxy.URL
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/index", testServer.URL), nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			resp, err := client.DoWithOptions(req, httpkit.WithRequestProxy(proxyUrl))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}()
+	}
+	wg.Wait()
+
+	assert.NotZero(t, firstConnects.Load(), "Expected first proxy to be used")
+	assert.NotZero(t, secondConnects.Load(), "Expected second proxy to be used")
+	assert.Equal(t, "", client.GetProxy(), "Expected client proxy to stay untouched")
+}
+
+func TestClient_DoWithOptions_RedirectAndTimeoutOnlyApplyToRequest(t *testing.T) {
+	testServer := getWebServer()
+	testServer.Start()
+	defer testServer.Close()
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(), httpkit.WithNotFollowRedirects())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/redirect", testServer.URL), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.DoWithOptions(req, httpkit.WithRequestFollowRedirects(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.False(t, client.GetFollowRedirect(), "Expected client redirect policy to stay untouched")
+
+	req, err = http.NewRequest(http.MethodGet, fmt.Sprintf("%s/timeout", testServer.URL), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = client.DoWithOptions(req, httpkit.WithRequestTimeout(500*time.Millisecond))
+
+	assert.Nil(t, resp)
+	assert.Error(t, err)
+}
+
+// getConnectProxy returns a started HTTP proxy which only supports CONNECT tunnels and counts them.
+func getConnectProxy(connects *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		connects.Add(1)
+
+		upstream, err := net.Dial("tcp", req.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			_ = upstream.Close()
+			return
+		}
+
+		go func() {
+			_, _ = io.Copy(upstream, conn)
+			_ = upstream.Close()
+		}()
+
+		_, _ = io.Copy(conn, upstream)
+		_ = conn.Close()
+	}))
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Mathious6/httpkit"
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/bogdanfinn/fhttp/httptest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_RetryPolicy_RetriesUntilSuccess(t *testing.T) {
+	var attempts atomic.Int32
+	testServer := getFlakyServer(&attempts, 2)
+	defer testServer.Close()
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(), httpkit.WithRetryPolicy(fastRetryPolicy()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, testServer.URL, strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), attempts.Load(), "Expected two retries before success")
+}
+
+func TestClient_RetryPolicy_NonIdempotentRequestIsNotRetried(t *testing.T) {
+	var attempts atomic.Int32
+	testServer := getFlakyServer(&attempts, 2)
+	defer testServer.Close()
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(), httpkit.WithRetryPolicy(fastRetryPolicy()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, testServer.URL, strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), attempts.Load(), "Expected POST not to be retried")
+}
+
+func TestClient_RetryPolicy_RetryAfterAboveLimitIsNotWaitedFor(t *testing.T) {
+	var attempts atomic.Int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		attempts.Add(1)
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer testServer.Close()
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(), httpkit.WithRetryPolicy(fastRetryPolicy()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Get(testServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, int32(1), attempts.Load(), "Expected no retry for a Retry-After above the limit")
+}
+
+func fastRetryPolicy() httpkit.RetryPolicy {
+	retryPolicy := httpkit.DefaultRetryPolicy()
+	retryPolicy.InitialBackoff = 10 * time.Millisecond
+	retryPolicy.MaxRetryAfter = time.Second
+
+	return retryPolicy
+}
+
+// getFlakyServer returns a started server answering 503 to the first failures requests.
+func getFlakyServer(attempts *atomic.Int32, failures int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if attempts.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+}
@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Mathious6/httpkit"
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/bogdanfinn/fhttp/httptest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_FetchContext_SameSiteCookiesOnRedirects(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/bounce" {
+			http.Redirect(w, req, req.URL.Query().Get("to"), http.StatusFound)
+			return
+		}
+
+		w.Header().Set("X-Cookie", req.Header.Get("Cookie"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	serverUrl, _ := url.Parse(testServer.URL)
+	bounceUrl := strings.Replace(testServer.URL, "127.0.0.1", "localhost", 1) + "/bounce?to=" + url.QueryEscape(testServer.URL+"/echo")
+
+	jar := httpkit.NewCookieJar()
+	jar.SetCookies(serverUrl, []*http.Cookie{
+		{Name: "strict", Value: "1", SameSite: http.SameSiteStrictMode},
+		{Name: "lax", Value: "1", SameSite: http.SameSiteLaxMode},
+	})
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(), httpkit.WithCookieJar(jar))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(rawUrl string, fetch httpkit.FetchContext) string {
+		req, err := http.NewRequestWithContext(httpkit.WithFetchContext(context.Background(), fetch), http.MethodGet, rawUrl, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		return resp.Header.Get("X-Cookie")
+	}
+
+	assert.Equal(t, "strict=1; lax=1", send(testServer.URL+"/echo", httpkit.FetchContext{Initiator: testServer.URL, Navigation: true}))
+	assert.Equal(t, "", send(testServer.URL+"/echo", httpkit.FetchContext{Initiator: "https://other.com"}))
+	assert.Equal(t, "lax=1", send(bounceUrl, httpkit.FetchContext{Initiator: testServer.URL, Navigation: true}), "Expected a cross-site redirect to exclude strict cookies")
+}
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Mathious6/httpkit"
+	"github.com/Mathious6/httpkit/profiles"
+	http "github.com/bogdanfinn/fhttp"
+	"github.com/bogdanfinn/fhttp/httptest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_ClientTrace_FiresConnectionLifecycleHooks(t *testing.T) {
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	testServer.EnableHTTP2 = true
+	testServer.StartTLS()
+	defer testServer.Close()
+
+	var connects atomic.Int32
+	proxyServer := getConnectProxy(&connects)
+	defer proxyServer.Close()
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger(), httpkit.WithClientProfile(profiles.Chrome_133), httpkit.WithInsecureSkipVerify())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lck sync.Mutex
+	var events []string
+	var handshake httpkit.TLSHandshakeInfo
+	var conns []httpkit.GotConnInfo
+
+	record := func(event string) {
+		lck.Lock()
+		defer lck.Unlock()
+		events = append(events, event)
+	}
+
+	trace := &httpkit.ClientTrace{
+		DNSStart:          func(host string) { record("dns start") },
+		DNSDone:           func(addrs []net.IPAddr, err error) { record("dns done") },
+		ConnectStart:      func(network, addr string) { record("connect start") },
+		ConnectDone:       func(network, addr string, err error) { record("connect done") },
+		ProxyConnectStart: func(proxyAddr, targetAddr string) { record("proxy connect start") },
+		ProxyConnectDone:  func(proxyAddr, targetAddr string, err error) { record("proxy connect done") },
+		TLSHandshakeStart: func(serverName string) { record("tls start") },
+		TLSHandshakeDone: func(info httpkit.TLSHandshakeInfo, err error) {
+			record("tls done")
+			handshake = info
+		},
+		GotConn: func(info httpkit.GotConnInfo) {
+			record("got conn")
+			conns = append(conns, info)
+		},
+		GotFirstResponseByte: func() { record("first byte") },
+	}
+
+	url := strings.Replace(testServer.URL, "127.0.0.1", "localhost", 1)
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := client.DoWithOptions(req.WithContext(httpkit.WithClientTrace(req.Context(), trace)), httpkit.WithRequestProxy(proxyServer.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	assert.Equal(t, []string{
+		"connect start", "connect done", "proxy connect start", "proxy connect done", "tls start", "tls done", "got conn", "first byte",
+		"got conn", "first byte",
+	}, events)
+
+	assert.Equal(t, "h2", handshake.NegotiatedProtocol)
+	assert.NotZero(t, handshake.CipherSuite)
+	assert.Len(t, conns, 2)
+	assert.Equal(t, "h2", conns[0].Protocol)
+	assert.False(t, conns[0].Reused)
+	assert.True(t, conns[1].Reused)
+}
+
+func TestClient_ClientTrace_DirectDialerFiresDNSHooks(t *testing.T) {
+	testServer := getWebServer()
+	testServer.Start()
+	defer testServer.Close()
+
+	client, err := httpkit.NewHttpClient(httpkit.NewNoopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dnsHost string
+	var connected atomic.Bool
+
+	trace := &httpkit.ClientTrace{
+		DNSStart:    func(host string) { dnsHost = host },
+		ConnectDone: func(network, addr string, err error) { connected.Store(err == nil) },
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.Replace(testServer.URL, "127.0.0.1", "localhost", 1)+"/index", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Do(req.WithContext(httpkit.WithClientTrace(req.Context(), trace)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	assert.Equal(t, "localhost", dnsHost)
+	assert.True(t, connected.Load())
+}
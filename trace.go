@@ -0,0 +1,204 @@
+package httpkit
+
+import (
+	"context"
+	"net"
+	nethttptrace "net/http/httptrace"
+	"time"
+
+	"github.com/bogdanfinn/fhttp/http2"
+	"github.com/bogdanfinn/fhttp/httptrace"
+	tls "github.com/bogdanfinn/utls"
+)
+
+// ClientTrace is a set of hooks run at the various stages of an outgoing request, like net/http/httptrace.ClientTrace.
+// Any particular hook may be nil. Hooks may be called concurrently from different goroutines and some may be called
+// after the request completed or failed.
+//
+// The trace is carried by the request context, see WithClientTrace. It is fired by the dialers, the TLS handshake and
+// the HTTP/1, HTTP/2 and HTTP/3 transports, whatever the proxy of the client or of the request is.
+// HTTP/2 connections opened by the connection pool after the first one of an origin do not carry the request context,
+// therefore only GotConn and GotFirstResponseByte are fired for them.
+type ClientTrace struct {
+	// DNSStart is called when a DNS lookup begins.
+	DNSStart func(host string)
+	// DNSDone is called when a DNS lookup ends.
+	DNSDone func(addrs []net.IPAddr, err error)
+	// ConnectStart is called when a new connection's dial begins, to the origin or to the proxy. With multiple IP
+	// addresses to try, it may be called multiple times.
+	ConnectStart func(network, addr string)
+	// ConnectDone is called when a new connection's dial completes.
+	ConnectDone func(network, addr string, err error)
+	// ProxyConnectStart is called before the CONNECT request is sent to an HTTP proxy.
+	ProxyConnectStart func(proxyAddr, targetAddr string)
+	// ProxyConnectDone is called when the proxy answered the CONNECT request. err is a *ProxyConnectError if the proxy refused it.
+	ProxyConnectDone func(proxyAddr, targetAddr string, err error)
+	// TLSHandshakeStart is called when the TLS handshake with the origin begins.
+	TLSHandshakeStart func(serverName string)
+	// TLSHandshakeDone is called after the TLS handshake with the origin with either the negotiated parameters or an error.
+	TLSHandshakeDone func(info TLSHandshakeInfo, err error)
+	// GotConn is called when a connection was obtained for the request, either freshly dialed or reused.
+	GotConn func(info GotConnInfo)
+	// GotFirstResponseByte is called when the first byte of the response headers is available.
+	GotFirstResponseByte func()
+}
+
+// TLSHandshakeInfo holds the parameters negotiated by a TLS handshake.
+type TLSHandshakeInfo struct {
+	ServerName         string
+	NegotiatedProtocol string
+	Version            uint16
+	CipherSuite        uint16
+	DidResume          bool
+}
+
+// GotConnInfo is the argument of ClientTrace.GotConn.
+type GotConnInfo struct {
+	// Conn is the connection obtained. For HTTP/3 it only exposes its local and remote addresses.
+	Conn net.Conn
+	// Protocol is the application protocol of the connection: "http/1.1", "h2" or "h3".
+	Protocol string
+	// Reused is whether this connection has been previously used for another request.
+	Reused bool
+	// WasIdle is whether this connection was obtained from an idle pool.
+	WasIdle bool
+	// IdleTime reports how long the connection was previously idle, if WasIdle is true.
+	IdleTime time.Duration
+}
+
+type clientTraceContextKey struct{}
+
+// WithClientTrace returns a new context based on the provided parent ctx. Requests made with the returned context
+// fire the hooks of trace.
+func WithClientTrace(ctx context.Context, trace *ClientTrace) context.Context {
+	if trace == nil {
+		panic("nil trace")
+	}
+
+	ctx = context.WithValue(ctx, clientTraceContextKey{}, trace)
+
+	transportTrace := &httptrace.ClientTrace{}
+
+	if trace.GotConn != nil {
+		transportTrace.GotConn = func(info httptrace.GotConnInfo) {
+			trace.GotConn(GotConnInfo{
+				Conn:     info.Conn,
+				Protocol: connProtocol(info.Conn),
+				Reused:   info.Reused,
+				WasIdle:  info.WasIdle,
+				IdleTime: info.IdleTime,
+			})
+		}
+	}
+
+	if trace.GotFirstResponseByte != nil {
+		transportTrace.GotFirstResponseByte = trace.GotFirstResponseByte
+	}
+
+	return httptrace.WithClientTrace(ctx, transportTrace)
+}
+
+// ContextClientTrace returns the ClientTrace associated with the provided context. If none, it returns nil.
+func ContextClientTrace(ctx context.Context) *ClientTrace {
+	trace, _ := ctx.Value(clientTraceContextKey{}).(*ClientTrace)
+
+	return trace
+}
+
+// withNetTrace forwards the DNS and connect hooks of the trace of ctx to the net.Dialer, which fires net/http/httptrace hooks.
+func withNetTrace(ctx context.Context) context.Context {
+	trace := ContextClientTrace(ctx)
+	if trace == nil {
+		return ctx
+	}
+
+	netTrace := &nethttptrace.ClientTrace{
+		ConnectStart: trace.ConnectStart,
+		ConnectDone:  trace.ConnectDone,
+	}
+
+	if trace.DNSStart != nil {
+		netTrace.DNSStart = func(info nethttptrace.DNSStartInfo) {
+			trace.DNSStart(info.Host)
+		}
+	}
+
+	if trace.DNSDone != nil {
+		netTrace.DNSDone = func(info nethttptrace.DNSDoneInfo) {
+			trace.DNSDone(info.Addrs, info.Err)
+		}
+	}
+
+	return nethttptrace.WithClientTrace(ctx, netTrace)
+}
+
+func (t *ClientTrace) dnsStart(host string) {
+	if t != nil && t.DNSStart != nil {
+		t.DNSStart(host)
+	}
+}
+
+func (t *ClientTrace) dnsDone(addrs []net.IPAddr, err error) {
+	if t != nil && t.DNSDone != nil {
+		t.DNSDone(addrs, err)
+	}
+}
+
+func (t *ClientTrace) connectStart(network, addr string) {
+	if t != nil && t.ConnectStart != nil {
+		t.ConnectStart(network, addr)
+	}
+}
+
+func (t *ClientTrace) connectDone(network, addr string, err error) {
+	if t != nil && t.ConnectDone != nil {
+		t.ConnectDone(network, addr, err)
+	}
+}
+
+func (t *ClientTrace) proxyConnectStart(proxyAddr, targetAddr string) {
+	if t != nil && t.ProxyConnectStart != nil {
+		t.ProxyConnectStart(proxyAddr, targetAddr)
+	}
+}
+
+func (t *ClientTrace) proxyConnectDone(proxyAddr, targetAddr string, err error) {
+	if t != nil && t.ProxyConnectDone != nil {
+		t.ProxyConnectDone(proxyAddr, targetAddr, err)
+	}
+}
+
+func (t *ClientTrace) tlsHandshakeStart(serverName string) {
+	if t != nil && t.TLSHandshakeStart != nil {
+		t.TLSHandshakeStart(serverName)
+	}
+}
+
+func (t *ClientTrace) tlsHandshakeDone(state tls.ConnectionState, err error) {
+	if t != nil && t.TLSHandshakeDone != nil {
+		t.TLSHandshakeDone(TLSHandshakeInfo{
+			ServerName:         state.ServerName,
+			NegotiatedProtocol: state.NegotiatedProtocol,
+			Version:            state.Version,
+			CipherSuite:        state.CipherSuite,
+			DidResume:          state.DidResume,
+		}, err)
+	}
+}
+
+// connProtocol returns the application protocol of a connection handed out by one of the transports.
+func connProtocol(conn net.Conn) string {
+	if conn == nil {
+		return ""
+	}
+
+	if _, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		return "h3"
+	}
+
+	if tlsConn, ok := conn.(*tls.UConn); ok && tlsConn.ConnectionState().NegotiatedProtocol == http2.NextProtoTLS {
+		return http2.NextProtoTLS
+	}
+
+	return "http/1.1"
+}
@@ -0,0 +1,285 @@
+package httpkit
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/Mathious6/httpkit/profiles"
+	http "github.com/bogdanfinn/fhttp"
+	tls "github.com/bogdanfinn/utls"
+)
+
+// maxSpecKeys bounds the number of spec factories whose key is memoized, see specKey.
+const maxSpecKeys = 1024
+
+// specKeys memoizes the keys of the spec factories, by the closure of the factory.
+var specKeys = struct {
+	keys map[unsafe.Pointer]string
+	sync.Mutex
+}{keys: make(map[unsafe.Pointer]string)}
+
+// DefaultMaxCachedTransports is the number of per-request transports a client keeps alive. Once exceeded, the least recently used
+// transport is evicted and its idle connections are closed.
+var DefaultMaxCachedTransports = 64
+
+// transportCache keeps the round trippers created for per-request proxies and client profiles, so that concurrent requests
+// can use them in parallel while keeping their pooled connections and TLS session tickets.
+type transportCache struct {
+	entries  map[string]*list.Element
+	order    *list.List
+	maxSize  int
+	building sync.Mutex
+	sync.Mutex
+}
+
+type cachedTransport struct {
+	key       string
+	transport http.RoundTripper
+}
+
+func newTransportCache(maxSize int) *transportCache {
+	return &transportCache{
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		maxSize: maxSize,
+	}
+}
+
+// get returns the transport for the given proxy and client profile, building it on first use.
+func (tc *transportCache) get(proxyUrl string, clientProfile profiles.ClientProfile, build func() (http.RoundTripper, error)) (http.RoundTripper, error) {
+	key := proxyUrl + "|" + clientProfileKey(clientProfile)
+
+	if transport, ok := tc.lookup(key); ok {
+		return transport, nil
+	}
+
+	// building is serialized so that concurrent requests for the same key do not create duplicate transports
+	tc.building.Lock()
+	defer tc.building.Unlock()
+
+	if transport, ok := tc.lookup(key); ok {
+		return transport, nil
+	}
+
+	transport, err := build()
+	if err != nil {
+		return nil, err
+	}
+
+	tc.Lock()
+	defer tc.Unlock()
+
+	tc.entries[key] = tc.order.PushFront(&cachedTransport{key: key, transport: transport})
+
+	for tc.maxSize > 0 && tc.order.Len() > tc.maxSize {
+		oldest := tc.order.Back()
+		tc.order.Remove(oldest)
+
+		evicted := oldest.Value.(*cachedTransport)
+		delete(tc.entries, evicted.key)
+		closeTransport(evicted.transport)
+	}
+
+	return transport, nil
+}
+
+func (tc *transportCache) lookup(key string) (http.RoundTripper, bool) {
+	tc.Lock()
+	defer tc.Unlock()
+
+	element, ok := tc.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	tc.order.MoveToFront(element)
+
+	return element.Value.(*cachedTransport).transport, true
+}
+
+func (tc *transportCache) closeIdleConnections() {
+	tc.Lock()
+	defer tc.Unlock()
+
+	for element := tc.order.Front(); element != nil; element = element.Next() {
+		closeIdleConnections(element.Value.(*cachedTransport).transport)
+	}
+}
+
+func (tc *transportCache) close() error {
+	tc.Lock()
+	defer tc.Unlock()
+
+	var err error
+	for element := tc.order.Front(); element != nil; element = element.Next() {
+		err = errors.Join(err, closeTransport(element.Value.(*cachedTransport).transport))
+	}
+
+	return err
+}
+
+// closeTransport closes transport, or only its idle connections if it can not be closed.
+func closeTransport(transport http.RoundTripper) error {
+	if closer, ok := transport.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	closeIdleConnections(transport)
+
+	return nil
+}
+
+func closeIdleConnections(transport http.RoundTripper) {
+	type closeIdler interface {
+		CloseIdleConnections()
+	}
+
+	if tr, ok := transport.(closeIdler); ok {
+		tr.CloseIdleConnections()
+	}
+}
+
+// clientProfileKey identifies a client profile by its ClientHelloID, the ClientHelloSpec its spec factory builds and its
+// HTTP/2 and QUIC fingerprints. Profiles sending the same ClientHello and fingerprints share a key, whatever the
+// function building their spec.
+func clientProfileKey(clientProfile profiles.ClientProfile) string {
+	clientHelloId := clientProfile.GetClientHelloId()
+
+	return fmt.Sprintf("%s|%s|%v|%v|%v|%d|%v|%v|%s",
+		clientHelloId.Str(),
+		specKey(clientHelloId),
+		clientProfile.GetSettings(),
+		clientProfile.GetSettingsOrder(),
+		clientProfile.GetPseudoHeaderOrder(),
+		clientProfile.GetConnectionFlow(),
+		clientProfile.GetPriorities(),
+		clientProfile.GetHeaderPriority(),
+		quicProfileKey(clientProfile.GetQUICProfile()),
+	)
+}
+
+// quicProfileKey returns the contents of the QUIC profile, empty for profiles without one.
+func quicProfileKey(quicProfile *profiles.QUICProfile) string {
+	if quicProfile == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%+v", *quicProfile)
+}
+
+// specKey returns a hash of the ClientHelloSpec built by the spec factory of clientHelloId. The factories of the
+// ClientHelloIDs built into the TLS library fail, those are identified by the pointer of their factory.
+//
+// Building and hashing the spec is too costly to be done for every request, the key is memoized by the closure of the
+// factory: copies of a profile share it, while closures of the same function capturing different values do not.
+// The memoized closures are kept alive, so that their address is not reused by another one.
+func specKey(clientHelloId tls.ClientHelloID) string {
+	if clientHelloId.SpecFactory == nil {
+		return ""
+	}
+
+	factory := clientHelloId.SpecFactory
+	closure := *(*unsafe.Pointer)(unsafe.Pointer(&factory))
+
+	specKeys.Lock()
+	key, ok := specKeys.keys[closure]
+	specKeys.Unlock()
+
+	if ok {
+		return key
+	}
+
+	spec, err := factory()
+	if err != nil {
+		key = fmt.Sprintf("%x", reflect.ValueOf(factory).Pointer())
+	} else {
+		hash := sha256.New()
+		writeValue(hash, reflect.ValueOf(spec), 0)
+		key = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	specKeys.Lock()
+	defer specKeys.Unlock()
+
+	if len(specKeys.keys) >= maxSpecKeys {
+		clear(specKeys.keys)
+	}
+
+	specKeys.keys[closure] = key
+
+	return key
+}
+
+// writeValue writes the contents of v to w, following pointers and interfaces rather than writing their addresses, so
+// that equal values built by different calls write the same bytes. Functions are written as their code pointer.
+func writeValue(w io.Writer, v reflect.Value, depth int) {
+	if depth > 32 {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Invalid:
+		fmt.Fprint(w, "nil;")
+	case reflect.Bool:
+		fmt.Fprintf(w, "%t;", v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		fmt.Fprintf(w, "%d;", v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		fmt.Fprintf(w, "%d;", v.Uint())
+	case reflect.Float32, reflect.Float64:
+		fmt.Fprintf(w, "%g;", v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		fmt.Fprintf(w, "%g;", v.Complex())
+	case reflect.String:
+		fmt.Fprintf(w, "%q;", v.String())
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			fmt.Fprint(w, "nil;")
+			return
+		}
+
+		fmt.Fprintf(w, "%s{", v.Elem().Type())
+		writeValue(w, v.Elem(), depth+1)
+		fmt.Fprint(w, "}")
+	case reflect.Slice, reflect.Array:
+		fmt.Fprintf(w, "[%d:", v.Len())
+
+		for i := 0; i < v.Len(); i++ {
+			writeValue(w, v.Index(i), depth+1)
+		}
+
+		fmt.Fprint(w, "]")
+	case reflect.Struct:
+		fmt.Fprint(w, "{")
+
+		for i := 0; i < v.NumField(); i++ {
+			fmt.Fprintf(w, "%s:", v.Type().Field(i).Name)
+			writeValue(w, v.Field(i), depth+1)
+		}
+
+		fmt.Fprint(w, "}")
+	case reflect.Map:
+		entries := make([]string, 0, v.Len())
+
+		for iter := v.MapRange(); iter.Next(); {
+			var entry strings.Builder
+			writeValue(&entry, iter.Key(), depth+1)
+			writeValue(&entry, iter.Value(), depth+1)
+			entries = append(entries, entry.String())
+		}
+
+		sort.Strings(entries)
+		fmt.Fprintf(w, "map%v", entries)
+	default:
+		fmt.Fprintf(w, "%x;", v.Pointer())
+	}
+}
@@ -0,0 +1,81 @@
+package httpkit
+
+import (
+	"testing"
+
+	"github.com/Mathious6/httpkit/profiles"
+	http "github.com/bogdanfinn/fhttp"
+	tls "github.com/bogdanfinn/utls"
+	"github.com/stretchr/testify/assert"
+)
+
+func ja3Profile(t *testing.T, ja3 string) profiles.ClientProfile {
+	t.Helper()
+
+	specFactory, _, err := GetSpecFactoryFromJa3Options(ja3, Ja3SpecOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return profiles.NewClientProfile(tls.ClientHelloID{Client: "Custom", Version: "1", SpecFactory: specFactory}, nil, nil, nil, 0, nil, nil, nil)
+}
+
+func TestTransportCache_GivenProfilesOfTheSameName_WhenTheirClientHellosDiffer_ThenTheyDoNotShareTransports(t *testing.T) {
+	first := ja3Profile(t, "771,4865-4866-4867,0-10-11-43,29-23-24,0")
+	second := ja3Profile(t, "771,4865-4866,0-10-11-43,29-23,0")
+	same := ja3Profile(t, "771,4865-4866-4867,0-10-11-43,29-23-24,0")
+
+	assert.Equal(t, first.GetClientHelloStr(), second.GetClientHelloStr())
+	assert.NotEqual(t, clientProfileKey(first), clientProfileKey(second), "Expected profiles sending different ClientHellos to have different keys")
+	assert.Equal(t, clientProfileKey(first), clientProfileKey(same), "Expected profiles sending the same ClientHello to share a key")
+
+	cache := newTransportCache(DefaultMaxCachedTransports)
+	builds := 0
+
+	build := func() (http.RoundTripper, error) {
+		builds++
+		return &http.Transport{}, nil
+	}
+
+	firstTransport, _ := cache.get("", first, build)
+	secondTransport, _ := cache.get("", second, build)
+	sameTransport, _ := cache.get("", same, build)
+
+	assert.Equal(t, 2, builds)
+	assert.NotSame(t, firstTransport, secondTransport, "Expected the second profile to get its own transport")
+	assert.Same(t, firstTransport, sameTransport)
+}
+
+func TestClientProfileKey_GivenBuiltInClientHelloIDs_ThenTheyHaveDifferentKeys(t *testing.T) {
+	assert.NotEqual(t, clientProfileKey(profiles.Chrome_103), clientProfileKey(profiles.Chrome_133))
+	assert.Equal(t, clientProfileKey(profiles.Chrome_133), clientProfileKey(profiles.Chrome_133.WithHeaders(nil)))
+}
+
+func TestClientProfileKey_GivenTheSameProfile_ThenItsSpecIsBuiltOnce(t *testing.T) {
+	builds := 0
+
+	profile := profiles.NewClientProfile(tls.ClientHelloID{Client: "Custom", Version: "memoized", SpecFactory: func() (tls.ClientHelloSpec, error) {
+		builds++
+		return tls.ClientHelloSpec{CipherSuites: []uint16{tls.TLS_AES_128_GCM_SHA256}}, nil
+	}}, nil, nil, nil, 0, nil, nil, nil)
+
+	key := clientProfileKey(profile)
+
+	assert.Equal(t, key, clientProfileKey(profile))
+	assert.Equal(t, key, clientProfileKey(profile.WithHeaders(nil)))
+	assert.Equal(t, 1, builds, "Expected the key of the spec to be memoized")
+}
+
+func TestClientProfileKey_GivenQUICProfiles_ThenTheirContentsAreCompared(t *testing.T) {
+	quicProfile := func(maxIdleTimeout uint64) *profiles.QUICProfile {
+		return &profiles.QUICProfile{TransportParameters: map[profiles.QUICTransportParameterID]uint64{profiles.QUICTransportParameterMaxIdleTimeout: maxIdleTimeout}}
+	}
+
+	withQUIC := func(q *profiles.QUICProfile) profiles.ClientProfile {
+		return profiles.NewClientProfile(profiles.Chrome_133.GetClientHelloId(), nil, nil, nil, 0, nil, nil, q)
+	}
+
+	assert.Equal(t, clientProfileKey(withQUIC(quicProfile(30000))), clientProfileKey(withQUIC(quicProfile(30000))), "Expected equal QUIC profiles to share a key")
+	assert.NotEqual(t, clientProfileKey(withQUIC(quicProfile(30000))), clientProfileKey(withQUIC(quicProfile(60000))))
+	assert.NotEqual(t, clientProfileKey(withQUIC(nil)), clientProfileKey(withQUIC(quicProfile(30000))))
+}